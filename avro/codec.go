@@ -0,0 +1,75 @@
+package avro
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/kvanticoss/goutils/v2/codec"
+	"github.com/kvanticoss/goutils/v2/iterator"
+)
+
+// Codec writes avro object container files with the schema derived from the first record; registered as "avro"
+var Codec = NewCodec(nil, WriterOptions{})
+
+func init() {
+	codec.Register(Codec)
+}
+
+// NewCodec returns a codec.Codec writing object container files with the schema and options.
+// A nil schema is derived from the first record of each file. Use it with e.g. recordwriter.PartitionedBySize
+// to write size rotated avro files; each file gets its own header. Records are buffered in blocks so files
+// are rotated at block boundaries (see WriterOptions.BlockSize).
+func NewCodec(schema *Schema, opts WriterOptions) codec.Codec {
+	return avroCodec{schema: schema, opts: opts}
+}
+
+type avroCodec struct {
+	schema *Schema
+	opts   WriterOptions
+}
+
+func (avroCodec) Name() string {
+	return "avro"
+}
+
+func (avroCodec) Extension() string {
+	return ".avro"
+}
+
+func (c avroCodec) NewEncoder(w io.Writer) codec.Encoder {
+	return NewWriter(w, c.schema, c.opts)
+}
+
+func (avroCodec) NewDecoder(r io.Reader) codec.Decoder {
+	return &lazyDecoder{r: r}
+}
+
+// lazyDecoder defers reading the header until the first Decode since codec.Codec.NewDecoder can't fail. Empty
+// streams (e.g. written by an encoder without a schema nor records) have no records rather than being invalid.
+type lazyDecoder struct {
+	r      io.Reader
+	reader *Reader
+	err    error
+}
+
+func (d *lazyDecoder) Decode(v interface{}) error {
+	if d.reader == nil && d.err == nil {
+		br := bufio.NewReader(d.r)
+		if _, err := br.Peek(1); err == io.EOF {
+			d.err = io.EOF
+		} else {
+			d.reader, d.err = NewReader(br)
+		}
+	}
+	if d.err != nil {
+		return d.err
+	}
+	return d.reader.Decode(v)
+}
+
+// NewRecordIterator returns a RecordIterator over the records of an object container file
+// @new - creator to allocate a new struct (or map) for each record
+// @r - the object container file; closed once all records have been read if it is an io.Closer
+func NewRecordIterator[T any](new func() T, r io.Reader) iterator.RecordIterator[T] {
+	return iterator.CodecRecordIterator(new, r, Codec)
+}
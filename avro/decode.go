@@ -0,0 +1,452 @@
+package avro
+
+import (
+	"encoding"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"reflect"
+	"time"
+)
+
+// decoder reads avro binary encoded values from an in memory block
+type decoder struct {
+	buf []byte
+	pos int
+}
+
+func (d *decoder) long() (int64, error) {
+	n, size := binary.Varint(d.buf[d.pos:])
+	if size <= 0 {
+		return 0, fmt.Errorf("%w: invalid varint", io.ErrUnexpectedEOF)
+	}
+	d.pos += size
+	return n, nil
+}
+
+func (d *decoder) next(n int64) ([]byte, error) {
+	if n < 0 || n > int64(len(d.buf)-d.pos) {
+		return nil, fmt.Errorf("%w: %d bytes requested; %d remaining", io.ErrUnexpectedEOF, n, len(d.buf)-d.pos)
+	}
+	b := d.buf[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return b, nil
+}
+
+func (d *decoder) bytes() ([]byte, error) {
+	n, err := d.long()
+	if err != nil {
+		return nil, err
+	}
+	return d.next(n)
+}
+
+// blockCount reads the item count of an array or map block; negative counts are followed by the block size
+func (d *decoder) blockCount() (int64, error) {
+	n, err := d.long()
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		if _, err := d.long(); err != nil {
+			return 0, err
+		}
+		n = -n
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("%w: invalid block count", ErrInvalidValue)
+	}
+	return n, nil
+}
+
+// maxZeroWidthItems caps the number of items in an array or block when the items are encoded without any bytes
+// (e.g. nulls) since their count can't be bounded by the size of the data
+const maxZeroWidthItems = 1 << 20
+
+// checkCount rejects counts of items which can't fit in the remaining data; total is the number of items already
+// read and zeroWidth tells if the items can be encoded without any bytes
+func (d *decoder) checkCount(n, total int64, zeroWidth bool) error {
+	if zeroWidth {
+		if n > maxZeroWidthItems-total {
+			return fmt.Errorf("%w: more than %d zero width items", ErrBlockTooLarge, maxZeroWidthItems)
+		}
+	} else if remaining := int64(len(d.buf) - d.pos); n > remaining {
+		return fmt.Errorf("%w: %d items with %d bytes remaining", io.ErrUnexpectedEOF, n, remaining)
+	}
+	return nil
+}
+
+// zeroWidth tells if values of the schema can be encoded without any bytes
+func zeroWidth(s *Schema) bool {
+	return isZeroWidth(s, map[*Schema]bool{})
+}
+
+func isZeroWidth(s *Schema, seen map[*Schema]bool) bool {
+	switch s.Type {
+	case TypeNull:
+		return true
+	case TypeFixed:
+		return s.Size == 0
+	case TypeRecord:
+		if seen[s] { // a record containing itself can't be encoded at all
+			return false
+		}
+		seen[s] = true
+		defer delete(seen, s)
+		for _, field := range s.Fields {
+			if !isZeroWidth(field.Type, seen) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// value decodes the next value into its generic go representation; records and maps become map[string]interface{},
+// arrays []interface{}, timestamps and dates time.Time, time-micros a "15:04:05.999999" string and decimals json.Number.
+func (d *decoder) value(s *Schema) (interface{}, error) {
+	switch s.Type {
+	case TypeNull:
+		return nil, nil
+	case TypeBoolean:
+		b, err := d.next(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0] != 0, nil
+	case TypeInt:
+		n, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if s.LogicalType == LogicalDate {
+			return time.Unix(n*24*60*60, 0).UTC(), nil
+		}
+		return int32(n), nil
+	case TypeLong:
+		n, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		switch s.LogicalType {
+		case LogicalTimestampMicros:
+			return time.UnixMicro(n).UTC(), nil
+		case LogicalTimestampMillis:
+			return time.UnixMilli(n).UTC(), nil
+		case LogicalTimeMicros:
+			return time.UnixMicro(n).UTC().Format("15:04:05.999999"), nil
+		}
+		return n, nil
+	case TypeFloat:
+		b, err := d.next(4)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float32frombits(binary.LittleEndian.Uint32(b)), nil
+	case TypeDouble:
+		b, err := d.next(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+	case TypeBytes:
+		b, err := d.bytes()
+		if err != nil {
+			return nil, err
+		}
+		if s.LogicalType == LogicalDecimal {
+			return decimalNumber(b, s.Scale), nil
+		}
+		return append([]byte{}, b...), nil
+	case TypeString:
+		b, err := d.bytes()
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case TypeFixed:
+		b, err := d.next(int64(s.Size))
+		if err != nil {
+			return nil, err
+		}
+		if s.LogicalType == LogicalDecimal {
+			return decimalNumber(b, s.Scale), nil
+		}
+		return append([]byte{}, b...), nil
+	case TypeEnum:
+		index, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if index < 0 || index >= int64(len(s.Symbols)) {
+			return nil, fmt.Errorf("%w: enum index %d out of range for %s", ErrInvalidValue, index, s.Name)
+		}
+		return s.Symbols[index], nil
+	case TypeUnion:
+		index, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if index < 0 || index >= int64(len(s.Branches)) {
+			return nil, fmt.Errorf("%w: union index %d out of range", ErrInvalidValue, index)
+		}
+		return d.value(s.Branches[index])
+	case TypeArray:
+		res := []interface{}{}
+		zero := zeroWidth(s.Items)
+		for {
+			n, err := d.blockCount()
+			if err != nil {
+				return nil, err
+			}
+			if n == 0 {
+				return res, nil
+			}
+			if err := d.checkCount(n, int64(len(res)), zero); err != nil {
+				return nil, err
+			}
+			for ; n > 0; n-- {
+				item, err := d.value(s.Items)
+				if err != nil {
+					return nil, err
+				}
+				res = append(res, item)
+			}
+		}
+	case TypeMap:
+		res := map[string]interface{}{}
+		for {
+			n, err := d.blockCount()
+			if err != nil {
+				return nil, err
+			}
+			if n == 0 {
+				return res, nil
+			}
+			// every item has at least the length of its key
+			if err := d.checkCount(n, 0, false); err != nil {
+				return nil, err
+			}
+			for ; n > 0; n-- {
+				key, err := d.bytes()
+				if err != nil {
+					return nil, err
+				}
+				if res[string(key)], err = d.value(s.Values); err != nil {
+					return nil, err
+				}
+			}
+		}
+	case TypeRecord:
+		res := make(map[string]interface{}, len(s.Fields))
+		for _, field := range s.Fields {
+			var err error
+			if res[field.Name], err = d.value(field.Type); err != nil {
+				return nil, fmt.Errorf("field %s: %w", field.Name, err)
+			}
+		}
+		return res, nil
+	}
+	return nil, fmt.Errorf("%w: unknown type %s", ErrInvalidSchema, s.Type)
+}
+
+// decimalNumber converts a big-endian two's-complement unscaled value to a json.Number
+func decimalNumber(b []byte, scale int) json.Number {
+	n := new(big.Int).SetBytes(b)
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(b)*8)))
+	}
+	r := new(big.Rat).SetFrac(n, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil))
+	return json.Number(r.FloatString(scale))
+}
+
+// assign stores a generic decoded value in dst, converting it to the type of dst where possible.
+// Struct fields are matched by the same names used when deriving schemas with SchemaOf.
+func assign(dst reflect.Value, val interface{}) error {
+	if val == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.Interface:
+		if dst.NumMethod() == 0 {
+			dst.Set(reflect.ValueOf(val))
+			return nil
+		}
+	case reflect.Ptr:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return assign(dst.Elem(), val)
+	}
+
+	switch dst.Type() {
+	case typeOfTime:
+		switch t := val.(type) {
+		case time.Time:
+			dst.Set(reflect.ValueOf(t))
+			return nil
+		case string:
+			for _, layout := range timestampLayouts {
+				if parsed, err := time.Parse(layout, t); err == nil {
+					dst.Set(reflect.ValueOf(parsed))
+					return nil
+				}
+			}
+		}
+		return cantAssign(dst, val)
+	case typeOfRat:
+		r, ok := new(big.Rat).SetString(fmt.Sprint(val))
+		if !ok {
+			return cantAssign(dst, val)
+		}
+		dst.Set(reflect.ValueOf(*r))
+		return nil
+	case typeOfDuration:
+		if clock, ok := val.(string); ok {
+			t, err := time.Parse("15:04:05.999999999", clock)
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidValue, err)
+			}
+			h, m, sec := t.Clock()
+			dst.SetInt(int64(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(sec)*time.Second + time.Duration(t.Nanosecond())))
+			return nil
+		}
+	}
+
+	if str, ok := val.(string); ok && dst.CanAddr() {
+		if u, ok := dst.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(str))
+		}
+	}
+
+	switch dst.Kind() {
+	case reflect.Bool:
+		if b, ok := val.(bool); ok {
+			dst.SetBool(b)
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := toInt64(reflect.ValueOf(val))
+		if err != nil || dst.OverflowInt(n) {
+			return cantAssign(dst, val)
+		}
+		dst.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := toInt64(reflect.ValueOf(val))
+		if err != nil || n < 0 || dst.OverflowUint(uint64(n)) {
+			return cantAssign(dst, val)
+		}
+		dst.SetUint(uint64(n))
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, err := toDouble(reflect.ValueOf(val))
+		if err != nil {
+			return cantAssign(dst, val)
+		}
+		dst.SetFloat(f)
+		return nil
+	case reflect.String:
+		switch v := val.(type) {
+		case string:
+			dst.SetString(v)
+		case []byte:
+			dst.SetString(string(v))
+		case json.Number:
+			dst.SetString(string(v))
+		case time.Time:
+			dst.SetString(v.Format(time.RFC3339Nano))
+		default:
+			str, err := toString(&Schema{Type: TypeString}, reflect.ValueOf(val))
+			if err != nil {
+				return cantAssign(dst, val)
+			}
+			dst.SetString(str)
+		}
+		return nil
+	case reflect.Slice:
+		if dst.Type().Elem().Kind() == reflect.Uint8 {
+			b, err := toBytes(reflect.ValueOf(val))
+			if err != nil {
+				return cantAssign(dst, val)
+			}
+			dst.SetBytes(append([]byte{}, b...))
+			return nil
+		}
+		items, ok := val.([]interface{})
+		if !ok {
+			return cantAssign(dst, val)
+		}
+		slice := reflect.MakeSlice(dst.Type(), len(items), len(items))
+		for index, item := range items {
+			if err := assign(slice.Index(index), item); err != nil {
+				return err
+			}
+		}
+		dst.Set(slice)
+		return nil
+	case reflect.Array:
+		b, ok := val.([]byte)
+		if !ok || dst.Type().Elem().Kind() != reflect.Uint8 || len(b) != dst.Len() {
+			return cantAssign(dst, val)
+		}
+		reflect.Copy(dst, reflect.ValueOf(b))
+		return nil
+	case reflect.Map:
+		values, ok := val.(map[string]interface{})
+		if !ok || dst.Type().Key().Kind() != reflect.String {
+			return cantAssign(dst, val)
+		}
+		m := reflect.MakeMapWithSize(dst.Type(), len(values))
+		for key, value := range values {
+			elem := reflect.New(dst.Type().Elem()).Elem()
+			if err := assign(elem, value); err != nil {
+				return fmt.Errorf("key %s: %w", key, err)
+			}
+			m.SetMapIndex(reflect.ValueOf(key).Convert(dst.Type().Key()), elem)
+		}
+		dst.Set(m)
+		return nil
+	case reflect.Struct:
+		values, ok := val.(map[string]interface{})
+		if !ok {
+			return cantAssign(dst, val)
+		}
+		fields := cachedStructFields(dst.Type())
+		for name, value := range values {
+			sf, ok := fields[name]
+			if !ok {
+				continue
+			}
+			if err := assign(fieldByIndexAlloc(dst, sf.index), value); err != nil {
+				return fmt.Errorf("field %s: %w", name, err)
+			}
+		}
+		return nil
+	}
+	return cantAssign(dst, val)
+}
+
+// fieldByIndexAlloc is like reflect.Value.FieldByIndex but allocates nil embedded struct pointers
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+func cantAssign(dst reflect.Value, val interface{}) error {
+	return fmt.Errorf("%w: can't assign %T to %s", ErrInvalidValue, val, dst.Type())
+}
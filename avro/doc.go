@@ -0,0 +1,4 @@
+// Package avro provides an Avro Object Container File (OCF) writer and reader with null, deflate and
+// snappy block compression. Schemas can be derived from go structs or from a bigquery_schema.TableFieldSchema.
+// The package registers an "avro" codec.Codec on import.
+package avro
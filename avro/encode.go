@@ -0,0 +1,500 @@
+package avro
+
+import (
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// timestampLayouts are tried in order when a string is encoded as a timestamp; strings without a zone are assumed to be UTC
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// appendValue appends the avro binary encoding of v according to the schema. Values are converted leniently
+// (e.g. "123" can be written as a long) since records often originate from loosely typed JSON data.
+func appendValue(buf []byte, s *Schema, v reflect.Value) ([]byte, error) {
+	if s.Type == TypeUnion {
+		return appendUnion(buf, s, v)
+	}
+
+	v = indirect(v)
+	if s.Type == TypeNull {
+		if v.IsValid() {
+			return nil, invalidValue(s, v)
+		}
+		return buf, nil
+	}
+	if !v.IsValid() {
+		return nil, fmt.Errorf("%w: nil value for non nullable %s", ErrInvalidValue, s.Type)
+	}
+
+	switch s.Type {
+	case TypeBoolean:
+		b, err := toBool(v)
+		if err != nil {
+			return nil, err
+		}
+		if b {
+			return append(buf, 1), nil
+		}
+		return append(buf, 0), nil
+	case TypeInt, TypeLong:
+		n, err := toLong(s, v)
+		if err != nil {
+			return nil, err
+		}
+		if s.Type == TypeInt && (n > math.MaxInt32 || n < math.MinInt32) {
+			return nil, fmt.Errorf("%w: %d overflows int", ErrInvalidValue, n)
+		}
+		return binary.AppendVarint(buf, n), nil
+	case TypeFloat:
+		f, err := toDouble(v)
+		if err != nil {
+			return nil, err
+		}
+		return binary.LittleEndian.AppendUint32(buf, math.Float32bits(float32(f))), nil
+	case TypeDouble:
+		f, err := toDouble(v)
+		if err != nil {
+			return nil, err
+		}
+		return binary.LittleEndian.AppendUint64(buf, math.Float64bits(f)), nil
+	case TypeBytes:
+		if s.LogicalType == LogicalDecimal {
+			b, err := toDecimal(v, s.Scale)
+			if err != nil {
+				return nil, err
+			}
+			return appendBytes(buf, b), nil
+		}
+		b, err := toBytes(v)
+		if err != nil {
+			return nil, err
+		}
+		return appendBytes(buf, b), nil
+	case TypeString:
+		str, err := toString(s, v)
+		if err != nil {
+			return nil, err
+		}
+		return appendBytes(buf, []byte(str)), nil
+	case TypeFixed:
+		b, err := toBytes(v)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) != s.Size {
+			return nil, fmt.Errorf("%w: fixed %s requires %d bytes; got %d", ErrInvalidValue, s.Name, s.Size, len(b))
+		}
+		return append(buf, b...), nil
+	case TypeEnum:
+		if v.Kind() != reflect.String {
+			return nil, invalidValue(s, v)
+		}
+		for index, symbol := range s.Symbols {
+			if symbol == v.String() {
+				return binary.AppendVarint(buf, int64(index)), nil
+			}
+		}
+		return nil, fmt.Errorf("%w: %q is not a symbol of enum %s", ErrInvalidValue, v.String(), s.Name)
+	case TypeArray:
+		if (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) || v.Type() == typeOfBytes {
+			return nil, invalidValue(s, v)
+		}
+		if v.Len() > 0 {
+			buf = binary.AppendVarint(buf, int64(v.Len()))
+			for i := 0; i < v.Len(); i++ {
+				var err error
+				if buf, err = appendValue(buf, s.Items, v.Index(i)); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return append(buf, 0), nil
+	case TypeMap:
+		if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+			return nil, invalidValue(s, v)
+		}
+		if v.Len() > 0 {
+			keys := v.MapKeys()
+			sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+			buf = binary.AppendVarint(buf, int64(len(keys)))
+			for _, key := range keys {
+				buf = appendBytes(buf, []byte(key.String()))
+				var err error
+				if buf, err = appendValue(buf, s.Values, v.MapIndex(key)); err != nil {
+					return nil, fmt.Errorf("key %s: %w", key.String(), err)
+				}
+			}
+		}
+		return append(buf, 0), nil
+	case TypeRecord:
+		return appendRecord(buf, s, v)
+	}
+	return nil, fmt.Errorf("%w: unknown type %s", ErrInvalidSchema, s.Type)
+}
+
+func appendRecord(buf []byte, s *Schema, v reflect.Value) ([]byte, error) {
+	var lookup func(name string) (reflect.Value, bool)
+	switch {
+	case v.Kind() == reflect.Struct && v.Type() != typeOfTime:
+		fields := cachedStructFields(v.Type())
+		lookup = func(name string) (reflect.Value, bool) {
+			sf, ok := fields[name]
+			if !ok {
+				return reflect.Value{}, false
+			}
+			fv, err := v.FieldByIndexErr(sf.index)
+			if err != nil { // nil embedded pointer
+				return reflect.Value{}, true
+			}
+			return fv, true
+		}
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		lookup = func(name string) (reflect.Value, bool) {
+			fv := v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+			return fv, fv.IsValid()
+		}
+	default:
+		return nil, invalidValue(s, v)
+	}
+
+	for _, field := range s.Fields {
+		fv, found := lookup(field.Name)
+		if !found && field.HasDefault {
+			fv = reflect.ValueOf(field.Default)
+		}
+		var err error
+		if buf, err = appendValue(buf, field.Type, fv); err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+	}
+	return buf, nil
+}
+
+// appendUnion writes the index of the first branch that can encode the value followed by the value.
+// Branches matching the kind of the value are tried before branches requiring conversions.
+func appendUnion(buf []byte, s *Schema, v reflect.Value) ([]byte, error) {
+	v = indirect(v)
+	if !v.IsValid() {
+		index := s.nullIndex()
+		if index < 0 {
+			return nil, fmt.Errorf("%w: nil value for union without null", ErrInvalidValue)
+		}
+		return binary.AppendVarint(buf, int64(index)), nil
+	}
+
+	var lastErr error
+	for pass := 0; pass < 2; pass++ {
+		for index, branch := range s.Branches {
+			if branch.Type == TypeNull || (pass == 0 && !kindMatches(branch, v)) {
+				continue
+			}
+			out, err := appendValue(binary.AppendVarint(buf, int64(index)), branch, v)
+			if err == nil {
+				return out, nil
+			}
+			lastErr = err
+		}
+	}
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return nil, invalidValue(s, v)
+}
+
+// kindMatches reports if the value is of the natural go kind for the schema
+func kindMatches(s *Schema, v reflect.Value) bool {
+	switch s.Type {
+	case TypeBoolean:
+		return v.Kind() == reflect.Bool
+	case TypeInt, TypeLong:
+		if v.Type() == typeOfTime {
+			return s.LogicalType != ""
+		}
+		return isIntKind(v.Kind())
+	case TypeFloat, TypeDouble:
+		return v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64
+	case TypeString, TypeEnum:
+		return v.Kind() == reflect.String
+	case TypeBytes, TypeFixed:
+		if s.LogicalType == LogicalDecimal {
+			return v.Type() == typeOfRat
+		}
+		return (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() == reflect.Uint8
+	case TypeArray:
+		return (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8
+	case TypeMap:
+		return v.Kind() == reflect.Map
+	case TypeRecord:
+		return v.Kind() == reflect.Map || (v.Kind() == reflect.Struct && v.Type() != typeOfTime && v.Type() != typeOfRat)
+	}
+	return false
+}
+
+func appendBytes(buf []byte, b []byte) []byte {
+	buf = binary.AppendVarint(buf, int64(len(b)))
+	return append(buf, b...)
+}
+
+// indirect dereferences pointers and interfaces; nil yields an invalid reflect.Value
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func invalidValue(s *Schema, v reflect.Value) error {
+	return fmt.Errorf("%w: can't encode %s as %s", ErrInvalidValue, v.Type(), s.Type)
+}
+
+func isIntKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+func toBool(v reflect.Value) (bool, error) {
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.String:
+		b, err := strconv.ParseBool(v.String())
+		if err != nil {
+			return false, fmt.Errorf("%w: %v", ErrInvalidValue, err)
+		}
+		return b, nil
+	}
+	return false, fmt.Errorf("%w: can't encode %s as boolean", ErrInvalidValue, v.Type())
+}
+
+func toLong(s *Schema, v reflect.Value) (int64, error) {
+	switch s.LogicalType {
+	case LogicalTimestampMicros, LogicalTimestampMillis, LogicalDate, LogicalTimeMicros:
+		if s.LogicalType == LogicalTimeMicros && v.Type() == typeOfDuration {
+			return v.Int() / int64(time.Microsecond), nil
+		}
+		t, isTime, err := toTime(s, v)
+		if err != nil {
+			return 0, err
+		}
+		if isTime {
+			switch s.LogicalType {
+			case LogicalTimestampMicros:
+				return t.UnixMicro(), nil
+			case LogicalTimestampMillis:
+				return t.UnixMilli(), nil
+			case LogicalDate:
+				y, m, d := t.Date()
+				return time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / (24 * 60 * 60), nil
+			case LogicalTimeMicros:
+				h, m, sec := t.Clock()
+				return (int64(h*60+m)*60+int64(sec))*1e6 + int64(t.Nanosecond()/1e3), nil
+			}
+		}
+	}
+	return toInt64(v)
+}
+
+// toTime converts time.Time and non numeric strings to time; isTime is false if the value should be treated as a number
+func toTime(s *Schema, v reflect.Value) (t time.Time, isTime bool, err error) {
+	if v.Type() == typeOfTime {
+		return v.Interface().(time.Time), true, nil
+	}
+	if v.Kind() != reflect.String {
+		return t, false, nil
+	}
+	str := v.String()
+	if _, err := strconv.ParseInt(str, 10, 64); err == nil {
+		return t, false, nil
+	}
+
+	if s.LogicalType == LogicalTimeMicros {
+		t, err = time.Parse("15:04:05.999999999", str)
+		if err != nil {
+			return t, true, fmt.Errorf("%w: %v", ErrInvalidValue, err)
+		}
+		return t, true, nil
+	}
+	for _, layout := range timestampLayouts {
+		if t, err = time.Parse(layout, str); err == nil {
+			return t, true, nil
+		}
+	}
+	return t, true, fmt.Errorf("%w: can't parse %q as %s", ErrInvalidValue, str, s.LogicalType)
+}
+
+func toInt64(v reflect.Value) (int64, error) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Uint() > math.MaxInt64 {
+			return 0, fmt.Errorf("%w: %d overflows long", ErrInvalidValue, v.Uint())
+		}
+		return int64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		if f := v.Float(); f == math.Trunc(f) && f >= math.MinInt64 && f <= math.MaxInt64 {
+			return int64(f), nil
+		}
+		return 0, fmt.Errorf("%w: %v is not an integer", ErrInvalidValue, v.Float())
+	case reflect.String:
+		if n, err := strconv.ParseInt(v.String(), 10, 64); err == nil {
+			return n, nil
+		}
+		if f, err := strconv.ParseFloat(v.String(), 64); err == nil && f == math.Trunc(f) {
+			return int64(f), nil
+		}
+		return 0, fmt.Errorf("%w: %q is not an integer", ErrInvalidValue, v.String())
+	}
+	return 0, fmt.Errorf("%w: can't encode %s as long", ErrInvalidValue, v.Type())
+}
+
+func toDouble(v reflect.Value) (float64, error) {
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), nil
+	case reflect.String:
+		f, err := strconv.ParseFloat(v.String(), 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrInvalidValue, err)
+		}
+		return f, nil
+	}
+	return 0, fmt.Errorf("%w: can't encode %s as double", ErrInvalidValue, v.Type())
+}
+
+func toBytes(v reflect.Value) ([]byte, error) {
+	switch {
+	case v.Kind() == reflect.String:
+		return []byte(v.String()), nil
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		return v.Bytes(), nil
+	case v.Kind() == reflect.Array && v.Type().Elem().Kind() == reflect.Uint8:
+		b := make([]byte, v.Len())
+		reflect.Copy(reflect.ValueOf(b), v)
+		return b, nil
+	}
+	return nil, fmt.Errorf("%w: can't encode %s as bytes", ErrInvalidValue, v.Type())
+}
+
+func toString(s *Schema, v reflect.Value) (string, error) {
+	if v.Type() == typeOfTime {
+		if s.LogicalType == LogicalDateTime {
+			return v.Interface().(time.Time).Format("2006-01-02T15:04:05.999999"), nil
+		}
+		return v.Interface().(time.Time).Format(time.RFC3339Nano), nil
+	}
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		b, err := m.MarshalText()
+		return string(b), err
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64), nil
+	case reflect.Map, reflect.Struct, reflect.Slice, reflect.Array:
+		// e.g. BigQuery JSON columns
+		return jsoniter.ConfigCompatibleWithStandardLibrary.MarshalToString(v.Interface())
+	}
+	return "", fmt.Errorf("%w: can't encode %s as string", ErrInvalidValue, v.Type())
+}
+
+// toDecimal returns the two's-complement big-endian representation of the value scaled by 10^scale
+func toDecimal(v reflect.Value, scale int) ([]byte, error) {
+	r := new(big.Rat)
+	switch {
+	case v.Type() == typeOfRat:
+		rat := v.Interface().(big.Rat)
+		r.Set(&rat)
+	case v.Kind() == reflect.String:
+		if _, ok := r.SetString(strings.TrimSpace(v.String())); !ok {
+			return nil, fmt.Errorf("%w: %q is not a decimal", ErrInvalidValue, v.String())
+		}
+	case v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64:
+		if math.IsNaN(v.Float()) || math.IsInf(v.Float(), 0) {
+			return nil, fmt.Errorf("%w: %v is not a decimal", ErrInvalidValue, v.Float())
+		}
+		r.SetString(strconv.FormatFloat(v.Float(), 'f', -1, 64))
+	case isIntKind(v.Kind()):
+		n, err := toInt64(v)
+		if err != nil {
+			return nil, err
+		}
+		r.SetInt64(n)
+	default:
+		return nil, fmt.Errorf("%w: can't encode %s as decimal", ErrInvalidValue, v.Type())
+	}
+
+	// unscaled = round(r * 10^scale)
+	num := new(big.Int).Mul(r.Num(), new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil))
+	unscaled, rem := new(big.Int).QuoRem(num, r.Denom(), new(big.Int))
+	if rem.Sign() != 0 && new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2)).Cmp(r.Denom()) >= 0 {
+		unscaled.Add(unscaled, big.NewInt(int64(num.Sign())))
+	}
+	return twosComplement(unscaled), nil
+}
+
+func twosComplement(n *big.Int) []byte {
+	if n.Sign() >= 0 {
+		b := n.Bytes()
+		if len(b) == 0 || b[0]&0x80 != 0 {
+			b = append([]byte{0}, b...)
+		}
+		return b
+	}
+	size := new(big.Int).Not(n).BitLen()/8 + 1
+	b := new(big.Int).Add(n, new(big.Int).Lsh(big.NewInt(1), uint(size*8))).Bytes()
+	for len(b) < size {
+		b = append([]byte{0xff}, b...)
+	}
+	return b
+}
+
+var structFieldsCache sync.Map // map[reflect.Type]map[string]structField
+
+func cachedStructFields(t reflect.Type) map[string]structField {
+	if fields, ok := structFieldsCache.Load(t); ok {
+		return fields.(map[string]structField)
+	}
+	fields := map[string]structField{}
+	for _, sf := range structFields(t) {
+		fields[sf.name] = sf
+	}
+	structFieldsCache.Store(t, fields)
+	return fields
+}
@@ -0,0 +1,20 @@
+package avro
+
+import "errors"
+
+var (
+	// ErrInvalidSchema is returned when a schema can't be parsed or is inconsistent
+	ErrInvalidSchema = errors.New("invalid avro schema")
+	// ErrUnsupportedType is returned when no avro schema can be derived for a go type
+	ErrUnsupportedType = errors.New("unsupported type for avro schema")
+	// ErrInvalidValue is returned when a value can't be encoded with the schema
+	ErrInvalidValue = errors.New("value does not match avro schema")
+	// ErrNotOCF is returned when the stream isn't an avro object container file
+	ErrNotOCF = errors.New("not an avro object container file")
+	// ErrInvalidSync is returned when a block isn't terminated by the sync marker of the file
+	ErrInvalidSync = errors.New("invalid avro sync marker")
+	// ErrBlockTooLarge is returned when a block or header value exceeds MaxBlockSize or holds too many items
+	ErrBlockTooLarge = errors.New("avro block too large")
+	// ErrUnsupportedCompression is returned for unknown block compression codecs
+	ErrUnsupportedCompression = errors.New("unsupported avro compression codec")
+)
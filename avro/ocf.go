@@ -0,0 +1,442 @@
+package avro
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/klauspost/compress/snappy"
+)
+
+// Compression is the codec used to compress the blocks of an object container file
+type Compression string
+
+// Supported block compressions
+const (
+	CompressionNull    Compression = "null"
+	CompressionDeflate Compression = "deflate"
+	CompressionSnappy  Compression = "snappy"
+)
+
+// DefaultBlockSize is the number of (uncompressed) bytes buffered before a block is written
+const DefaultBlockSize = 64 * 1024
+
+// MaxBlockSize is the max size of a block (compressed as well as uncompressed) or header value accepted by a Reader;
+// larger sizes are rejected with ErrBlockTooLarge rather than allocated
+const MaxBlockSize = 64 << 20
+
+const (
+	metaSchema = "avro.schema"
+	metaCodec  = "avro.codec"
+	syncSize   = 16
+)
+
+var magic = []byte{'O', 'b', 'j', 1}
+
+// WriterOptions configures a Writer
+type WriterOptions struct {
+	// Compression of the blocks; defaults to CompressionNull
+	Compression Compression
+	// CompressionLevel is passed to deflate; 0 means flate.DefaultCompression
+	CompressionLevel int
+	// BlockSize is the approximate uncompressed size of each block; defaults to DefaultBlockSize
+	BlockSize int
+	// Metadata is added to the file header; keys must not start with "avro."
+	Metadata map[string]string
+}
+
+// Writer writes records to an avro object container file. Records are buffered in blocks which
+// are written to the underlying writer in a single Write call each.
+type Writer struct {
+	w      io.Writer
+	schema *Schema
+	opts   WriterOptions
+	sync   [syncSize]byte
+
+	headerWritten bool
+	block         []byte
+	count         int64
+	compressed    bytes.Buffer
+	flateWriter   *flate.Writer
+}
+
+// NewWriter returns a Writer writing an object container file to w. If schema is nil it is derived
+// with SchemaOf from the first record encoded. The header is written together with the first block.
+func NewWriter(w io.Writer, schema *Schema, opts WriterOptions) *Writer {
+	if opts.Compression == "" {
+		opts.Compression = CompressionNull
+	}
+	if opts.BlockSize <= 0 {
+		opts.BlockSize = DefaultBlockSize
+	}
+	if opts.CompressionLevel == 0 {
+		opts.CompressionLevel = flate.DefaultCompression
+	}
+	writer := &Writer{
+		w:      w,
+		schema: schema,
+		opts:   opts,
+	}
+	_, _ = rand.Read(writer.sync[:])
+	return writer
+}
+
+// Schema returns the schema of the file; nil until the first record has been written if no schema was provided
+func (w *Writer) Schema() *Schema {
+	return w.schema
+}
+
+// Encode appends a record (struct or map[string]interface{}) to the current block
+func (w *Writer) Encode(v interface{}) error {
+	switch w.opts.Compression {
+	case CompressionNull, CompressionDeflate, CompressionSnappy:
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedCompression, w.opts.Compression)
+	}
+
+	if w.schema == nil {
+		schema, err := SchemaOf(v)
+		if err != nil {
+			return err
+		}
+		w.schema = schema
+	}
+
+	block, err := appendValue(w.block, w.schema, reflect.ValueOf(v))
+	if err != nil {
+		return err
+	}
+	w.block = block
+	w.count++
+
+	if len(w.block) >= w.opts.BlockSize {
+		return w.Flush()
+	}
+	return nil
+}
+
+// Flush writes the buffered records as a block
+func (w *Writer) Flush() error {
+	if w.count == 0 {
+		return nil
+	}
+
+	data, err := w.compress(w.block)
+	if err != nil {
+		return err
+	}
+
+	var out []byte
+	if !w.headerWritten {
+		if out, err = w.appendHeader(out); err != nil {
+			return err
+		}
+	}
+	out = binary.AppendVarint(out, w.count)
+	out = binary.AppendVarint(out, int64(len(data)))
+	out = append(out, data...)
+	out = append(out, w.sync[:]...)
+	if _, err := w.w.Write(out); err != nil {
+		return err
+	}
+
+	w.headerWritten = true
+	w.block = w.block[:0]
+	w.count = 0
+	return nil
+}
+
+// Close flushes any buffered records. The underlying writer is not closed. If no records were written
+// with a schema provided a header-only file is written, without a schema nothing is written.
+func (w *Writer) Close() error {
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if w.headerWritten || w.schema == nil {
+		return nil
+	}
+	header, err := w.appendHeader(nil)
+	if err != nil {
+		return err
+	}
+	w.headerWritten = true
+	_, err = w.w.Write(header)
+	return err
+}
+
+func (w *Writer) appendHeader(buf []byte) ([]byte, error) {
+	schema, err := w.schema.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	meta := map[string][]byte{
+		metaSchema: schema,
+		metaCodec:  []byte(w.opts.Compression),
+	}
+	for key, value := range w.opts.Metadata {
+		if strings.HasPrefix(key, "avro.") {
+			return nil, fmt.Errorf("%w: metadata key %s is reserved", ErrInvalidValue, key)
+		}
+		meta[key] = []byte(value)
+	}
+	keys := make([]string, 0, len(meta))
+	for key := range meta {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	buf = append(buf, magic...)
+	buf = binary.AppendVarint(buf, int64(len(keys)))
+	for _, key := range keys {
+		buf = appendBytes(buf, []byte(key))
+		buf = appendBytes(buf, meta[key])
+	}
+	buf = append(buf, 0)
+	return append(buf, w.sync[:]...), nil
+}
+
+func (w *Writer) compress(data []byte) ([]byte, error) {
+	switch w.opts.Compression {
+	case CompressionDeflate:
+		w.compressed.Reset()
+		if w.flateWriter == nil {
+			fw, err := flate.NewWriter(&w.compressed, w.opts.CompressionLevel)
+			if err != nil {
+				return nil, err
+			}
+			w.flateWriter = fw
+		} else {
+			w.flateWriter.Reset(&w.compressed)
+		}
+		if _, err := w.flateWriter.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.flateWriter.Close(); err != nil {
+			return nil, err
+		}
+		return w.compressed.Bytes(), nil
+	case CompressionSnappy:
+		out := snappy.Encode(nil, data)
+		return binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(data)), nil
+	}
+	return data, nil
+}
+
+// Reader reads records from an avro object container file
+type Reader struct {
+	r           *bufio.Reader
+	schema      *Schema
+	compression Compression
+	metadata    map[string]string
+	sync        [syncSize]byte
+
+	block decoder
+	count int64
+}
+
+// NewReader reads the header of the object container file and returns a Reader for its records
+func NewReader(r io.Reader) (*Reader, error) {
+	reader := &Reader{r: bufio.NewReader(r), metadata: map[string]string{}}
+
+	header := make([]byte, len(magic))
+	if _, err := io.ReadFull(reader.r, header); err != nil || !bytes.Equal(header, magic) {
+		return nil, ErrNotOCF
+	}
+
+	for {
+		n, err := reader.blockCount()
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			break
+		}
+		for ; n > 0; n-- {
+			key, err := reader.readBytes()
+			if err != nil {
+				return nil, err
+			}
+			value, err := reader.readBytes()
+			if err != nil {
+				return nil, err
+			}
+			switch string(key) {
+			case metaSchema:
+				if reader.schema, err = ParseSchema(value); err != nil {
+					return nil, err
+				}
+			case metaCodec:
+				reader.compression = Compression(value)
+			default:
+				reader.metadata[string(key)] = string(value)
+			}
+		}
+	}
+	if reader.schema == nil {
+		return nil, fmt.Errorf("%w: header without schema", ErrNotOCF)
+	}
+	switch reader.compression {
+	case "":
+		reader.compression = CompressionNull
+	case CompressionNull, CompressionDeflate, CompressionSnappy:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedCompression, reader.compression)
+	}
+
+	if _, err := io.ReadFull(reader.r, reader.sync[:]); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotOCF, err)
+	}
+	return reader, nil
+}
+
+// Schema returns the writer schema of the file
+func (r *Reader) Schema() *Schema {
+	return r.schema
+}
+
+// Metadata returns the user metadata of the file header (excluding the reserved avro.* keys)
+func (r *Reader) Metadata() map[string]string {
+	return r.metadata
+}
+
+// Next returns the next record in its generic representation (map[string]interface{} for record schemas);
+// io.EOF is returned once all records have been read.
+func (r *Reader) Next() (interface{}, error) {
+	for r.count == 0 {
+		if err := r.readBlock(); err != nil {
+			return nil, err
+		}
+	}
+	r.count--
+	return r.block.value(r.schema)
+}
+
+// Decode reads the next record into v which must be a non nil pointer; io.EOF is returned once all records have been read.
+func (r *Reader) Decode(v interface{}) error {
+	dst := reflect.ValueOf(v)
+	if dst.Kind() != reflect.Ptr || dst.IsNil() {
+		return fmt.Errorf("%w: Decode requires a non nil pointer; got %T", ErrInvalidValue, v)
+	}
+	val, err := r.Next()
+	if err != nil {
+		return err
+	}
+	return assign(dst.Elem(), val)
+}
+
+func (r *Reader) readBlock() error {
+	count, err := binary.ReadVarint(r.r)
+	if err == io.EOF {
+		return io.EOF
+	} else if err != nil {
+		return err
+	}
+	size, err := binary.ReadVarint(r.r)
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+	if count < 0 || size < 0 {
+		return fmt.Errorf("%w: negative block count or size", ErrInvalidValue)
+	}
+	if size > MaxBlockSize {
+		return fmt.Errorf("%w: %d bytes", ErrBlockTooLarge, size)
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		return unexpectedEOF(err)
+	}
+	var sync [syncSize]byte
+	if _, err := io.ReadFull(r.r, sync[:]); err != nil {
+		return unexpectedEOF(err)
+	}
+	if sync != r.sync {
+		return ErrInvalidSync
+	}
+
+	if data, err = r.decompress(data); err != nil {
+		return err
+	}
+	r.block = decoder{buf: data}
+	if err := r.block.checkCount(count, 0, zeroWidth(r.schema)); err != nil {
+		return err
+	}
+	r.count = count
+	return nil
+}
+
+func (r *Reader) decompress(data []byte) ([]byte, error) {
+	switch r.compression {
+	case CompressionDeflate:
+		out, err := io.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(data)), MaxBlockSize+1))
+		if err == nil && len(out) > MaxBlockSize {
+			return nil, fmt.Errorf("%w: more than %d bytes decompressed", ErrBlockTooLarge, MaxBlockSize)
+		}
+		return out, err
+	case CompressionSnappy:
+		if len(data) < 4 {
+			return nil, fmt.Errorf("%w: snappy block without checksum", io.ErrUnexpectedEOF)
+		}
+		if n, err := snappy.DecodedLen(data[:len(data)-4]); err != nil {
+			return nil, err
+		} else if n > MaxBlockSize {
+			return nil, fmt.Errorf("%w: %d bytes decompressed", ErrBlockTooLarge, n)
+		}
+		out, err := snappy.Decode(nil, data[:len(data)-4])
+		if err != nil {
+			return nil, err
+		}
+		if crc32.ChecksumIEEE(out) != binary.BigEndian.Uint32(data[len(data)-4:]) {
+			return nil, fmt.Errorf("%w: snappy checksum mismatch", ErrInvalidValue)
+		}
+		return out, nil
+	}
+	return data, nil
+}
+
+func (r *Reader) blockCount() (int64, error) {
+	n, err := binary.ReadVarint(r.r)
+	if err != nil {
+		return 0, unexpectedEOF(err)
+	}
+	if n < 0 {
+		if _, err := binary.ReadVarint(r.r); err != nil {
+			return 0, unexpectedEOF(err)
+		}
+		n = -n
+	}
+	return n, nil
+}
+
+func (r *Reader) readBytes() ([]byte, error) {
+	n, err := binary.ReadVarint(r.r)
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	if n < 0 {
+		return nil, fmt.Errorf("%w: negative length", ErrInvalidValue)
+	}
+	if n > MaxBlockSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrBlockTooLarge, n)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r.r, b); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return b, nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
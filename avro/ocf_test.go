@@ -0,0 +1,228 @@
+package avro_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/kvanticoss/goutils/v2/avro"
+	"github.com/kvanticoss/goutils/v2/codec"
+	"github.com/kvanticoss/goutils/v2/iterator"
+	"github.com/kvanticoss/goutils/v2/iterator/test_utils"
+	"github.com/kvanticoss/goutils/v2/recordwriter"
+	"github.com/kvanticoss/goutils/v2/writerfactory"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type inner struct {
+	Key   string `json:"key"`
+	Value *int   `json:"value"`
+}
+
+type testRecord struct {
+	Name    string            `json:"name"`
+	Count   int               `json:"count"`
+	Score   float64           `json:"score"`
+	Active  bool              `json:"active"`
+	Tags    []string          `json:"tags"`
+	Attrs   map[string]string `json:"attrs"`
+	Created time.Time         `json:"created"`
+	Amount  big.Rat           `json:"amount"`
+	Inner   *inner            `json:"inner"`
+	Skipped string            `json:"-"`
+}
+
+func testRecords() []testRecord {
+	value := 42
+	return []testRecord{
+		{
+			Name:    "a",
+			Count:   1,
+			Score:   0.5,
+			Active:  true,
+			Tags:    []string{"t1", "t2"},
+			Attrs:   map[string]string{"k": "v"},
+			Created: time.Date(2023, 1, 2, 3, 4, 5, 6000, time.UTC),
+			Amount:  *big.NewRat(-12345, 100),
+			Inner:   &inner{Key: "k", Value: &value},
+		},
+		{
+			Name:    "b",
+			Count:   -2,
+			Tags:    []string{},
+			Attrs:   map[string]string{},
+			Created: time.Unix(0, 0).UTC(),
+			Amount:  *big.NewRat(1, 4),
+		},
+	}
+}
+
+func TestWriterReaderRoundTrip(t *testing.T) {
+	for _, compression := range []avro.Compression{avro.CompressionNull, avro.CompressionDeflate, avro.CompressionSnappy} {
+		t.Run(string(compression), func(t *testing.T) {
+			var buf bytes.Buffer
+			w := avro.NewWriter(&buf, nil, avro.WriterOptions{
+				Compression: compression,
+				BlockSize:   1, // one block per record
+				Metadata:    map[string]string{"origin": "test"},
+			})
+			for _, r := range testRecords() {
+				assert.NoError(t, w.Encode(r))
+			}
+			assert.NoError(t, w.Close())
+
+			r, err := avro.NewReader(&buf)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, "test", r.Metadata()["origin"])
+			assert.Equal(t, w.Schema().String(), r.Schema().String())
+
+			for _, expected := range testRecords() {
+				var record testRecord
+				assert.NoError(t, r.Decode(&record))
+				assert.Equal(t, expected, record)
+			}
+			assert.Equal(t, io.EOF, r.Decode(&testRecord{}))
+		})
+	}
+}
+
+func TestReaderGenericRecords(t *testing.T) {
+	schema, err := avro.ParseSchema([]byte(`{"type": "record", "name": "r", "fields": [
+		{"name": "id", "type": "long"},
+		{"name": "label", "type": ["null", "string"], "default": null}
+	]}`))
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	w := avro.NewWriter(&buf, schema, avro.WriterOptions{})
+	assert.NoError(t, w.Encode(map[string]interface{}{"id": "7", "label": "x"}))
+	assert.NoError(t, w.Encode(map[string]interface{}{"id": 8}))
+	assert.NoError(t, w.Close())
+
+	r, err := avro.NewReader(&buf)
+	assert.NoError(t, err)
+	record, err := r.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"id": int64(7), "label": "x"}, record)
+	record, err = r.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"id": int64(8), "label": nil}, record)
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestWriterRejectsInvalidValues(t *testing.T) {
+	schema, err := avro.ParseSchema([]byte(`{"type": "record", "name": "r", "fields": [{"name": "id", "type": "int"}]}`))
+	assert.NoError(t, err)
+
+	w := avro.NewWriter(io.Discard, schema, avro.WriterOptions{})
+	assert.ErrorIs(t, w.Encode(map[string]interface{}{"id": "not a number"}), avro.ErrInvalidValue)
+	assert.ErrorIs(t, w.Encode(map[string]interface{}{"id": int64(1) << 40}), avro.ErrInvalidValue)
+	assert.ErrorIs(t, w.Encode(map[string]interface{}{}), avro.ErrInvalidValue)
+}
+
+func TestReaderRejectsCorruptFiles(t *testing.T) {
+	_, err := avro.NewReader(bytes.NewReader([]byte("not avro")))
+	assert.ErrorIs(t, err, avro.ErrNotOCF)
+
+	var buf bytes.Buffer
+	w := avro.NewWriter(&buf, nil, avro.WriterOptions{})
+	assert.NoError(t, w.Encode(testRecords()[1]))
+	assert.NoError(t, w.Close())
+	data := buf.Bytes()
+	data[len(data)-1] ^= 0xff // break the trailing sync marker
+
+	r, err := avro.NewReader(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.ErrorIs(t, r.Decode(&testRecord{}), avro.ErrInvalidSync)
+}
+
+func TestReaderRejectsLargeBlocks(t *testing.T) {
+	schema, err := avro.ParseSchema([]byte(`{"type": "record", "name": "r", "fields": [{"name": "id", "type": "int"}]}`))
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	assert.NoError(t, avro.NewWriter(&buf, schema, avro.WriterOptions{}).Close()) // header only
+
+	data := binary.AppendVarint(buf.Bytes(), 1)           // record count
+	data = binary.AppendVarint(data, avro.MaxBlockSize+1) // block size
+	r, err := avro.NewReader(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.ErrorIs(t, r.Decode(&struct{ ID int }{}), avro.ErrBlockTooLarge)
+}
+
+func TestReaderRejectsLargeCounts(t *testing.T) {
+	block := func(schemaJSON string, count int64, data []byte) *avro.Reader {
+		schema, err := avro.ParseSchema([]byte(schemaJSON))
+		assert.NoError(t, err)
+		var buf bytes.Buffer
+		w := avro.NewWriter(&buf, schema, avro.WriterOptions{})
+		assert.NoError(t, w.Close()) // header only
+		sync := buf.Bytes()[buf.Len()-16:]
+
+		b := binary.AppendVarint(buf.Bytes(), count)
+		b = binary.AppendVarint(b, int64(len(data)))
+		b = append(append(b, data...), sync...)
+		r, err := avro.NewReader(bytes.NewReader(b))
+		assert.NoError(t, err)
+		return r
+	}
+
+	// More records than bytes in the block
+	r := block(`{"type": "record", "name": "r", "fields": [{"name": "id", "type": "int"}]}`, 1<<40, []byte{2})
+	assert.ErrorIs(t, r.Decode(&struct{ ID int }{}), io.ErrUnexpectedEOF)
+
+	// More array items than bytes in the block
+	data := binary.AppendVarint(nil, 1<<40)
+	r = block(`{"type": "record", "name": "r", "fields": [{"name": "a", "type": {"type": "array", "items": "int"}}]}`, 1, data)
+	assert.ErrorIs(t, r.Decode(&struct{ A []int }{}), io.ErrUnexpectedEOF)
+
+	// Items without width are capped in total
+	data = binary.AppendVarint(nil, 1<<40)
+	r = block(`{"type": "record", "name": "r", "fields": [{"name": "a", "type": {"type": "array", "items": "null"}}]}`, 1, data)
+	assert.ErrorIs(t, r.Decode(&map[string]interface{}{}), avro.ErrBlockTooLarge)
+
+	// Negated minimum counts don't wrap around
+	data = binary.AppendVarint(nil, math.MinInt64)
+	data = binary.AppendVarint(data, 0)
+	r = block(`{"type": "record", "name": "r", "fields": [{"name": "a", "type": {"type": "array", "items": "int"}}]}`, 1, data)
+	assert.ErrorIs(t, r.Decode(&struct{ A []int }{}), avro.ErrInvalidValue)
+}
+
+func TestCodecWithPartitionedBySize(t *testing.T) {
+	c, err := codec.Lookup("avro")
+	assert.NoError(t, err)
+	assert.Equal(t, ".avro", c.Extension())
+
+	// Size rotation happens on block boundaries; use one block per record
+	c = avro.NewCodec(nil, avro.WriterOptions{BlockSize: 1})
+	db, wf := writerfactory.GetMemoryWriterFactory()
+	records := testRecords()
+	err = recordwriter.PartitionedBySize(test_utils.NewDummyIteratorFromArr(records), wf, 1, "test", false, c)
+	assert.Equal(t, iterator.ErrIteratorStop, err)
+	assert.Len(t, db, 2)
+
+	// Without records (nor schema) the first file is empty but still readable
+	emptyDB, emptyWF := writerfactory.GetMemoryWriterFactory()
+	err = recordwriter.PartitionedBySize(test_utils.NewDummyIteratorFromArr([]testRecord{}), emptyWF, 1, "empty", false, avro.Codec)
+	assert.Equal(t, iterator.ErrIteratorStop, err)
+	assert.Equal(t, 0, emptyDB["empty_000000.avro"].Len())
+	_, err = avro.NewRecordIterator(func() *testRecord { return &testRecord{} }, emptyDB["empty_000000.avro"])()
+	assert.Equal(t, iterator.ErrIteratorStop, err)
+
+	// Every file is a complete object container file
+	for index, name := range []string{"test_000000.avro", "test_000001.avro"} {
+		it := avro.NewRecordIterator(func() *testRecord { return &testRecord{} }, bytes.NewReader(db[name].Bytes()))
+		record, err := it()
+		assert.NoError(t, err, name)
+		assert.Equal(t, records[index], *record)
+		_, err = it()
+		assert.Equal(t, iterator.ErrIteratorStop, err)
+	}
+}
@@ -0,0 +1,313 @@
+package avro
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Avro schema types
+const (
+	TypeNull    = "null"
+	TypeBoolean = "boolean"
+	TypeInt     = "int"
+	TypeLong    = "long"
+	TypeFloat   = "float"
+	TypeDouble  = "double"
+	TypeBytes   = "bytes"
+	TypeString  = "string"
+	TypeRecord  = "record"
+	TypeEnum    = "enum"
+	TypeArray   = "array"
+	TypeMap     = "map"
+	TypeFixed   = "fixed"
+	// TypeUnion is used for schemas that are a JSON array of branches
+	TypeUnion = "union"
+)
+
+// Avro logical types
+const (
+	LogicalDecimal         = "decimal"
+	LogicalDate            = "date"
+	LogicalTimeMicros      = "time-micros"
+	LogicalTimestampMillis = "timestamp-millis"
+	LogicalTimestampMicros = "timestamp-micros"
+	// LogicalDateTime is the BigQuery extension for DATETIME columns (a string)
+	LogicalDateTime = "datetime"
+)
+
+// Schema is an avro schema; see https://avro.apache.org/docs/1.11.1/specification/
+type Schema struct {
+	Type        string
+	Name        string    // record, enum and fixed
+	Namespace   string    // record, enum and fixed
+	Doc         string    // record and enum
+	Fields      []*Field  // record
+	Symbols     []string  // enum
+	Items       *Schema   // array
+	Values      *Schema   // map
+	Branches    []*Schema // union
+	Size        int       // fixed
+	LogicalType string
+	Precision   int // decimal
+	Scale       int // decimal
+}
+
+// Field is a field of a record schema
+type Field struct {
+	Name string
+	Doc  string
+	Type *Schema
+	// Default is used when a record lacks the field; only valid if HasDefault is true
+	Default    interface{}
+	HasDefault bool
+}
+
+// FullName returns the namespace qualified name of named types
+func (s *Schema) FullName() string {
+	if s.Namespace == "" || strings.Contains(s.Name, ".") {
+		return s.Name
+	}
+	return s.Namespace + "." + s.Name
+}
+
+// IsNamed reports if the schema is a named type (record, enum or fixed)
+func (s *Schema) IsNamed() bool {
+	return s.Type == TypeRecord || s.Type == TypeEnum || s.Type == TypeFixed
+}
+
+// Nullable wraps the schema in an union with null (as the first branch); unions are returned with null added if missing.
+func Nullable(s *Schema) *Schema {
+	if s.Type == TypeUnion {
+		if s.nullIndex() >= 0 {
+			return s
+		}
+		return &Schema{Type: TypeUnion, Branches: append([]*Schema{{Type: TypeNull}}, s.Branches...)}
+	}
+	return &Schema{Type: TypeUnion, Branches: []*Schema{{Type: TypeNull}, s}}
+}
+
+func (s *Schema) nullIndex() int {
+	for index, branch := range s.Branches {
+		if branch.Type == TypeNull {
+			return index
+		}
+	}
+	return -1
+}
+
+// String returns the JSON representation of the schema
+func (s *Schema) String() string {
+	d, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Sprintf("invalid schema: %v", err)
+	}
+	return string(d)
+}
+
+// MarshalJSON returns the avro JSON representation of the schema. Named types are only defined once,
+// subsequent uses are references by name.
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.toJSON(map[string]bool{}))
+}
+
+func (s *Schema) toJSON(defined map[string]bool) interface{} {
+	if s.IsNamed() {
+		if defined[s.FullName()] {
+			return s.FullName()
+		}
+		defined[s.FullName()] = true
+	}
+
+	res := map[string]interface{}{"type": s.Type}
+	switch s.Type {
+	case TypeUnion:
+		branches := make([]interface{}, len(s.Branches))
+		for index, branch := range s.Branches {
+			branches[index] = branch.toJSON(defined)
+		}
+		return branches
+	case TypeRecord:
+		fields := make([]interface{}, len(s.Fields))
+		for index, field := range s.Fields {
+			f := map[string]interface{}{
+				"name": field.Name,
+				"type": field.Type.toJSON(defined),
+			}
+			if field.Doc != "" {
+				f["doc"] = field.Doc
+			}
+			if field.HasDefault {
+				f["default"] = field.Default
+			}
+			fields[index] = f
+		}
+		res["fields"] = fields
+	case TypeEnum:
+		res["symbols"] = s.Symbols
+	case TypeArray:
+		res["items"] = s.Items.toJSON(defined)
+	case TypeMap:
+		res["values"] = s.Values.toJSON(defined)
+	case TypeFixed:
+		res["size"] = s.Size
+	default:
+		if s.LogicalType == "" {
+			return s.Type
+		}
+	}
+
+	if s.IsNamed() {
+		res["name"] = s.Name
+		if s.Namespace != "" {
+			res["namespace"] = s.Namespace
+		}
+	}
+	if s.Doc != "" {
+		res["doc"] = s.Doc
+	}
+	if s.LogicalType != "" {
+		res["logicalType"] = s.LogicalType
+		if s.LogicalType == LogicalDecimal {
+			res["precision"] = s.Precision
+			res["scale"] = s.Scale
+		}
+	}
+	return res
+}
+
+// ParseSchema parses an avro schema from its JSON representation
+func ParseSchema(schema []byte) (*Schema, error) {
+	var raw interface{}
+	if err := json.Unmarshal(schema, &raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	return parseSchema(raw, "", map[string]*Schema{})
+}
+
+func parseSchema(raw interface{}, namespace string, named map[string]*Schema) (*Schema, error) {
+	switch t := raw.(type) {
+	case string:
+		switch t {
+		case TypeNull, TypeBoolean, TypeInt, TypeLong, TypeFloat, TypeDouble, TypeBytes, TypeString:
+			return &Schema{Type: t}, nil
+		}
+		if s, ok := named[t]; ok {
+			return s, nil
+		}
+		if s, ok := named[namespace+"."+t]; ok {
+			return s, nil
+		}
+		return nil, fmt.Errorf("%w: unknown type %s", ErrInvalidSchema, t)
+	case []interface{}:
+		s := &Schema{Type: TypeUnion}
+		for _, rawBranch := range t {
+			branch, err := parseSchema(rawBranch, namespace, named)
+			if err != nil {
+				return nil, err
+			}
+			s.Branches = append(s.Branches, branch)
+		}
+		return s, nil
+	case map[string]interface{}:
+		return parseComplexSchema(t, namespace, named)
+	}
+	return nil, fmt.Errorf("%w: unexpected %T", ErrInvalidSchema, raw)
+}
+
+func parseComplexSchema(raw map[string]interface{}, namespace string, named map[string]*Schema) (*Schema, error) {
+	typeName, ok := raw["type"].(string)
+	if !ok {
+		// {"type": {...}} and {"type": [...]} are allowed as wrappers
+		if inner, ok := raw["type"]; ok {
+			return parseSchema(inner, namespace, named)
+		}
+		return nil, fmt.Errorf("%w: missing type", ErrInvalidSchema)
+	}
+
+	s := &Schema{Type: typeName}
+	s.Doc, _ = raw["doc"].(string)
+	s.LogicalType, _ = raw["logicalType"].(string)
+	if precision, ok := raw["precision"].(float64); ok {
+		s.Precision = int(precision)
+	}
+	if scale, ok := raw["scale"].(float64); ok {
+		s.Scale = int(scale)
+	}
+
+	switch typeName {
+	case TypeNull, TypeBoolean, TypeInt, TypeLong, TypeFloat, TypeDouble, TypeBytes, TypeString:
+		return s, nil
+	case TypeArray:
+		items, err := parseSchema(raw["items"], namespace, named)
+		if err != nil {
+			return nil, err
+		}
+		s.Items = items
+		return s, nil
+	case TypeMap:
+		values, err := parseSchema(raw["values"], namespace, named)
+		if err != nil {
+			return nil, err
+		}
+		s.Values = values
+		return s, nil
+	case TypeRecord, TypeEnum, TypeFixed, "error":
+	default:
+		// A reference to a named type with additional attributes
+		return parseSchema(typeName, namespace, named)
+	}
+
+	if typeName == "error" {
+		s.Type = TypeRecord
+	}
+	s.Name, _ = raw["name"].(string)
+	if s.Name == "" {
+		return nil, fmt.Errorf("%w: %s without name", ErrInvalidSchema, typeName)
+	}
+	s.Namespace, _ = raw["namespace"].(string)
+	if s.Namespace == "" && !strings.Contains(s.Name, ".") {
+		s.Namespace = namespace
+	}
+	named[s.FullName()] = s
+
+	// Nested types inherit the namespace of the enclosing named type
+	childNamespace := s.Namespace
+	if index := strings.LastIndex(s.Name, "."); index >= 0 {
+		childNamespace = s.Name[:index]
+	}
+
+	switch s.Type {
+	case TypeRecord:
+		rawFields, _ := raw["fields"].([]interface{})
+		for _, rawField := range rawFields {
+			fieldMap, ok := rawField.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%w: invalid field in %s", ErrInvalidSchema, s.Name)
+			}
+			field := &Field{}
+			field.Name, _ = fieldMap["name"].(string)
+			field.Doc, _ = fieldMap["doc"].(string)
+			field.Default, field.HasDefault = fieldMap["default"]
+			fieldType, err := parseSchema(fieldMap["type"], childNamespace, named)
+			if err != nil {
+				return nil, fmt.Errorf("field %s.%s: %w", s.Name, field.Name, err)
+			}
+			field.Type = fieldType
+			s.Fields = append(s.Fields, field)
+		}
+	case TypeEnum:
+		rawSymbols, _ := raw["symbols"].([]interface{})
+		for _, rawSymbol := range rawSymbols {
+			symbol, _ := rawSymbol.(string)
+			s.Symbols = append(s.Symbols, symbol)
+		}
+	case TypeFixed:
+		size, ok := raw["size"].(float64)
+		if !ok {
+			return nil, fmt.Errorf("%w: fixed %s without size", ErrInvalidSchema, s.Name)
+		}
+		s.Size = int(size)
+	}
+	return s, nil
+}
@@ -0,0 +1,259 @@
+package avro
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/kvanticoss/goutils/v2/iterator/bigquery_schema"
+)
+
+var (
+	typeOfTime     = reflect.TypeOf(time.Time{})
+	typeOfDuration = reflect.TypeOf(time.Duration(0))
+	typeOfRat      = reflect.TypeOf(big.Rat{})
+	typeOfBytes    = reflect.TypeOf([]byte{})
+)
+
+// DefaultDecimalPrecision and DefaultDecimalScale are used for *big.Rat fields and BigQuery NUMERIC columns
+const (
+	DefaultDecimalPrecision = 38
+	DefaultDecimalScale     = 9
+)
+
+// SchemaOf derives an avro record schema from the (struct) type of v.
+// Fields are named by their `avro` tag, falling back to the `json` tag and the field name; a tag of "-" skips the field.
+// Pointers become nullable, time.Time becomes a timestamp-micros long and big.Rat a decimal.
+func SchemaOf(v interface{}) (*Schema, error) {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return nil, fmt.Errorf("%w: nil", ErrUnsupportedType)
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: %s; only structs can be used as records", ErrUnsupportedType, t)
+	}
+	return schemaOfType(t, map[reflect.Type]*Schema{})
+}
+
+func schemaOfType(t reflect.Type, defined map[reflect.Type]*Schema) (*Schema, error) {
+	switch t {
+	case typeOfTime:
+		return &Schema{Type: TypeLong, LogicalType: LogicalTimestampMicros}, nil
+	case typeOfDuration:
+		return &Schema{Type: TypeLong}, nil
+	case typeOfRat:
+		return &Schema{Type: TypeBytes, LogicalType: LogicalDecimal, Precision: DefaultDecimalPrecision, Scale: DefaultDecimalScale}, nil
+	case typeOfBytes:
+		return &Schema{Type: TypeBytes}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: TypeBoolean}, nil
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: TypeInt}, nil
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: TypeLong}, nil
+	case reflect.Float32:
+		return &Schema{Type: TypeFloat}, nil
+	case reflect.Float64:
+		return &Schema{Type: TypeDouble}, nil
+	case reflect.String:
+		return &Schema{Type: TypeString}, nil
+	case reflect.Ptr:
+		inner, err := schemaOfType(t.Elem(), defined)
+		if err != nil {
+			return nil, err
+		}
+		return Nullable(inner), nil
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Array && t.Elem().Kind() == reflect.Uint8 {
+			if s, ok := defined[t]; ok {
+				return s, nil
+			}
+			s := &Schema{Type: TypeFixed, Name: avroName(t.String()), Size: t.Len()}
+			defined[t] = s
+			return s, nil
+		}
+		items, err := schemaOfType(t.Elem(), defined)
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: TypeArray, Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("%w: %s; map keys must be strings", ErrUnsupportedType, t)
+		}
+		values, err := schemaOfType(t.Elem(), defined)
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: TypeMap, Values: values}, nil
+	case reflect.Struct:
+		if s, ok := defined[t]; ok {
+			return s, nil
+		}
+		s := &Schema{Type: TypeRecord, Name: avroName(t.Name())}
+		if s.Name == "" {
+			s.Name = fmt.Sprintf("record_%d", len(defined))
+		}
+		defined[t] = s
+		for _, sf := range structFields(t) {
+			fieldType, err := schemaOfType(sf.typ, defined)
+			if err != nil {
+				return nil, fmt.Errorf("field %s.%s: %w", t.Name(), sf.name, err)
+			}
+			s.Fields = append(s.Fields, &Field{Name: sf.name, Type: fieldType})
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, t)
+}
+
+// SchemaFromBigQuery converts an (inferred) BigQuery schema to an avro record schema named name.
+// NULLABLE fields become unions with null, REPEATED fields arrays and RECORD fields nested records.
+// The mapping follows what BigQuery expects when loading avro files.
+func SchemaFromBigQuery(name string, bq bigquery_schema.TableFieldSchema) (*Schema, error) {
+	if name == "" {
+		name = "root"
+	}
+	return bqRecordSchema(avroName(name), bq.Description, bq.Fields)
+}
+
+func bqRecordSchema(name, doc string, fields []*bigquery_schema.TableFieldSchema) (*Schema, error) {
+	s := &Schema{Type: TypeRecord, Name: name, Doc: doc}
+	for _, field := range fields {
+		fieldType, err := bqFieldSchema(name, field)
+		if err != nil {
+			return nil, err
+		}
+		s.Fields = append(s.Fields, &Field{Name: avroName(field.Name), Doc: field.Description, Type: fieldType})
+	}
+	return s, nil
+}
+
+func bqFieldSchema(parent string, field *bigquery_schema.TableFieldSchema) (*Schema, error) {
+	var s *Schema
+	switch bigquery_schema.FieldType(strings.ToUpper(field.Type)) {
+	case bigquery_schema.StringFieldType, bigquery_schema.GeographyFieldType, bigquery_schema.JSONFieldType,
+		bigquery_schema.IntervalFieldType, bigquery_schema.NOFieldType:
+		s = &Schema{Type: TypeString}
+	case bigquery_schema.BytesFieldType:
+		s = &Schema{Type: TypeBytes}
+	case bigquery_schema.IntegerFieldType, "INT64":
+		s = &Schema{Type: TypeLong}
+	case bigquery_schema.FloatFieldType, "FLOAT64":
+		s = &Schema{Type: TypeDouble}
+	case bigquery_schema.BooleanFieldType, "BOOL":
+		s = &Schema{Type: TypeBoolean}
+	case bigquery_schema.TimestampFieldType:
+		s = &Schema{Type: TypeLong, LogicalType: LogicalTimestampMicros}
+	case bigquery_schema.DateFieldType:
+		s = &Schema{Type: TypeInt, LogicalType: LogicalDate}
+	case bigquery_schema.TimeFieldType:
+		s = &Schema{Type: TypeLong, LogicalType: LogicalTimeMicros}
+	case bigquery_schema.DateTimeFieldType:
+		s = &Schema{Type: TypeString, LogicalType: LogicalDateTime}
+	case bigquery_schema.NumericFieldType, bigquery_schema.BigNumericFieldType:
+		precision, scale := int(field.Precision), int(field.Scale)
+		if precision == 0 {
+			precision, scale = DefaultDecimalPrecision, DefaultDecimalScale
+		}
+		s = &Schema{Type: TypeBytes, LogicalType: LogicalDecimal, Precision: precision, Scale: scale}
+	case bigquery_schema.RecordFieldType, "STRUCT":
+		var err error
+		if s, err = bqRecordSchema(parent+"_"+avroName(field.Name), field.Description, field.Fields); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: BigQuery type %s of field %s", ErrUnsupportedType, field.Type, field.Name)
+	}
+
+	switch strings.ToUpper(field.Mode) {
+	case "REPEATED":
+		return &Schema{Type: TypeArray, Items: s}, nil
+	case "REQUIRED":
+		return s, nil
+	}
+	return Nullable(s), nil
+}
+
+type structField struct {
+	name  string
+	index []int
+	typ   reflect.Type
+}
+
+// structFields returns the exported fields of a struct type; embedded structs without a name are promoted
+func structFields(t reflect.Type) []structField {
+	res := []structField{}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, skip := fieldName(sf)
+		if skip || (!sf.IsExported() && !sf.Anonymous) {
+			continue
+		}
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			for _, embedded := range structFields(ft) {
+				embedded.index = append([]int{i}, embedded.index...)
+				res = append(res, embedded)
+			}
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		res = append(res, structField{name: avroName(name), index: []int{i}, typ: sf.Type})
+	}
+	return res
+}
+
+// fieldName returns the tagged name of the struct field (avro tag first, json second) and if the field should be skipped
+func fieldName(sf reflect.StructField) (string, bool) {
+	for _, tagName := range []string{"avro", "json"} {
+		tag, ok := sf.Tag.Lookup(tagName)
+		if !ok {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if name == "-" {
+			return "", true
+		}
+		if name != "" {
+			return name, false
+		}
+	}
+	return "", false
+}
+
+// avroName replaces characters not allowed in avro names ([A-Za-z_][A-Za-z0-9_]*) with _
+func avroName(name string) string {
+	var sb strings.Builder
+	for index, r := range name {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+			sb.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if index == 0 {
+				sb.WriteRune('_')
+			}
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune('_')
+		}
+	}
+	return sb.String()
+}
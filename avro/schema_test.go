@@ -0,0 +1,110 @@
+package avro
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kvanticoss/goutils/v2/iterator/bigquery_schema"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppendValueSpecExample(t *testing.T) {
+	// From the avro specification: {"a": 27, "b": "foo"} encodes as 36 06 66 6f 6f
+	schema, err := ParseSchema([]byte(`{"type": "record", "name": "test", "fields": [
+		{"name": "a", "type": "long"},
+		{"name": "b", "type": "string"}
+	]}`))
+	assert.NoError(t, err)
+
+	buf, err := appendValue(nil, schema, reflect.ValueOf(map[string]interface{}{"a": 27, "b": "foo"}))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x36, 0x06, 0x66, 0x6f, 0x6f}, buf)
+}
+
+func TestParseSchemaMarshalRoundTrip(t *testing.T) {
+	raw := `{"fields":[{"name":"a","type":{"name":"ns.node","type":"fixed","size":4}},` +
+		`{"name":"b","type":"ns.node"},` +
+		`{"name":"c","type":["null",{"name":"color","type":"enum","symbols":["RED","GREEN"]}],"default":null}],` +
+		`"name":"rec","namespace":"ns","type":"record"}`
+	schema, err := ParseSchema([]byte(raw))
+	assert.NoError(t, err)
+	assert.Same(t, schema.Fields[0].Type, schema.Fields[1].Type, "references resolve to the named type")
+	assert.Equal(t, TypeEnum, schema.Fields[2].Type.Branches[1].Type)
+	assert.True(t, schema.Fields[2].HasDefault)
+
+	reparsed, err := ParseSchema([]byte(schema.String()))
+	assert.NoError(t, err)
+	assert.Equal(t, schema.String(), reparsed.String())
+
+	_, err = ParseSchema([]byte(`{"type": "record", "name": "r", "fields": [{"name": "a", "type": "unknown"}]}`))
+	assert.ErrorIs(t, err, ErrInvalidSchema)
+}
+
+func TestSchemaFromBigQuery(t *testing.T) {
+	schema, err := SchemaFromBigQuery("events", bigquery_schema.TableFieldSchema{Fields: []*bigquery_schema.TableFieldSchema{
+		{Name: "id", Type: "INTEGER", Mode: "REQUIRED"},
+		{Name: "ts", Type: "TIMESTAMP"},
+		{Name: "day", Type: "DATE", Mode: "REQUIRED"},
+		{Name: "local", Type: "DATETIME", Mode: "REQUIRED"},
+		{Name: "price", Type: "NUMERIC", Mode: "REQUIRED"},
+		{Name: "tags", Type: "STRING", Mode: "REPEATED"},
+		{Name: "user", Type: "RECORD", Fields: []*bigquery_schema.TableFieldSchema{
+			{Name: "name", Type: "STRING"},
+		}},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"fields":[`+
+		`{"name":"id","type":"long"},`+
+		`{"name":"ts","type":["null",{"logicalType":"timestamp-micros","type":"long"}]},`+
+		`{"name":"day","type":{"logicalType":"date","type":"int"}},`+
+		`{"name":"local","type":{"logicalType":"datetime","type":"string"}},`+
+		`{"name":"price","type":{"logicalType":"decimal","precision":38,"scale":9,"type":"bytes"}},`+
+		`{"name":"tags","type":{"items":"string","type":"array"}},`+
+		`{"name":"user","type":["null",{"fields":[{"name":"name","type":["null","string"]}],"name":"events_user","type":"record"}]}`+
+		`],"name":"events","type":"record"}`, schema.String())
+
+	// Values as they appear in JSON data are converted leniently
+	record := map[string]interface{}{
+		"id":    "12",
+		"ts":    "2023-01-02T03:04:05.123456Z",
+		"day":   "2023-01-02",
+		"local": "2023-01-02 03:04:05",
+		"price": "1.5",
+		"tags":  []interface{}{"a"},
+		"user":  map[string]interface{}{"name": "n"},
+	}
+	buf, err := appendValue(nil, schema, reflect.ValueOf(record))
+	assert.NoError(t, err)
+
+	decoded, err := (&decoder{buf: buf}).value(schema)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"id":    int64(12),
+		"ts":    time.Date(2023, 1, 2, 3, 4, 5, 123456000, time.UTC),
+		"day":   time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC),
+		"local": "2023-01-02 03:04:05",
+		"price": json.Number("1.500000000"),
+		"tags":  []interface{}{"a"},
+		"user":  map[string]interface{}{"name": "n"},
+	}, decoded)
+}
+
+func TestDecimalRoundTrip(t *testing.T) {
+	schema := &Schema{Type: TypeBytes, LogicalType: LogicalDecimal, Precision: 10, Scale: 2}
+	for input, expected := range map[interface{}]string{
+		"-0.005": "-0.01",
+		"128":    "128.00",
+		-1:       "-1.00",
+		0.125:    "0.13",
+		"0":      "0.00",
+	} {
+		buf, err := appendValue(nil, schema, reflect.ValueOf(input))
+		assert.NoError(t, err)
+		decoded, err := (&decoder{buf: buf}).value(schema)
+		assert.NoError(t, err)
+		assert.Equal(t, json.Number(expected), decoded, input)
+	}
+}
@@ -0,0 +1,158 @@
+package checksum
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Algorithm is a checksum algorithm; its name is used as the extension of sidecar files
+type Algorithm string
+
+// Supported algorithms
+const (
+	SHA256 Algorithm = "sha256"
+	CRC32C Algorithm = "crc32c"
+	MD5    Algorithm = "md5"
+)
+
+// Checksums are the digests (hex encoded) and size of an object
+type Checksums struct {
+	Path    string
+	Bytes   int64
+	Digests map[Algorithm]string
+}
+
+// Hasher computes the digests of everything written to it with several algorithms at once
+type Hasher struct {
+	hashes map[Algorithm]hash.Hash
+	n      int64
+}
+
+// NewHasher returns a Hasher for the algorithms; SHA256 if none are given
+func NewHasher(algos ...Algorithm) (*Hasher, error) {
+	if len(algos) == 0 {
+		algos = []Algorithm{SHA256}
+	}
+	hashes := map[Algorithm]hash.Hash{}
+	for _, algo := range algos {
+		switch algo {
+		case SHA256:
+			hashes[algo] = sha256.New()
+		case CRC32C:
+			hashes[algo] = crc32.New(crc32.MakeTable(crc32.Castagnoli))
+		case MD5:
+			hashes[algo] = md5.New()
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrUnknownAlgorithm, algo)
+		}
+	}
+	return &Hasher{hashes: hashes}, nil
+}
+
+// Write adds p to all digests
+func (h *Hasher) Write(p []byte) (int, error) {
+	for _, hash := range h.hashes {
+		hash.Write(p) // never returns an error
+	}
+	h.n += int64(len(p))
+	return len(p), nil
+}
+
+// Algorithms returns the algorithms of the Hasher in sorted order
+func (h *Hasher) Algorithms() []Algorithm {
+	algos := make([]Algorithm, 0, len(h.hashes))
+	for algo := range h.hashes {
+		algos = append(algos, algo)
+	}
+	sort.Slice(algos, func(i, j int) bool { return algos[i] < algos[j] })
+	return algos
+}
+
+// Checksums returns the digests and the number of bytes written so far
+func (h *Hasher) Checksums(path string) Checksums {
+	digests := make(map[Algorithm]string, len(h.hashes))
+	for algo, hash := range h.hashes {
+		digests[algo] = hex.EncodeToString(hash.Sum(nil))
+	}
+	return Checksums{Path: path, Bytes: h.n, Digests: digests}
+}
+
+// SidecarPath returns the path of the sidecar of the algorithm for the file at p; e.g. "data.ndjson.gz.sha256"
+func SidecarPath(p string, algo Algorithm) string {
+	return p + "." + string(algo)
+}
+
+// sidecarSizePrefix starts the comment line holding the size of the file; comment lines are ignored by sha256sum -c
+const sidecarSizePrefix = "# size: "
+
+// FormatSidecar returns the sidecar content for the digest and the size (in bytes) of the file at p in the format of
+// sha256sum/md5sum; allowing e.g. `sha256sum -c data.ndjson.gz.sha256` to verify a downloaded file. The size is kept
+// on a comment line.
+func FormatSidecar(digest string, size int64, p string) []byte {
+	return []byte(digest + "  " + path.Base(p) + "\n" + sidecarSizePrefix + strconv.FormatInt(size, 10) + "\n")
+}
+
+// ParseSidecar returns the digest and the size from the content of a sidecar; the size is -1 for sidecars without it
+// (e.g. written by sha256sum)
+func ParseSidecar(content []byte) (string, int64, error) {
+	fields := bytes.Fields(content)
+	if len(fields) == 0 {
+		return "", -1, ErrInvalidSidecar
+	}
+	if _, err := hex.DecodeString(string(fields[0])); err != nil {
+		return "", -1, fmt.Errorf("%w: %v", ErrInvalidSidecar, err)
+	}
+
+	size := int64(-1)
+	for _, line := range strings.Split(string(content), "\n") {
+		value, ok := strings.CutPrefix(strings.TrimSpace(line), sidecarSizePrefix)
+		if !ok {
+			continue
+		}
+		var err error
+		if size, err = strconv.ParseInt(value, 10, 64); err != nil || size < 0 {
+			return "", -1, fmt.Errorf("%w: invalid size %q", ErrInvalidSidecar, value)
+		}
+	}
+	return strings.ToLower(string(fields[0])), size, nil
+}
+
+// Verify reads r to the end and compares its digests with the expected ones, as well as the size unless
+// expected.Bytes is negative. A mismatch is returned as ErrMismatch.
+func Verify(r io.Reader, expected Checksums) (Checksums, error) {
+	algos := make([]Algorithm, 0, len(expected.Digests))
+	for algo := range expected.Digests {
+		algos = append(algos, algo)
+	}
+	h := &Hasher{hashes: map[Algorithm]hash.Hash{}} // only the size is verified without digests
+	if len(algos) > 0 {
+		var err error
+		if h, err = NewHasher(algos...); err != nil {
+			return Checksums{}, err
+		}
+	}
+	if _, err := io.Copy(h, r); err != nil {
+		return Checksums{}, err
+	}
+
+	actual := h.Checksums(expected.Path)
+	if expected.Bytes >= 0 && actual.Bytes != expected.Bytes {
+		return actual, fmt.Errorf("%w: %s: expected %d bytes, got %d", ErrMismatch, expected.Path, expected.Bytes, actual.Bytes)
+	}
+	for _, algo := range h.Algorithms() {
+		if want := strings.ToLower(expected.Digests[algo]); actual.Digests[algo] != want {
+			return actual, fmt.Errorf("%w: %s: expected %s %s, got %s", ErrMismatch, expected.Path, algo, want, actual.Digests[algo])
+		}
+	}
+	return actual, nil
+}
@@ -0,0 +1,72 @@
+package checksum
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHasher(t *testing.T) {
+	h, err := NewHasher(SHA256, CRC32C, MD5)
+	assert.NoError(t, err)
+	h.Write([]byte("hel"))
+	h.Write([]byte("lo"))
+
+	assert.Equal(t, []Algorithm{CRC32C, MD5, SHA256}, h.Algorithms())
+	assert.Equal(t, Checksums{
+		Path:  "dir/hello.txt",
+		Bytes: 5,
+		Digests: map[Algorithm]string{
+			SHA256: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+			CRC32C: "9a71bb4c",
+			MD5:    "5d41402abc4b2a76b9719d911017c592",
+		},
+	}, h.Checksums("dir/hello.txt"))
+
+	h, err = NewHasher()
+	assert.NoError(t, err)
+	assert.Equal(t, []Algorithm{SHA256}, h.Algorithms())
+
+	_, err = NewHasher("sha1")
+	assert.True(t, errors.Is(err, ErrUnknownAlgorithm))
+}
+
+func TestSidecar(t *testing.T) {
+	content := FormatSidecar("ABCDEF", 42, "dir/file.gz")
+	assert.Equal(t, "ABCDEF  file.gz\n# size: 42\n", string(content))
+	assert.Equal(t, "dir/file.gz.md5", SidecarPath("dir/file.gz", MD5))
+
+	digest, size, err := ParseSidecar(content)
+	assert.NoError(t, err)
+	assert.Equal(t, "abcdef", digest)
+	assert.Equal(t, int64(42), size)
+
+	digest, size, err = ParseSidecar([]byte("abcdef  file.gz\n"))
+	assert.NoError(t, err, "sidecars written by sha256sum have no size")
+	assert.Equal(t, "abcdef", digest)
+	assert.Equal(t, int64(-1), size)
+
+	_, _, err = ParseSidecar([]byte("\n"))
+	assert.True(t, errors.Is(err, ErrInvalidSidecar))
+	_, _, err = ParseSidecar([]byte("not-hex  file.gz"))
+	assert.True(t, errors.Is(err, ErrInvalidSidecar))
+	_, _, err = ParseSidecar([]byte("abcdef  file.gz\n# size: -1\n"))
+	assert.True(t, errors.Is(err, ErrInvalidSidecar))
+}
+
+func TestVerify(t *testing.T) {
+	expected := Checksums{Path: "hello", Bytes: 5, Digests: map[Algorithm]string{MD5: "5D41402ABC4B2A76B9719D911017C592"}}
+	actual, err := Verify(bytes.NewReader([]byte("hello")), expected)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), actual.Bytes)
+
+	_, err = Verify(bytes.NewReader([]byte("hellO")), expected)
+	assert.True(t, errors.Is(err, ErrMismatch))
+	_, err = Verify(bytes.NewReader([]byte("hello!")), expected)
+	assert.True(t, errors.Is(err, ErrMismatch))
+
+	_, err = Verify(bytes.NewReader([]byte("hello!")), Checksums{Bytes: 6})
+	assert.NoError(t, err, "only the size is verified without digests")
+}
@@ -0,0 +1,4 @@
+// Package checksum computes SHA-256, CRC32C and MD5 digests of streams, formats them as sidecar files compatible
+// with sha256sum/md5sum ("<hex digest>  <file name>", followed by a "# size: <bytes>" comment) and verifies streams
+// against them.
+package checksum
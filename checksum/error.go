@@ -0,0 +1,12 @@
+package checksum
+
+import "errors"
+
+var (
+	// ErrUnknownAlgorithm is returned for unsupported checksum algorithms
+	ErrUnknownAlgorithm = errors.New("checksum: unknown algorithm")
+	// ErrInvalidSidecar is returned when a sidecar doesn't contain a digest
+	ErrInvalidSidecar = errors.New("checksum: invalid sidecar")
+	// ErrMismatch is returned when the digest or the size of a stream doesn't match the expected value
+	ErrMismatch = errors.New("checksum: mismatch")
+)
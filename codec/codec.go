@@ -0,0 +1,109 @@
+package codec
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrUnknownCodec is returned when no codec is registered under the requested name or extension
+var ErrUnknownCodec = errors.New("unknown codec")
+
+// Encoder serializes records to a byte stream. Each call to Encode should result in the record being
+// written to the underlying writer in as few Write calls as possible (preferably one) so that records
+// aren't interlaced when the writer is shared. Close flushes any buffered data (headers, blocks, footers etc)
+// but does NOT close the underlying writer.
+type Encoder interface {
+	Encode(v interface{}) error
+	Close() error
+}
+
+// Decoder deserializes records from a byte stream. Decode should return io.EOF when there are no more records.
+type Decoder interface {
+	Decode(v interface{}) error
+}
+
+// Codec creates Encoders and Decoders for one serialization format.
+type Codec interface {
+	// Name is the unique name the codec is registered under (e.g. "json")
+	Name() string
+	// Extension is the file extension, including the leading dot, used for files written with the codec (e.g. ".ndjson")
+	Extension() string
+	// NewEncoder returns an Encoder writing to w
+	NewEncoder(w io.Writer) Encoder
+	// NewDecoder returns a Decoder reading from r
+	NewDecoder(r io.Reader) Decoder
+}
+
+var (
+	registryMutex sync.RWMutex
+	registry      = map[string]Codec{}
+)
+
+// Register makes a codec available by its name and extension through Lookup and ForPath.
+// Registering a codec with an already registered name replaces the previous codec.
+func Register(c Codec) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	registry[c.Name()] = c
+}
+
+// Lookup returns the codec registered under name or ErrUnknownCodec
+func Lookup(name string) (Codec, error) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	if c, ok := registry[name]; ok {
+		return c, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownCodec, name)
+}
+
+// ForPath returns the registered codec whose extension matches the path. Compression suffixes
+// such as .gz are ignored; e.g. "foo/bar_000001.ndjson.gz" yields the JSON codec.
+func ForPath(p string) (Codec, error) {
+	base := path.Base(p)
+	for _, compression := range []string{".gz", ".zst", ".sz"} {
+		base = strings.TrimSuffix(base, compression)
+	}
+
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	// Iterate in a stable order so overlapping extensions always resolve the same way
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if c := registry[name]; c.Extension() != "" && strings.HasSuffix(base, c.Extension()) {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no codec for path %s", ErrUnknownCodec, p)
+}
+
+// Names returns the names of all registered codecs, sorted
+func Names() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	Register(JSON)
+	Register(CSV)
+	Register(Gob)
+	Register(MsgPack)
+	Register(Proto)
+}
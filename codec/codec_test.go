@@ -0,0 +1,109 @@
+package codec_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/kvanticoss/goutils/v2/codec"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testRecord struct {
+	Name  string   `json:"name"`
+	Count int      `json:"count"`
+	Tags  []string `json:"tags"`
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	records := []testRecord{
+		{Name: "a", Count: 1, Tags: []string{"t1"}},
+		{Name: "b, \"quoted\"", Count: 2, Tags: []string{"t1", "t2"}},
+	}
+
+	for _, c := range []codec.Codec{codec.JSON, codec.CSV, codec.Gob, codec.MsgPack} {
+		t.Run(c.Name(), func(t *testing.T) {
+			var buf bytes.Buffer
+			enc := c.NewEncoder(&buf)
+			for _, r := range records {
+				assert.NoError(t, enc.Encode(r))
+			}
+			assert.NoError(t, enc.Close())
+
+			dec := c.NewDecoder(&buf)
+			for _, expected := range records {
+				var r testRecord
+				assert.NoError(t, dec.Decode(&r))
+				assert.Equal(t, expected, r)
+			}
+			assert.Equal(t, io.EOF, dec.Decode(&testRecord{}))
+		})
+	}
+}
+
+func TestJSONEncodesOneWritePerRecord(t *testing.T) {
+	writes := 0
+	enc := codec.JSON.NewEncoder(writerFunc(func(p []byte) (int, error) {
+		writes++
+		return len(p), nil
+	}))
+	assert.NoError(t, enc.Encode(map[string]interface{}{"b": 1, "a": 2}))
+	assert.NoError(t, enc.Encode(map[string]interface{}{"b": 1, "a": 2}))
+	assert.Equal(t, 2, writes)
+}
+
+func TestJSONDecodeMalformedRecord(t *testing.T) {
+	for name, stream := range map[string]string{
+		"invalid value": "{\"a\":1}\n{\"a\":tru}\n{\"a\":3}\n",
+		"stray brace":   "{\"a\":1}\n}\n{\"a\":3}\n",
+	} {
+		dec := codec.JSON.NewDecoder(strings.NewReader(stream))
+		record := map[string]interface{}{}
+		assert.NoError(t, dec.Decode(&record), name)
+
+		err := dec.Decode(&record)
+		assert.Error(t, err, name)
+		assert.NotEqual(t, io.EOF, err, name)
+		assert.Equal(t, err, dec.Decode(&record), "%s: the error is not turned into the end of the stream", name)
+	}
+
+	dec := codec.JSON.NewDecoder(strings.NewReader("{\"a\":1}\n \n"))
+	assert.NoError(t, dec.Decode(&map[string]interface{}{}))
+	assert.Equal(t, io.EOF, dec.Decode(&map[string]interface{}{}), "trailing white space")
+}
+
+func TestRegistry(t *testing.T) {
+	c, err := codec.Lookup("json")
+	assert.NoError(t, err)
+	assert.Equal(t, codec.JSON, c)
+
+	_, err = codec.Lookup("does-not-exist")
+	assert.True(t, errors.Is(err, codec.ErrUnknownCodec))
+
+	for path, name := range map[string]string{
+		"a/b/file_000001.ndjson":    "json",
+		"a/b/file_000001.ndjson.gz": "json",
+		"file.csv.gz":               "csv",
+		"file.gob":                  "gob",
+		"file.msgpack":              "msgpack",
+	} {
+		c, err := codec.ForPath(path)
+		if assert.NoError(t, err, path) {
+			assert.Equal(t, name, c.Name(), path)
+		}
+	}
+
+	_, err = codec.ForPath("file.unknown")
+	assert.True(t, errors.Is(err, codec.ErrUnknownCodec))
+
+	assert.Equal(t, []string{"csv", "gob", "json", "msgpack", "protobuf"}, codec.Names())
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
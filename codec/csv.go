@@ -0,0 +1,606 @@
+package codec
+
+import (
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// ErrUnknownCSVColumn is returned when a record contains a column that isn't part of the CSV header
+var ErrUnknownCSVColumn = errors.New("record contains a column which is not part of the csv header")
+
+// ErrInvalidCSVTarget is returned when decoding into something else than a pointer to a struct or map
+var ErrInvalidCSVTarget = errors.New("csv can only be decoded into a pointer to a struct or a map[string]interface{}")
+
+// CSVNestedMode controls how nested values (maps and structs) are written to CSV
+type CSVNestedMode int
+
+const (
+	// CSVFlatten flattens nested maps and structs into separate columns named parent.child
+	CSVFlatten CSVNestedMode = iota
+	// CSVJSONEncode writes nested maps and structs as a JSON encoded string in a single column
+	CSVJSONEncode
+)
+
+// DefaultCSVSampleSize is the number of records inspected to derive the header when no columns are provided
+const DefaultCSVSampleSize = 100
+
+// CSVOptions configures how records are written as CSV. The zero value is usable.
+type CSVOptions struct {
+	// Columns is the header to use. If empty the header is derived from the first SampleSize records;
+	// struct fields keep their declaration order while map keys are sorted.
+	Columns []string
+	// SampleSize is the number of records inspected to derive the header; defaults to DefaultCSVSampleSize
+	SampleSize int
+	// Nested controls if nested maps and structs are flattened or JSON encoded. Slices are always JSON encoded.
+	Nested CSVNestedMode
+	// Comma is the field delimiter; defaults to ','
+	Comma rune
+}
+
+// CSV encodes records as CSV with a header row using the default CSVOptions
+var CSV Codec = NewCSV(CSVOptions{})
+
+// NewCSV returns a CSV codec. Struct fields are named by their `csv` tag, falling back to the `json` tag
+// and the field name. If no columns are provided the encoder buffers the first opts.SampleSize records
+// to derive the header; records containing columns not present in the header will yield ErrUnknownCSVColumn.
+func NewCSV(opts CSVOptions) Codec {
+	return csvCodec{opts}
+}
+
+type csvCodec struct {
+	opts CSVOptions
+}
+
+func (csvCodec) Name() string      { return "csv" }
+func (csvCodec) Extension() string { return ".csv" }
+
+func (c csvCodec) NewEncoder(w io.Writer) Encoder {
+	return NewCSVEncoder(w, c.opts)
+}
+
+func (c csvCodec) NewDecoder(r io.Reader) Decoder {
+	cr := csv.NewReader(r)
+	if c.opts.Comma != 0 {
+		cr.Comma = c.opts.Comma
+	}
+	return &csvDecoder{r: cr, nested: c.opts.Nested}
+}
+
+// CSVColumns derives the header columns from the records in a deterministic order; struct fields in
+// declaration order and map keys sorted. Columns are ordered by first appearance across the records.
+func CSVColumns(nested CSVNestedMode, records ...interface{}) []string {
+	columns := []string{}
+	seen := map[string]bool{}
+	for _, record := range records {
+		for _, cell := range flattenCSVRecord(record, nested) {
+			if !seen[cell.column] {
+				seen[cell.column] = true
+				columns = append(columns, cell.column)
+			}
+		}
+	}
+	return columns
+}
+
+// CSVEncoder writes records as CSV rows preceded by a header row. Rows are buffered; they are written to the
+// underlying writer by Flush and Close.
+type CSVEncoder struct {
+	w           *csv.Writer
+	opts        CSVOptions
+	header      []string
+	columnIndex map[string]int
+
+	// records buffered until the header can be derived
+	sampled []interface{}
+}
+
+// NewCSVEncoder returns a CSVEncoder writing to w. Struct fields are named by their `csv` tag, falling back to
+// the `json` tag and the field name. If no columns are provided the first opts.SampleSize records are buffered
+// to derive the header; records containing columns not present in the header will yield ErrUnknownCSVColumn.
+func NewCSVEncoder(w io.Writer, opts CSVOptions) *CSVEncoder {
+	if opts.SampleSize <= 0 {
+		opts.SampleSize = DefaultCSVSampleSize
+	}
+	cw := csv.NewWriter(w)
+	if opts.Comma != 0 {
+		cw.Comma = opts.Comma
+	}
+	return &CSVEncoder{
+		w:    cw,
+		opts: opts,
+	}
+}
+
+// Encode writes the record as a csv row. Until the header is known (either from CSVOptions.Columns or
+// by sampling SampleSize records) records are buffered.
+func (e *CSVEncoder) Encode(v interface{}) error {
+	if e.header == nil && len(e.opts.Columns) == 0 {
+		e.sampled = append(e.sampled, v)
+		if len(e.sampled) < e.opts.SampleSize {
+			return nil
+		}
+		return e.writeHeaderAndSampled()
+	}
+	if e.header == nil {
+		if err := e.writeHeader(e.opts.Columns); err != nil {
+			return err
+		}
+	}
+	return e.writeRecord(v)
+}
+
+// Flush writes the buffered rows to the underlying writer. Records sampled for the header are written as well, with
+// the header derived from the records sampled so far.
+func (e *CSVEncoder) Flush() error {
+	if e.header == nil && len(e.sampled) > 0 {
+		if err := e.writeHeaderAndSampled(); err != nil {
+			return err
+		}
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// Close writes any buffered records and the header if it hasn't been written yet (only known without records if
+// CSVOptions.Columns is set); the underlying writer is not closed
+func (e *CSVEncoder) Close() error {
+	if e.header == nil && len(e.sampled) == 0 && len(e.opts.Columns) > 0 {
+		if err := e.writeHeader(e.opts.Columns); err != nil {
+			return err
+		}
+	}
+	return e.Flush()
+}
+
+func (e *CSVEncoder) writeHeaderAndSampled() error {
+	if err := e.writeHeader(CSVColumns(e.opts.Nested, e.sampled...)); err != nil {
+		return err
+	}
+	sampled := e.sampled
+	e.sampled = nil
+	for _, record := range sampled {
+		if err := e.writeRecord(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *CSVEncoder) writeHeader(header []string) error {
+	e.header = header
+	e.columnIndex = make(map[string]int, len(header))
+	for index, column := range header {
+		e.columnIndex[column] = index
+	}
+	if len(header) == 0 {
+		return nil
+	}
+	return e.writeRow(header)
+}
+
+func (e *CSVEncoder) writeRecord(record interface{}) error {
+	row := make([]string, len(e.header))
+	for _, cell := range flattenCSVRecord(record, e.opts.Nested) {
+		index, ok := e.columnIndex[cell.column]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownCSVColumn, cell.column)
+		}
+		row[index] = cell.value
+	}
+	return e.writeRow(row)
+}
+
+func (e *CSVEncoder) writeRow(row []string) error {
+	return e.w.Write(row)
+}
+
+type csvCell struct {
+	column string
+	value  string
+}
+
+var (
+	typeOfTime            = reflect.TypeOf(time.Time{})
+	typeOfTextMarshaler   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	typeOfTextUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// flattenCSVRecord returns the cells of a record in a deterministic order; struct fields in declaration order
+// and map keys sorted.
+func flattenCSVRecord(record interface{}, nested CSVNestedMode) []csvCell {
+	cells := []csvCell{}
+	v := reflect.ValueOf(record)
+	if !v.IsValid() {
+		return cells
+	}
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return cells
+		}
+		v = v.Elem()
+	}
+
+	switch {
+	case isCSVScalar(v):
+		return append(cells, csvCell{"value", formatCSVValue(v)})
+	case v.Kind() == reflect.Map, v.Kind() == reflect.Struct:
+		return appendCSVCells(cells, "", v, nested, false, map[reflect.Type]bool{})
+	default:
+		return append(cells, csvCell{"value", formatCSVValue(v)})
+	}
+}
+
+// appendCSVCells appends the cells of the map or struct v; path holds the struct types being expanded so nil
+// pointers of recursive types (e.g. type Node struct{ Next *Node }) yield a single empty cell instead of recursing
+func appendCSVCells(cells []csvCell, prefix string, v reflect.Value, nested CSVNestedMode, empty bool, path map[reflect.Type]bool) []csvCell {
+	addCell := func(name string, field reflect.Value) {
+		column := name
+		if prefix != "" {
+			column = prefix + "." + name
+		}
+
+		fieldEmpty := empty
+		for field.Kind() == reflect.Ptr || field.Kind() == reflect.Interface {
+			if field.IsNil() {
+				// Nil struct pointers still yield their columns so the header doesn't depend on the values.
+				if zero := reflect.Zero(field.Type().Elem()); field.Kind() == reflect.Ptr && nested == CSVFlatten && zero.Kind() == reflect.Struct && !isCSVScalar(zero) && !path[zero.Type()] {
+					field = zero
+					fieldEmpty = true
+					break
+				}
+				// Nil interfaces have no known columns; the cell is left empty
+				if field.Kind() == reflect.Interface && nested == CSVFlatten {
+					return
+				}
+				cells = append(cells, csvCell{column, ""})
+				return
+			}
+			field = field.Elem()
+		}
+
+		if nested == CSVFlatten && !isCSVScalar(field) && (field.Kind() == reflect.Map || field.Kind() == reflect.Struct) {
+			cells = appendCSVCells(cells, column, field, nested, fieldEmpty, path)
+			return
+		}
+		if fieldEmpty {
+			cells = append(cells, csvCell{column, ""})
+			return
+		}
+		cells = append(cells, csvCell{column, formatCSVValue(field)})
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		keys := v.MapKeys()
+		names := make([]string, len(keys))
+		byName := make(map[string]reflect.Value, len(keys))
+		for index, key := range keys {
+			names[index] = fmt.Sprint(key.Interface())
+			byName[names[index]] = v.MapIndex(key)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			addCell(name, byName[name])
+		}
+	case reflect.Struct:
+		t := v.Type()
+		if !path[t] {
+			path[t] = true
+			defer delete(path, t)
+		}
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if !sf.IsExported() {
+				continue
+			}
+			name, skip := csvFieldName(sf)
+			if skip {
+				continue
+			}
+			field := v.Field(i)
+
+			// Embedded structs are promoted to the parent; just like encoding/json
+			if sf.Anonymous && name == "" {
+				embedded := field
+				for embedded.Kind() == reflect.Ptr {
+					if embedded.IsNil() {
+						break
+					}
+					embedded = embedded.Elem()
+				}
+				if embedded.Kind() == reflect.Struct && !isCSVScalar(embedded) {
+					cells = appendCSVCells(cells, prefix, embedded, nested, empty, path)
+					continue
+				}
+			}
+			if name == "" {
+				name = sf.Name
+			}
+			addCell(name, field)
+		}
+	}
+	return cells
+}
+
+// csvFieldName returns the tagged name of the struct field (csv tag first, json second) and if the field should be skipped
+func csvFieldName(sf reflect.StructField) (string, bool) {
+	for _, tagName := range []string{"csv", "json"} {
+		tag, ok := sf.Tag.Lookup(tagName)
+		if !ok {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if name == "-" {
+			return "", true
+		}
+		if name != "" {
+			return name, false
+		}
+	}
+	return "", false
+}
+
+// isCSVScalar reports if the value can be represented as a single csv cell without encoding
+func isCSVScalar(v reflect.Value) bool {
+	if v.Type() == typeOfTime || v.Type().Implements(typeOfTextMarshaler) {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Map, reflect.Struct, reflect.Slice, reflect.Array:
+		return false
+	}
+	return true
+}
+
+func formatCSVValue(v reflect.Value) string {
+	if !v.IsValid() {
+		return ""
+	}
+	if v.Type() == typeOfTime {
+		return v.Interface().(time.Time).Format(time.RFC3339Nano)
+	}
+	if v.Type().Implements(typeOfTextMarshaler) {
+		if b, err := v.Interface().(encoding.TextMarshaler).MarshalText(); err == nil {
+			return string(b)
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 32)
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	case reflect.Map, reflect.Struct, reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return ""
+		}
+		d, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(v.Interface())
+		if err != nil {
+			return fmt.Sprint(v.Interface())
+		}
+		return string(d)
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+type csvDecoder struct {
+	r      *csv.Reader
+	nested CSVNestedMode
+	header []string
+}
+
+// Decode reads the next row into v which must be a pointer to a struct or a map[string]interface{}.
+// For maps the values are kept as strings; flattened columns are turned back into nested maps.
+func (d *csvDecoder) Decode(v interface{}) error {
+	if d.header == nil {
+		header, err := d.r.Read()
+		if err != nil {
+			return err
+		}
+		d.header = header
+	}
+
+	row, err := d.r.Read()
+	if err != nil {
+		return err
+	}
+
+	cells := make(map[string]string, len(row))
+	for index, column := range d.header {
+		if index < len(row) {
+			cells[column] = row[index]
+		}
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return ErrInvalidCSVTarget
+	}
+	return d.decodeInto(rv.Elem(), "", cells)
+}
+
+func (d *csvDecoder) decodeInto(v reflect.Value, prefix string, cells map[string]string) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decodeInto(v.Elem(), prefix, cells)
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return ErrInvalidCSVTarget
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		for column, value := range cells {
+			if !strings.HasPrefix(column, prefix) {
+				continue
+			}
+			if err := setCSVMapValue(v, strings.Split(strings.TrimPrefix(column, prefix), "."), value, d.nested); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if !sf.IsExported() {
+				continue
+			}
+			name, skip := csvFieldName(sf)
+			if skip {
+				continue
+			}
+			field := v.Field(i)
+
+			if sf.Anonymous && name == "" && indirectType(sf.Type).Kind() == reflect.Struct {
+				if err := d.decodeInto(field, prefix, cells); err != nil {
+					return err
+				}
+				continue
+			}
+			if name == "" {
+				name = sf.Name
+			}
+			column := prefix + name
+
+			if value, ok := cells[column]; ok {
+				if err := setCSVValue(field, value); err != nil {
+					return fmt.Errorf("column %s: %w", column, err)
+				}
+				continue
+			}
+			kind := indirectType(sf.Type).Kind()
+			if d.nested == CSVFlatten && (kind == reflect.Struct || kind == reflect.Map) && hasCSVValues(cells, column+".") {
+				if err := d.decodeInto(field, column+".", cells); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return ErrInvalidCSVTarget
+}
+
+// setCSVMapValue sets m[path[0]][path[1]]... = value creating nested maps as required
+func setCSVMapValue(m reflect.Value, path []string, value string, nested CSVNestedMode) error {
+	if len(path) == 1 || nested != CSVFlatten || m.Type().Elem().Kind() != reflect.Interface {
+		elem := reflect.New(m.Type().Elem()).Elem()
+		if err := setCSVValue(elem, value); err != nil {
+			return err
+		}
+		m.SetMapIndex(reflect.ValueOf(strings.Join(path, ".")), elem)
+		return nil
+	}
+
+	key := reflect.ValueOf(path[0])
+	child := m.MapIndex(key)
+	if !child.IsValid() || child.IsNil() {
+		child = reflect.ValueOf(map[string]interface{}{})
+	}
+	for child.Kind() == reflect.Interface {
+		child = child.Elem()
+	}
+	if child.Kind() != reflect.Map {
+		return fmt.Errorf("%w: column %s is both a value and a nested record", ErrInvalidCSVTarget, path[0])
+	}
+	if err := setCSVMapValue(child, path[1:], value, nested); err != nil {
+		return err
+	}
+	m.SetMapIndex(key, child)
+	return nil
+}
+
+// setCSVValue parses the cell into v; empty cells leaves v untouched.
+func setCSVValue(v reflect.Value, s string) error {
+	if s == "" {
+		return nil
+	}
+
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setCSVValue(v.Elem(), s)
+	}
+
+	if reflect.PtrTo(v.Type()).Implements(typeOfTextUnmarshaler) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(i)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return ErrInvalidCSVTarget
+		}
+		v.Set(reflect.ValueOf(s))
+	case reflect.Map, reflect.Struct, reflect.Slice, reflect.Array:
+		return jsoniter.ConfigCompatibleWithStandardLibrary.UnmarshalFromString(s, v.Addr().Interface())
+	default:
+		return ErrInvalidCSVTarget
+	}
+	return nil
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// hasCSVValues reports if any non empty cell is found under the prefix
+func hasCSVValues(cells map[string]string, prefix string) bool {
+	for column, value := range cells {
+		if value != "" && strings.HasPrefix(column, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package codec_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/kvanticoss/goutils/v2/codec"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type csvInner struct {
+	X int    `json:"x"`
+	Y string `csv:"why"`
+}
+
+type csvRecord struct {
+	Name  string            `csv:"name"`
+	Inner csvInner          `json:"inner"`
+	Ptr   *csvInner         `json:"ptr"`
+	Meta  map[string]string `json:"meta"`
+	When  time.Time         `json:"when"`
+	Skip  string            `csv:"-"`
+}
+
+func TestCSVEncoderSamplesHeader(t *testing.T) {
+	var buf bytes.Buffer
+	enc := codec.NewCSVEncoder(&buf, codec.CSVOptions{SampleSize: 2})
+
+	assert.NoError(t, enc.Encode(map[string]interface{}{"b": 1}))
+	assert.NoError(t, enc.Encode(map[string]interface{}{"a": 2}))
+	assert.Equal(t, "", buf.String(), "rows should be buffered until flushed")
+	assert.NoError(t, enc.Flush())
+	assert.Equal(t, "b,a\n1,\n,2\n", buf.String())
+
+	err := enc.Encode(map[string]interface{}{"c": 3})
+	assert.True(t, errors.Is(err, codec.ErrUnknownCSVColumn))
+}
+
+func TestCSVEncoderCloseFlushesSample(t *testing.T) {
+	var buf bytes.Buffer
+	enc := codec.CSV.NewEncoder(&buf)
+	assert.NoError(t, enc.Encode(map[string]interface{}{"b": 1, "a": "x"}))
+	assert.Equal(t, "", buf.String())
+	assert.NoError(t, enc.Close())
+	assert.Equal(t, "a,b\nx,1\n", buf.String())
+}
+
+func TestCSVNestedRoundTrip(t *testing.T) {
+	when := time.Date(2023, 1, 2, 3, 4, 5, 6, time.UTC)
+	records := []csvRecord{
+		{Name: "a", Inner: csvInner{1, "y"}, Meta: map[string]string{"k": "v"}, When: when, Skip: "skipped"},
+		{Name: "b", Ptr: &csvInner{2, "z"}, Meta: map[string]string{"k": "v2"}},
+	}
+
+	for _, nested := range []codec.CSVNestedMode{codec.CSVFlatten, codec.CSVJSONEncode} {
+		var buf bytes.Buffer
+		c := codec.NewCSV(codec.CSVOptions{Nested: nested})
+		enc := c.NewEncoder(&buf)
+		for _, r := range records {
+			assert.NoError(t, enc.Encode(r))
+		}
+		assert.NoError(t, enc.Close())
+
+		if nested == codec.CSVFlatten {
+			assert.Equal(t, "name,inner.x,inner.why,ptr.x,ptr.why,meta.k,when", firstLine(buf.String()))
+		} else {
+			assert.Equal(t, "name,inner,ptr,meta,when", firstLine(buf.String()))
+		}
+
+		dec := c.NewDecoder(&buf)
+		for _, expected := range records {
+			expected.Skip = ""
+			var r csvRecord
+			assert.NoError(t, dec.Decode(&r))
+			assert.Equal(t, expected, r)
+		}
+		assert.Equal(t, io.EOF, dec.Decode(&csvRecord{}))
+	}
+}
+
+func TestCSVDecodeIntoMap(t *testing.T) {
+	dec := codec.CSV.NewDecoder(bytes.NewBufferString("a,n.x,n.y\n1,2,\n"))
+
+	r := map[string]interface{}{}
+	assert.NoError(t, dec.Decode(&r))
+	assert.Equal(t, map[string]interface{}{
+		"a": "1",
+		"n": map[string]interface{}{"x": "2", "y": nil},
+	}, r)
+
+	assert.Equal(t, codec.ErrInvalidCSVTarget, codec.CSV.NewDecoder(bytes.NewBufferString("a\n1\n")).Decode(r))
+}
+
+func firstLine(s string) string {
+	return string(bytes.SplitN([]byte(s), []byte("\n"), 2)[0])
+}
+
+type csvNode struct {
+	Name string   `json:"name"`
+	Next *csvNode `json:"next"`
+}
+
+func TestCSVRecursiveType(t *testing.T) {
+	assert.Equal(t, []string{"name", "next"}, codec.CSVColumns(codec.CSVFlatten, csvNode{Name: "a"}))
+	assert.Equal(t, []string{"name", "next.name", "next.next"}, codec.CSVColumns(codec.CSVFlatten, csvNode{Name: "a", Next: &csvNode{Name: "b"}}))
+
+	var buf bytes.Buffer
+	enc := codec.NewCSV(codec.CSVOptions{Columns: []string{"name", "next"}}).NewEncoder(&buf)
+	assert.NoError(t, enc.Encode(&csvNode{Name: "a"}))
+	assert.NoError(t, enc.Close())
+	assert.Equal(t, "name,next\na,\n", buf.String())
+}
+
+func TestCSVEncoderFlush(t *testing.T) {
+	var buf bytes.Buffer
+	enc := codec.NewCSVEncoder(&buf, codec.CSVOptions{})
+	assert.NoError(t, enc.Encode(map[string]interface{}{"a": 1}))
+	assert.NoError(t, enc.Flush())
+	assert.Equal(t, "a\n1\n", buf.String(), "the header is derived from the records sampled so far")
+	assert.NoError(t, enc.Encode(map[string]interface{}{"a": 2}))
+	assert.NoError(t, enc.Close())
+	assert.Equal(t, "a\n1\n2\n", buf.String())
+}
+
+func TestCSVEncoderHeaderWithoutRecords(t *testing.T) {
+	var buf bytes.Buffer
+	enc := codec.NewCSV(codec.CSVOptions{Columns: []string{"a", "b"}}).NewEncoder(&buf)
+	assert.NoError(t, enc.Close())
+	assert.Equal(t, "a,b\n", buf.String(), "a header-only file is written when the columns are known")
+
+	buf.Reset()
+	assert.NoError(t, codec.CSV.NewEncoder(&buf).Close())
+	assert.Equal(t, "", buf.String(), "nothing is known without columns or records")
+}
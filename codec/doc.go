@@ -0,0 +1,3 @@
+// Package codec provides pluggable record Encoders and Decoders (JSON, CSV, gob, msgpack...) and a registry
+// to look them up by name or file extension.
+package codec
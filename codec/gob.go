@@ -0,0 +1,31 @@
+package codec
+
+import (
+	"encoding/gob"
+	"io"
+)
+
+// Gob encodes records as a encoding/gob stream. Note that records of interface types (such as
+// map[string]interface{}) require the concrete types to be registered with gob.Register.
+var Gob Codec = gobCodec{}
+
+type gobCodec struct{}
+
+func (gobCodec) Name() string      { return "gob" }
+func (gobCodec) Extension() string { return ".gob" }
+
+func (gobCodec) NewEncoder(w io.Writer) Encoder {
+	return &gobEncoder{gob.NewEncoder(w)}
+}
+
+func (gobCodec) NewDecoder(r io.Reader) Decoder {
+	return gob.NewDecoder(r)
+}
+
+type gobEncoder struct {
+	*gob.Encoder
+}
+
+func (e *gobEncoder) Close() error {
+	return nil
+}
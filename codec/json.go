@@ -0,0 +1,71 @@
+package codec
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+var jsonRecordDelimiter = []byte("\n")
+
+// JSON encodes records as new line delimited JSON; compatible with encoding/json
+var JSON Codec = jsonCodec{}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string      { return "json" }
+func (jsonCodec) Extension() string { return ".ndjson" }
+
+func (jsonCodec) NewEncoder(w io.Writer) Encoder {
+	return &jsonEncoder{w: w}
+}
+
+func (jsonCodec) NewDecoder(r io.Reader) Decoder {
+	return &jsonDecoder{dec: jsoniter.ConfigCompatibleWithStandardLibrary.NewDecoder(r)}
+}
+
+type jsonEncoder struct {
+	w io.Writer
+}
+
+// Encode writes the record and the new line delimiter in a single Write call
+func (e *jsonEncoder) Encode(v interface{}) error {
+	d, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(append(d, jsonRecordDelimiter...))
+	return err
+}
+
+func (e *jsonEncoder) Close() error {
+	return nil
+}
+
+type jsonDecoder struct {
+	dec *jsoniter.Decoder
+	err error
+}
+
+// Decode decodes the next record; io.EOF is returned once there are no more records (trailing white space is ignored).
+// A malformed record fails the decoder; the error is returned by all following calls.
+func (d *jsonDecoder) Decode(v interface{}) error {
+	if d.err != nil {
+		return d.err
+	}
+	if !d.dec.More() {
+		// More is also false for a stray ']' or '}' which is left buffered
+		if rest, _ := io.ReadAll(d.dec.Buffered()); len(bytes.TrimSpace(rest)) > 0 {
+			d.err = fmt.Errorf("invalid character %q looking for beginning of value", bytes.TrimSpace(rest)[0])
+			return d.err
+		}
+		return io.EOF
+	}
+	if err := d.dec.Decode(v); err != nil {
+		d.err = err
+		return err
+	}
+	return nil
+}
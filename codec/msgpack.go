@@ -0,0 +1,47 @@
+package codec
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// MsgPack encodes records as a stream of MessagePack objects. Struct fields are named by their
+// `msgpack` tag, falling back to the `json` tag.
+var MsgPack Codec = msgPackCodec{}
+
+type msgPackCodec struct{}
+
+func (msgPackCodec) Name() string      { return "msgpack" }
+func (msgPackCodec) Extension() string { return ".msgpack" }
+
+func (msgPackCodec) NewEncoder(w io.Writer) Encoder {
+	return &msgPackEncoder{w: w}
+}
+
+func (msgPackCodec) NewDecoder(r io.Reader) Decoder {
+	dec := msgpack.NewDecoder(r)
+	dec.SetCustomStructTag("json")
+	return dec
+}
+
+type msgPackEncoder struct {
+	w io.Writer
+}
+
+// Encode writes the record in a single Write call
+func (e *msgPackEncoder) Encode(v interface{}) error {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := e.w.Write(buf.Bytes())
+	return err
+}
+
+func (e *msgPackEncoder) Close() error {
+	return nil
+}
@@ -0,0 +1,54 @@
+package codec_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/kvanticoss/goutils/v2/codec"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProtoRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	enc := codec.Proto.NewEncoder(&buf)
+	for _, s := range []string{"a", "", "longer value"} {
+		assert.NoError(t, enc.Encode(wrapperspb.String(s)))
+	}
+	assert.NoError(t, enc.Close())
+	assert.Equal(t, []byte{3, 0x0a, 1, 'a'}, buf.Bytes()[:4], "varint length followed by the message")
+
+	dec := codec.Proto.NewDecoder(&buf)
+	for _, expected := range []string{"a", "", "longer value"} {
+		msg := &wrapperspb.StringValue{}
+		assert.NoError(t, dec.Decode(msg))
+		assert.Equal(t, expected, msg.GetValue())
+	}
+	assert.Equal(t, io.EOF, dec.Decode(&wrapperspb.StringValue{}))
+}
+
+func TestProtoMaxMessageSize(t *testing.T) {
+	c := codec.NewProto(4)
+	assert.ErrorIs(t, c.NewEncoder(io.Discard).Encode(wrapperspb.String("too long")), codec.ErrMessageTooLarge)
+
+	// A corrupt length prefix must not cause a huge allocation
+	data, _ := proto.Marshal(wrapperspb.String("too long"))
+	stream := append([]byte{byte(len(data))}, data...)
+	assert.ErrorIs(t, c.NewDecoder(bytes.NewReader(stream)).Decode(&wrapperspb.StringValue{}), codec.ErrMessageTooLarge)
+	stream = []byte{0xff, 0xff, 0xff, 0xff, 0x0f}
+	assert.ErrorIs(t, codec.Proto.NewDecoder(bytes.NewReader(stream)).Decode(&wrapperspb.StringValue{}), codec.ErrMessageTooLarge)
+}
+
+func TestProtoErrors(t *testing.T) {
+	assert.ErrorIs(t, codec.Proto.NewEncoder(io.Discard).Encode("not a message"), codec.ErrNotProtoMessage)
+
+	var buf bytes.Buffer
+	assert.NoError(t, codec.Proto.NewEncoder(&buf).Encode(wrapperspb.String("abc")))
+	truncated := buf.Bytes()[:buf.Len()-1]
+	assert.Equal(t, io.ErrUnexpectedEOF, codec.Proto.NewDecoder(bytes.NewReader(truncated)).Decode(&wrapperspb.StringValue{}))
+	assert.Equal(t, io.ErrUnexpectedEOF, codec.Proto.NewDecoder(bytes.NewReader([]byte{0x80})).Decode(&wrapperspb.StringValue{}))
+}
@@ -0,0 +1,5 @@
+// Package commit implements a job level commit protocol on top of a WriterFactory. Files are staged under a job
+// specific prefix (_temporary/{jobID}/) and only promoted to their final paths on Commit; which also writes a
+// manifest (_MANIFEST.json) and a _SUCCESS marker to every partition (directory) written by the job. Consumers
+// should only read partitions with a _SUCCESS marker. On Abort the staged files are removed.
+package commit
@@ -0,0 +1,12 @@
+package commit
+
+import "errors"
+
+var (
+	// ErrJobDone is returned when using a job which has already been committed or aborted
+	ErrJobDone = errors.New("job already committed or aborted")
+	// ErrOpenWriters is returned by Commit if writers of the job haven't been closed
+	ErrOpenWriters = errors.New("job has open writers")
+	// ErrCleanup is returned by Commit if the job was committed but staged files couldn't be removed
+	ErrCleanup = errors.New("job committed but staged files couldn't be removed")
+)
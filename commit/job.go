@@ -0,0 +1,338 @@
+package commit
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kvanticoss/goutils/v2/internal/layered"
+	"github.com/kvanticoss/goutils/v2/readerfactory"
+	"github.com/kvanticoss/goutils/v2/writerfactory"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+const (
+	// StagingDir is the directory under which jobs stage their files; it starts with "_" to be ignored by readers
+	StagingDir = "_temporary"
+	// ManifestName is the name of the manifest written to each partition on commit
+	ManifestName = "_MANIFEST.json"
+	// SuccessName is the name of the marker written to each partition once its files and manifest are in place
+	SuccessName = "_SUCCESS"
+)
+
+// Manifest lists the files a job committed to a partition
+type Manifest struct {
+	JobID       string         `json:"job_id"`
+	CommittedAt time.Time      `json:"committed_at"`
+	Files       []ManifestFile `json:"files"`
+}
+
+// ManifestFile describes a committed file; Path is relative to the partition. Records is only known if the file was
+// written by a record writer (see writerfactory.RecordCounter). Bytes and SHA256 are of the stored (e.g. compressed) content.
+type ManifestFile struct {
+	Path    string `json:"path"`
+	Records int64  `json:"records"`
+	Bytes   int64  `json:"bytes"`
+	SHA256  string `json:"sha256"`
+}
+
+// Job stages files and promotes them on Commit. It is safe for concurrent use.
+type Job struct {
+	id     string
+	wf     writerfactory.WriterFactory
+	rf     readerfactory.ReaderFactory
+	remove Remover
+
+	mutex  sync.Mutex
+	files  map[string]*stagedWriter // by final path
+	open   int
+	done   bool
+	nowFun func() time.Time
+}
+
+// NewJob returns a job writing through wf. The ReaderFactory and Remover must address the same storage as wf;
+// they are used to promote (copy) and remove staged files. An empty jobID generates a unique one.
+func NewJob(wf writerfactory.WriterFactory, rf readerfactory.ReaderFactory, remove Remover, jobID string) *Job {
+	if jobID == "" {
+		jobID = newJobID()
+	}
+	return &Job{
+		id:     jobID,
+		wf:     wf,
+		rf:     rf,
+		remove: remove,
+		files:  map[string]*stagedWriter{},
+		nowFun: time.Now,
+	}
+}
+
+func newJobID() string {
+	random := make([]byte, 4)
+	rand.Read(random)
+	return time.Now().UTC().Format("20060102T150405") + "-" + hex.EncodeToString(random)
+}
+
+// ID returns the id of the job
+func (j *Job) ID() string {
+	return j.id
+}
+
+// StagingPrefix is the prefix under which the files of the job are staged
+func (j *Job) StagingPrefix() string {
+	return StagingDir + "/" + j.id + "/"
+}
+
+// WriterFactory returns the writer factory of the job; paths are the final paths, the files are staged until Commit.
+// Writing the same path twice replaces the earlier file.
+func (j *Job) WriterFactory() writerfactory.WriterFactory {
+	return func(p string) (io.WriteCloser, error) {
+		j.mutex.Lock()
+		defer j.mutex.Unlock()
+		if j.done {
+			return nil, ErrJobDone
+		}
+		p = strings.TrimPrefix(path.Clean(p), "/")
+		wc, err := j.wf(j.StagingPrefix() + p)
+		if err != nil {
+			return nil, err
+		}
+		w := &stagedWriter{job: j, path: p, wc: wc, hash: sha256.New()}
+		j.files[p] = w
+		j.open++
+		return w, nil
+	}
+}
+
+// Commit promotes all staged (and not aborted) files to their final paths and writes a manifest and _SUCCESS marker per partition.
+// All writers must have been closed. If Commit fails the files promoted so far are removed again (files replaced by
+// them can't be restored) and the job can still be aborted to remove the staged files. Staged files which can't be
+// removed once the job has been committed are reported with ErrCleanup.
+func (j *Job) Commit() error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if j.done {
+		return ErrJobDone
+	}
+	if j.open > 0 {
+		return fmt.Errorf("%w: %d", ErrOpenWriters, j.open)
+	}
+
+	var cleanupErrs *multierror.Error
+	paths := make([]string, 0, len(j.files))
+	for p, w := range j.files {
+		if w.aborted {
+			if err := j.remove(j.StagingPrefix() + p); err != nil {
+				cleanupErrs = multierror.Append(cleanupErrs, err)
+			}
+			continue
+		}
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	written := []string{} // final paths (possibly partially) written; removed if the commit fails
+	fail := func(err error) error {
+		if rollbackErr := j.rollback(written); rollbackErr != nil {
+			return fmt.Errorf("%w (%v)", err, rollbackErr)
+		}
+		return err
+	}
+
+	committedAt := j.nowFun().UTC()
+	manifests := map[string]*Manifest{}
+	partitions := []string{}
+	for _, p := range paths {
+		written = append(written, p)
+		if err := j.promote(p); err != nil {
+			return fail(fmt.Errorf("failed to promote %s: %w", p, err))
+		}
+
+		dir := path.Dir(p)
+		manifest, ok := manifests[dir]
+		if !ok {
+			manifest = &Manifest{JobID: j.id, CommittedAt: committedAt, Files: []ManifestFile{}}
+			manifests[dir] = manifest
+			partitions = append(partitions, dir)
+		}
+		w := j.files[p]
+		manifest.Files = append(manifest.Files, ManifestFile{
+			Path:    path.Base(p),
+			Records: w.records,
+			Bytes:   w.bytes,
+			SHA256:  hex.EncodeToString(w.hash.Sum(nil)),
+		})
+	}
+
+	for _, dir := range partitions {
+		manifest, err := json.MarshalIndent(manifests[dir], "", "  ")
+		if err != nil {
+			return fail(err)
+		}
+		for _, file := range []struct {
+			name    string
+			content []byte
+		}{{ManifestName, manifest}, {SuccessName, nil}} {
+			p := path.Join(dir, file.name)
+			written = append(written, p)
+			if err := j.writeFile(p, file.content); err != nil {
+				return fail(fmt.Errorf("failed to write %s: %w", p, err))
+			}
+		}
+	}
+
+	j.done = true
+	for _, p := range paths {
+		if err := j.remove(j.StagingPrefix() + p); err != nil {
+			cleanupErrs = multierror.Append(cleanupErrs, err)
+		}
+	}
+	if err := cleanupErrs.ErrorOrNil(); err != nil {
+		return fmt.Errorf("%w: %w", ErrCleanup, err)
+	}
+	return nil
+}
+
+// rollback removes the final paths written by a failed commit; the markers first so partitions never look complete
+func (j *Job) rollback(written []string) error {
+	var errs *multierror.Error
+	for i := len(written) - 1; i >= 0; i-- {
+		if err := j.remove(written[i]); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("failed to roll back %s: %w", written[i], err))
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+// promote copies the staged file to its final path
+func (j *Job) promote(p string) error {
+	r, err := j.rf(j.StagingPrefix() + p)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	w, err := j.wf(p)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(w, r)
+	if err != nil {
+		layered.Abort(w)
+		return err
+	}
+	if n != j.files[p].bytes {
+		w.Close()
+		return fmt.Errorf("staged file has %d bytes; expected %d", n, j.files[p].bytes)
+	}
+	return w.Close()
+}
+
+func (j *Job) writeFile(p string, content []byte) error {
+	w, err := j.wf(p)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(content); err != nil {
+		layered.Abort(w)
+		return err
+	}
+	return w.Close()
+}
+
+// Abort aborts all open writers and removes all staged files. Aborting a committed job is a no-op.
+func (j *Job) Abort() error {
+	j.mutex.Lock()
+	if j.done {
+		j.mutex.Unlock()
+		return nil
+	}
+	j.done = true
+	files := j.files
+	j.mutex.Unlock()
+
+	var firstErr error
+	for p, w := range files {
+		w.Abort()
+		if err := j.remove(j.StagingPrefix() + p); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// stagedWriter writes to the staged file keeping track of size, checksum and records
+type stagedWriter struct {
+	job  *Job
+	path string
+	wc   io.WriteCloser
+
+	mutex   sync.Mutex
+	hash    hash.Hash
+	bytes   int64
+	records int64
+	closed  bool
+	aborted bool // aborted files aren't committed
+}
+
+func (w *stagedWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.closed {
+		return 0, ErrJobDone
+	}
+	n, err := w.wc.Write(p)
+	w.hash.Write(p[:n])
+	w.bytes += int64(n)
+	return n, err
+}
+
+// AddRecords implements writerfactory.RecordCounter
+func (w *stagedWriter) AddRecords(n int) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.records += int64(n)
+}
+
+func (w *stagedWriter) Close() error {
+	if !w.markClosed() {
+		return nil
+	}
+	return w.wc.Close()
+}
+
+// Abort implements writerfactory.Aborter; the file won't be committed
+func (w *stagedWriter) Abort() error {
+	w.mutex.Lock()
+	w.aborted = true
+	w.mutex.Unlock()
+	if !w.markClosed() {
+		return nil
+	}
+	return layered.Abort(w.wc)
+}
+
+// markClosed returns true the first time it's called
+func (w *stagedWriter) markClosed() bool {
+	w.mutex.Lock()
+	if w.closed {
+		w.mutex.Unlock()
+		return false
+	}
+	w.closed = true
+	w.mutex.Unlock()
+
+	w.job.mutex.Lock()
+	w.job.open--
+	w.job.mutex.Unlock()
+	return true
+}
@@ -0,0 +1,155 @@
+package commit_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kvanticoss/goutils/v2/commit"
+	"github.com/kvanticoss/goutils/v2/iterator"
+	"github.com/kvanticoss/goutils/v2/iterator/test_utils"
+	"github.com/kvanticoss/goutils/v2/readerfactory"
+	"github.com/kvanticoss/goutils/v2/recordwriter"
+	"github.com/kvanticoss/goutils/v2/writerfactory"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type record struct {
+	A string
+}
+
+func keys[V any](m map[string]V) []string {
+	res := []string{}
+	for k := range m {
+		res = append(res, k)
+	}
+	return res
+}
+
+func TestJobCommit(t *testing.T) {
+	buffers, wf := writerfactory.GetMemoryWriterFactory()
+	job := commit.NewJob(wf, readerfactory.GetMemoryReaderFactory(buffers), commit.GetMemoryRemover(buffers), "job1")
+
+	records := []record{{"a1"}, {"a2"}, {"a3"}}
+	err := recordwriter.NewLineJSONPartitionedBySize(test_utils.NewDummyIteratorFromArr(records), job.WriterFactory().WithPrefix("date=2023-01-01"), 1, "part", true)
+	assert.Equal(t, iterator.ErrIteratorStop, err)
+
+	assert.ElementsMatch(t, []string{
+		"_temporary/job1/date=2023-01-01/part_000000.ndjson.gz",
+		"_temporary/job1/date=2023-01-01/part_000001.ndjson.gz",
+		"_temporary/job1/date=2023-01-01/part_000002.ndjson.gz",
+	}, keys(buffers), "nothing is visible before commit")
+
+	assert.NoError(t, job.Commit())
+	assert.ElementsMatch(t, []string{
+		"date=2023-01-01/part_000000.ndjson.gz",
+		"date=2023-01-01/part_000001.ndjson.gz",
+		"date=2023-01-01/part_000002.ndjson.gz",
+		"date=2023-01-01/_MANIFEST.json",
+		"date=2023-01-01/_SUCCESS",
+	}, keys(buffers), "staged files are promoted and removed")
+
+	manifest := commit.Manifest{}
+	assert.NoError(t, json.Unmarshal(buffers["date=2023-01-01/_MANIFEST.json"].Bytes(), &manifest))
+	assert.Equal(t, "job1", manifest.JobID)
+	assert.WithinDuration(t, time.Now(), manifest.CommittedAt, time.Minute)
+	if assert.Len(t, manifest.Files, 3) {
+		content := buffers["date=2023-01-01/part_000000.ndjson.gz"].Bytes()
+		sum := sha256.Sum256(content)
+		assert.Equal(t, commit.ManifestFile{
+			Path:    "part_000000.ndjson.gz",
+			Records: 1,
+			Bytes:   int64(len(content)),
+			SHA256:  hex.EncodeToString(sum[:]),
+		}, manifest.Files[0])
+	}
+
+	_, err = job.WriterFactory()("more")
+	assert.ErrorIs(t, err, commit.ErrJobDone)
+	assert.ErrorIs(t, job.Commit(), commit.ErrJobDone)
+}
+
+func TestJobAbort(t *testing.T) {
+	buffers, wf := writerfactory.GetMemoryWriterFactory()
+	job := commit.NewJob(wf, readerfactory.GetMemoryReaderFactory(buffers), commit.GetMemoryRemover(buffers), "")
+
+	w, err := job.WriterFactory()("a/file.txt")
+	assert.NoError(t, err)
+	_, err = w.Write([]byte("content"))
+	assert.NoError(t, err)
+	assert.ErrorIs(t, job.Commit(), commit.ErrOpenWriters)
+
+	assert.NoError(t, job.Abort())
+	assert.Empty(t, buffers, "staged files are removed")
+	assert.ErrorIs(t, job.Commit(), commit.ErrJobDone)
+}
+
+func TestJobCommitRollsBack(t *testing.T) {
+	buffers, memoryWF := writerfactory.GetMemoryWriterFactory()
+	errBackend := errors.New("backend failure")
+	wf := func(p string) (io.WriteCloser, error) {
+		if p == "b/file.txt" {
+			return nil, errBackend
+		}
+		return memoryWF(p)
+	}
+	job := commit.NewJob(wf, readerfactory.GetMemoryReaderFactory(buffers), commit.GetMemoryRemover(buffers), "job")
+	for _, p := range []string{"a/file.txt", "b/file.txt"} {
+		w, err := job.WriterFactory()(p)
+		assert.NoError(t, err)
+		w.Write([]byte(p))
+		assert.NoError(t, w.Close())
+	}
+
+	assert.ErrorIs(t, job.Commit(), errBackend)
+	assert.ElementsMatch(t, []string{"_temporary/job/a/file.txt", "_temporary/job/b/file.txt"}, keys(buffers),
+		"promoted files are removed and the staged files kept")
+	assert.NoError(t, job.Abort())
+	assert.Empty(t, buffers)
+}
+
+func TestJobCommitReportsCleanupErrors(t *testing.T) {
+	buffers, wf := writerfactory.GetMemoryWriterFactory()
+	errRemove := errors.New("remove failure")
+	job := commit.NewJob(wf, readerfactory.GetMemoryReaderFactory(buffers), func(string) error { return errRemove }, "job")
+	w, err := job.WriterFactory()("file.txt")
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	err = job.Commit()
+	assert.ErrorIs(t, err, commit.ErrCleanup)
+	assert.Contains(t, err.Error(), errRemove.Error())
+	assert.Contains(t, buffers, commit.SuccessName, "the job is committed")
+}
+
+func TestJobSkipsAbortedWriters(t *testing.T) {
+	base := t.TempDir() + "/"
+	wf := writerfactory.GetLocalWriterFactory(base)
+	job := commit.NewJob(wf, readerfactory.GetLocalReaderFactory(base), commit.GetLocalRemover(base), "job")
+
+	w, err := job.WriterFactory()("kept.txt")
+	assert.NoError(t, err)
+	w.Write([]byte("kept"))
+	assert.NoError(t, w.Close())
+
+	w, err = job.WriterFactory().WithGzip()("aborted.txt")
+	assert.NoError(t, err)
+	w.Write([]byte("aborted"))
+	assert.NoError(t, w.(writerfactory.Aborter).Abort())
+
+	assert.NoError(t, job.Commit())
+	files := []string{}
+	it := readerfactory.GetLocalLister(base)("")
+	for info, err := it(); err == nil; info, err = it() {
+		files = append(files, info.Path)
+	}
+	assert.Equal(t, []string{"_MANIFEST.json", "_SUCCESS", "kept.txt"}, files)
+	_, err = os.Stat(base + commit.StagingDir)
+	assert.True(t, os.IsNotExist(err), "empty staging directories are removed")
+}
@@ -0,0 +1,45 @@
+package commit
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/kvanticoss/goutils/v2/writerfactory"
+)
+
+// Remover deletes the object at path; removing a missing path is not an error
+type Remover func(path string) error
+
+// GetLocalRemover returns a remover of local files in the basePath; parent directories left empty are removed too.
+// Paths are sanitized as by writerfactory.GetLocalWriterFactory.
+// If basePath == "" it defaults to current working dir ("./")
+func GetLocalRemover(basePath string) Remover {
+	return func(path string) error {
+		fullPath, err := writerfactory.JoinLocalPath(basePath, path)
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(fullPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		cleaned, _ := writerfactory.SanitizePath(path, writerfactory.LocalPathRules)
+		for dir := filepath.Dir(cleaned); dir != "." && dir != "/"; dir = filepath.Dir(dir) {
+			dirPath, _ := writerfactory.JoinLocalPath(basePath, dir)
+			if os.Remove(dirPath) != nil { // not empty
+				break
+			}
+		}
+		return nil
+	}
+}
+
+// GetMemoryRemover returns a remover of the buffers of writerfactory.GetMemoryWriterFactory
+func GetMemoryRemover(buffers map[string]*bytes.Buffer) Remover {
+	return func(path string) error {
+		delete(buffers, path)
+		return nil
+	}
+}
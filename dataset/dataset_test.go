@@ -0,0 +1,142 @@
+package dataset_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kvanticoss/goutils/v2/checksum"
+	"github.com/kvanticoss/goutils/v2/codec"
+	"github.com/kvanticoss/goutils/v2/dataset"
+	"github.com/kvanticoss/goutils/v2/gzip"
+	"github.com/kvanticoss/goutils/v2/iterator"
+	"github.com/kvanticoss/goutils/v2/readerfactory"
+	"github.com/kvanticoss/goutils/v2/writerfactory"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type event struct {
+	ID      int    `json:"id"`
+	Date    string `json:"date"`
+	Country string
+}
+
+func write(t *testing.T, wf writerfactory.WriterFactory, path, content string) {
+	w, err := wf(path)
+	assert.NoError(t, err)
+	_, err = w.Write([]byte(content))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+}
+
+func testDataset(t *testing.T) (readerfactory.DirLister, readerfactory.ReaderFactory, *[]string) {
+	buffers, wf := writerfactory.GetMemoryWriterFactory()
+	write(t, wf, "events/date=2023-01-01/country=se/part_000000.ndjson", `{"id":1}`+"\n"+`{"id":2}`+"\n")
+	write(t, wf.WithGzip(), "events/date=2023-01-01/country=no/part_000000.ndjson", `{"id":3}`+"\n")
+	write(t, wf, "events/date=2023-01-02/country=se/part_000000.ndjson", `{"id":4,"Country":"dk"}`+"\n")
+	write(t, wf, "events/date=2023-01-02/country=se/_SUCCESS", "")
+	write(t, wf, "events/date=2023-01-03/country=se/part_000000.ndjson", `{"id":5}`+"\n")
+
+	listed := []string{}
+	dl := readerfactory.GetMemoryDirLister(buffers)
+	return func(dir string) iterator.RecordIterator[readerfactory.ObjectInfo] {
+		listed = append(listed, dir)
+		return dl(dir)
+	}, readerfactory.GetMemoryReaderFactory(buffers), &listed
+}
+
+func readAll(t *testing.T, it iterator.RecordIterator[*event]) []event {
+	events := []event{}
+	for record, err := it(); err != iterator.ErrIteratorStop; record, err = it() {
+		if !assert.NoError(t, err) {
+			break
+		}
+		events = append(events, *record)
+	}
+	return events
+}
+
+func TestListFilesPrunes(t *testing.T) {
+	dl, _, listed := testDataset(t)
+
+	it := dataset.ListFiles(dl, "events/", dataset.Filter{
+		"date":    dataset.DateBetween(time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC), time.Time{}),
+		"country": dataset.Equals("se"),
+	})
+	paths := []string{}
+	for file, err := it(); err != iterator.ErrIteratorStop; file, err = it() {
+		assert.NoError(t, err)
+		paths = append(paths, file.Path)
+		assert.Equal(t, "se", file.Partitions.AsMap()["country"])
+	}
+	assert.Equal(t, []string{
+		"events/date=2023-01-02/country=se/part_000000.ndjson",
+		"events/date=2023-01-03/country=se/part_000000.ndjson",
+	}, paths)
+	assert.Equal(t, []string{
+		"events/",
+		"events/date=2023-01-02/",
+		"events/date=2023-01-02/country=se/",
+		"events/date=2023-01-03/",
+		"events/date=2023-01-03/country=se/",
+	}, *listed, "pruned directories must not be listed")
+}
+
+func TestNewRecordIterator(t *testing.T) {
+	dl, rf, _ := testDataset(t)
+
+	it := dataset.NewRecordIterator(func() *event { return &event{} }, dl, rf, "events", dataset.Options{
+		Filter:           dataset.Filter{"date": dataset.In("2023-01-01", "2023-01-02")},
+		InjectPartitions: true,
+	})
+	assert.Equal(t, []event{
+		{ID: 3, Date: "2023-01-01", Country: "no"}, // gzipped
+		{ID: 1, Date: "2023-01-01", Country: "se"},
+		{ID: 2, Date: "2023-01-01", Country: "se"},
+		{ID: 4, Date: "2023-01-02", Country: "dk"}, // values in the record take precedence
+	}, readAll(t, it))
+}
+
+func TestNewRecordIteratorSkipsSidecars(t *testing.T) {
+	buffers, wf := writerfactory.GetMemoryWriterFactory()
+	sidecarWF := wf.WithChecksums(checksum.SHA256, checksum.CRC32C, checksum.MD5)
+	write(t, sidecarWF, "events/date=2023-01-01/part_000000.ndjson", `{"id":1}`+"\n")
+	write(t, writerfactory.WithIndexedGzip(sidecarWF, gzip.IndexOptions{}), "events/date=2023-01-01/part_000001.ndjson.gz", `{"id":2}`+"\n")
+	assert.Len(t, buffers, 12, "two data files and an index with their sidecars")
+
+	dl, rf := readerfactory.GetMemoryDirLister(buffers), readerfactory.GetMemoryReaderFactory(buffers)
+	for _, opts := range []dataset.Options{{}, {Codec: codec.JSON}} {
+		it := dataset.NewRecordIterator(func() *event { return &event{} }, dl, rf, "events", opts)
+		assert.Equal(t, []event{{ID: 1}, {ID: 2}}, readAll(t, it))
+	}
+}
+
+func TestNewRecordIteratorMaps(t *testing.T) {
+	dl, rf, _ := testDataset(t)
+
+	it := dataset.NewRecordIterator(func() *map[string]interface{} { return &map[string]interface{}{} }, dl, rf, "events/date=2023-01-03", dataset.Options{
+		InjectPartitions: true,
+	})
+	record, err := it()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"id": float64(5), "date": "2023-01-03", "country": "se"}, *record)
+	_, err = it()
+	assert.Equal(t, iterator.ErrIteratorStop, err)
+}
+
+func TestPredicates(t *testing.T) {
+	assert.True(t, dataset.Between("2023-01", "2023-02")("2023-01-15"))
+	assert.False(t, dataset.Between("2023-01", "2023-02")("2023-02-15"))
+	assert.True(t, dataset.Between("", "b")("a"))
+
+	from := time.Date(2023, 1, 10, 12, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 2, 5, 0, 0, 0, 0, time.UTC)
+	between := dataset.DateBetween(from, to)
+	assert.True(t, between("2023-01-10"))
+	assert.True(t, between("20230205"))
+	assert.True(t, between("2023-01-20T13"))
+	assert.True(t, between("2023-01"), "months overlapping the range match")
+	assert.False(t, between("2023-03"))
+	assert.False(t, between("2023-01-09"))
+	assert.False(t, between("not a date"))
+}
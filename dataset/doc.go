@@ -0,0 +1,4 @@
+// Package dataset reads hive partitioned datasets (e.g. data/date=2023-01-01/country=se/part_000000.ndjson.gz).
+// Directories are listed one level at the time and sub trees whose key=value partitions don't match the
+// Filter are pruned without being listed.
+package dataset
@@ -0,0 +1,79 @@
+package dataset
+
+import (
+	"time"
+
+	"github.com/kvanticoss/goutils/v2/keyvaluelist"
+)
+
+// Predicate decides if a partition value should be read
+type Predicate func(value string) bool
+
+// Filter maps partition keys to predicates. Paths without a partition for a key are not pruned by that key.
+type Filter map[string]Predicate
+
+// Match reports if all partitions pass the predicates of their keys
+func (f Filter) Match(partitions keyvaluelist.KeyValues) bool {
+	for _, kv := range partitions {
+		if predicate, ok := f[kv.Key]; ok && !predicate(kv.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// Equals matches any of the values; i.e. equality or IN
+func Equals(values ...string) Predicate {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return func(value string) bool {
+		return set[value]
+	}
+}
+
+// In is an alias of Equals
+func In(values ...string) Predicate {
+	return Equals(values...)
+}
+
+// Between matches values in the inclusive lexicographic range [from, to]; an empty bound is unbounded.
+// Suitable for ISO dates (2006-01-02) and zero padded numbers.
+func Between(from, to string) Predicate {
+	return func(value string) bool {
+		return (from == "" || value >= from) && (to == "" || value <= to)
+	}
+}
+
+// dateLayouts are the partition value formats understood by DateBetween
+var dateLayouts = []string{
+	"2006-01-02",
+	"20060102",
+	"2006-01-02T15",
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01",
+}
+
+// DateBetween matches values which parse as dates (e.g. 2006-01-02, 20060102, 2006-01-02T15) with their
+// calendar day in the inclusive range [from, to]; a zero bound is unbounded. Values which aren't dates don't match.
+func DateBetween(from, to time.Time) Predicate {
+	day := func(t time.Time) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return func(value string) bool {
+		for _, layout := range dateLayouts {
+			t, err := time.Parse(layout, value)
+			if err != nil {
+				continue
+			}
+			if layout == "2006-01" { // a month partition overlaps the range if any of its days do
+				last := t.AddDate(0, 1, -1)
+				return (from.IsZero() || !last.Before(day(from))) && (to.IsZero() || !t.After(day(to)))
+			}
+			t = day(t)
+			return (from.IsZero() || !t.Before(day(from))) && (to.IsZero() || !t.After(day(to)))
+		}
+		return false
+	}
+}
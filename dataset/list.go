@@ -0,0 +1,92 @@
+package dataset
+
+import (
+	"path"
+	"strings"
+
+	"github.com/kvanticoss/goutils/v2/checksum"
+	"github.com/kvanticoss/goutils/v2/gzip"
+	"github.com/kvanticoss/goutils/v2/iterator"
+	"github.com/kvanticoss/goutils/v2/keyvaluelist"
+	"github.com/kvanticoss/goutils/v2/readerfactory"
+)
+
+// File is a data file of the dataset along with the partitions parsed from its path
+type File struct {
+	readerfactory.ObjectInfo
+	Partitions keyvaluelist.KeyValues
+}
+
+// sidecarExtensions are the extensions of files describing a data file written next to it; see
+// writerfactory.WithChecksums and writerfactory.WithIndexedGzip
+var sidecarExtensions = []string{
+	"." + string(checksum.SHA256),
+	"." + string(checksum.CRC32C),
+	"." + string(checksum.MD5),
+	gzip.IndexExtension,
+}
+
+// isSidecar tells if the file describes a data file rather than holding data
+func isSidecar(name string) bool {
+	for _, ext := range sidecarExtensions {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+type pendingDir struct {
+	it         iterator.RecordIterator[readerfactory.ObjectInfo]
+	partitions keyvaluelist.KeyValues
+}
+
+// ListFiles yields the files below prefix (a directory) whose partitions match the filter, depth first in
+// lexicographic order. Directories are listed one at the time and key=value directories not matching the filter
+// are never descended into. Files and directories whose names start with "_" or "." (e.g. _SUCCESS) are skipped as are
+// checksum sidecars (.sha256, .crc32c and .md5) and gzip indexes (.idx).
+func ListFiles(dl readerfactory.DirLister, prefix string, filter Filter) iterator.RecordIterator[File] {
+	rootPartitions := keyvaluelist.NewKeyValuesFromPath(prefix)
+	if !filter.Match(rootPartitions) {
+		return func() (File, error) {
+			return File{}, iterator.ErrIteratorStop
+		}
+	}
+
+	stack := []pendingDir{{it: dl(prefix), partitions: rootPartitions}}
+	return func() (File, error) {
+		for len(stack) > 0 {
+			current := stack[len(stack)-1]
+			object, err := current.it()
+			if err == iterator.ErrIteratorStop {
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			if err != nil {
+				stack = nil
+				return File{}, err
+			}
+
+			name := path.Base(strings.TrimSuffix(object.Path, "/"))
+			if strings.HasPrefix(name, "_") || strings.HasPrefix(name, ".") {
+				continue
+			}
+			if !object.IsDir {
+				if isSidecar(name) {
+					continue
+				}
+				return File{ObjectInfo: object, Partitions: current.partitions}, nil
+			}
+
+			partitions := current.partitions
+			if kv, err := keyvaluelist.FromHadoopPartition(name); err == nil {
+				partitions = append(partitions[:len(partitions):len(partitions)], kv)
+				if !filter.Match(keyvaluelist.KeyValues{kv}) {
+					continue
+				}
+			}
+			stack = append(stack, pendingDir{it: dl(object.Path), partitions: partitions})
+		}
+		return File{}, iterator.ErrIteratorStop
+	}
+}
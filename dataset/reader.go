@@ -0,0 +1,165 @@
+package dataset
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/kvanticoss/goutils/v2/codec"
+	"github.com/kvanticoss/goutils/v2/eioutil"
+	"github.com/kvanticoss/goutils/v2/iterator"
+	"github.com/kvanticoss/goutils/v2/keyvaluelist"
+	"github.com/kvanticoss/goutils/v2/readerfactory"
+)
+
+// Options configures NewRecordIterator
+type Options struct {
+	// Filter prunes partitions; nil reads all files
+	Filter Filter
+	// Codec decodes all files; if nil the codec registered for the extension of each file is used (see codec.ForPath)
+	Codec codec.Codec
+	// InjectPartitions sets the partition values of the file on each record read from it; see SetPartitions
+	InjectPartitions bool
+}
+
+// NewRecordIterator yields the records of all files below prefix matching the filter (see ListFiles).
+// Gzip, zstd and snappy compressed files are detected by their content and decompressed regardless of their names
+// (see eioutil.NewDecompressingReader). Errors are annotated with the path of the file and end the iteration.
+func NewRecordIterator[T any](
+	new func() T,
+	dl readerfactory.DirLister,
+	rf readerfactory.ReaderFactory,
+	prefix string,
+	opts Options,
+) iterator.RecordIterator[T] {
+	files := ListFiles(dl, prefix, opts.Filter)
+
+	var (
+		current  iterator.RecordIterator[T]
+		file     File
+		closer   io.Closer
+		finalErr error
+	)
+	fail := func(err error) (T, error) {
+		if closer != nil {
+			closer.Close()
+		}
+		finalErr = err
+		var empty T
+		return empty, err
+	}
+
+	return func() (T, error) {
+		if finalErr != nil {
+			var empty T
+			return empty, finalErr
+		}
+		for {
+			if current == nil {
+				var err error
+				if file, err = files(); err != nil {
+					return fail(err)
+				}
+				c := opts.Codec
+				if c == nil {
+					if c, err = codec.ForPath(file.Path); err != nil {
+						return fail(err)
+					}
+				}
+				r, err := open(rf, file.Path)
+				if err != nil {
+					return fail(fmt.Errorf("%s: %w", file.Path, err))
+				}
+				closer = r
+				current = iterator.CodecRecordIterator(new, r, c)
+			}
+
+			record, err := current()
+			if err == iterator.ErrIteratorStop {
+				current, closer = nil, nil // closed by the iterator
+				continue
+			}
+			if err != nil {
+				return fail(fmt.Errorf("%s: %w", file.Path, err))
+			}
+			if opts.InjectPartitions {
+				SetPartitions(record, file.Partitions)
+			}
+			return record, nil
+		}
+	}
+}
+
+// open opens the path and transparently decompresses it if the content is gzip, zstd or snappy compressed
+func open(rf readerfactory.ReaderFactory, path string) (io.ReadCloser, error) {
+	rc, err := rf(path)
+	if err != nil {
+		return nil, err
+	}
+	return eioutil.NewDecompressingReader(rc)
+}
+
+// SetPartitions sets the partitions on the record; records implementing keyvaluelist.PartitionSetter receive all
+// partitions. Maps with string keys (or pointers to them) get the missing keys added and for pointers to structs
+// empty string fields whose json tag (or name; case-insensitively) equals a partition key are set.
+func SetPartitions(record interface{}, partitions keyvaluelist.KeyValues) {
+	if setter, ok := record.(keyvaluelist.PartitionSetter); ok {
+		setter.SetPartitions(partitions)
+		return
+	}
+
+	rv := reflect.ValueOf(record)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return
+		}
+		rv = rv.Elem()
+	} else if rv.Kind() != reflect.Map {
+		return // a struct value can't be modified
+	}
+
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.IsNil() || rv.Type().Key().Kind() != reflect.String {
+			return
+		}
+		valueType := rv.Type().Elem()
+		if !reflect.TypeOf("").AssignableTo(valueType) {
+			return
+		}
+		for _, kv := range partitions {
+			key := reflect.ValueOf(kv.Key).Convert(rv.Type().Key())
+			if !rv.MapIndex(key).IsValid() {
+				rv.SetMapIndex(key, reflect.ValueOf(kv.Value).Convert(valueType))
+			}
+		}
+	case reflect.Struct:
+		for _, kv := range partitions {
+			if field, ok := fieldByKey(rv, kv.Key); ok && field.Kind() == reflect.String && field.String() == "" {
+				field.SetString(kv.Value)
+			}
+		}
+	}
+}
+
+// fieldByKey finds the exported field of the struct named key by its json tag or (if untagged) its name
+func fieldByKey(rv reflect.Value, key string) (reflect.Value, bool) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			if tagName := strings.Split(tag, ",")[0]; tagName != "" {
+				name = tagName
+			}
+		}
+		if strings.EqualFold(name, key) {
+			return rv.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
@@ -0,0 +1,76 @@
+package eioutil
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+
+	"github.com/kvanticoss/goutils/v2/gzip"
+	"github.com/kvanticoss/goutils/v2/snappy"
+	"github.com/kvanticoss/goutils/v2/zstd"
+)
+
+// Compression is a compression format recognized by its magic bytes
+type Compression string
+
+// Compression formats detected by DetectCompression
+const (
+	None   Compression = ""
+	Gzip   Compression = "gzip"
+	Zstd   Compression = "zstd"
+	Snappy Compression = "snappy"
+)
+
+var (
+	gzipMagic   = []byte{0x1f, 0x8b}
+	zstdMagic   = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	snappyMagic = []byte("\xff\x06\x00\x00sNaPpY") // stream identifier of the framing format
+	s2Magic     = []byte("\xff\x06\x00\x00S2sTwO")
+
+	maxMagicLength = len(snappyMagic)
+)
+
+// DetectCompression returns the compression format of the stream starting with the bytes b
+func DetectCompression(b []byte) Compression {
+	switch {
+	case bytes.HasPrefix(b, gzipMagic):
+		return Gzip
+	case bytes.HasPrefix(b, zstdMagic):
+		return Zstd
+	case bytes.HasPrefix(b, snappyMagic), bytes.HasPrefix(b, s2Magic):
+		return Snappy
+	}
+	return None
+}
+
+// NewDecompressingReader peeks at the first bytes of r and returns a reader decompressing gzip, zstd or framed
+// snappy streams; other streams are returned as is regardless of any file extension. Closing the returned reader
+// closes r, as does a failure to read the compression header.
+func NewDecompressingReader(r io.ReadCloser) (ReadCloser, error) {
+	br := bufio.NewReader(r)
+	// Only peek further when the first byte can start a magic sequence; so that plain streams (e.g. a pipe of
+	// NDJSON) aren't blocked waiting for more data than the first record
+	magic, _ := br.Peek(1)
+	if len(magic) == 1 && (magic[0] == gzipMagic[0] || magic[0] == zstdMagic[0] || magic[0] == snappyMagic[0]) {
+		magic, _ = br.Peek(maxMagicLength) // short streams are returned as is
+	}
+	buffered := NewReadCloser(br, r.Close)
+
+	var decompressor ReadCloser
+	var err error
+	switch DetectCompression(magic) {
+	case Gzip:
+		decompressor, err = gzip.NewReader(buffered)
+	case Zstd:
+		decompressor, err = zstd.NewReader(buffered)
+	case Snappy:
+		decompressor = snappy.NewReader(buffered)
+	default:
+		decompressor = buffered
+	}
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	return decompressor, nil
+}
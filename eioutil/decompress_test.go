@@ -0,0 +1,71 @@
+package eioutil_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/kvanticoss/goutils/v2/eioutil"
+	"github.com/kvanticoss/goutils/v2/gzip"
+	"github.com/kvanticoss/goutils/v2/snappy"
+	"github.com/kvanticoss/goutils/v2/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+type closeTracker struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeTracker) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestNewDecompressingReader(t *testing.T) {
+	content := []byte(`{"id":1}` + "\n" + `{"id":2}` + "\n")
+	compress := func(newWriter func(w io.Writer) io.WriteCloser) []byte {
+		var buf bytes.Buffer
+		w := newWriter(&buf)
+		w.Write(content)
+		assert.NoError(t, w.Close())
+		return buf.Bytes()
+	}
+
+	for name, test := range map[string]struct {
+		data        []byte
+		compression eioutil.Compression
+	}{
+		"plain":  {content, eioutil.None},
+		"empty":  {[]byte{}, eioutil.None},
+		"gzip":   {compress(func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }), eioutil.Gzip},
+		"zstd":   {compress(func(w io.Writer) io.WriteCloser { return zstd.NewWriter(w) }), eioutil.Zstd},
+		"snappy": {compress(func(w io.Writer) io.WriteCloser { return snappy.NewWriter(w) }), eioutil.Snappy},
+		"s2": {compress(func(w io.Writer) io.WriteCloser {
+			return s2.NewWriter(w) // the S2 extension of the framing format
+		}), eioutil.Snappy},
+	} {
+		assert.Equal(t, test.compression, eioutil.DetectCompression(test.data), name)
+
+		underlying := &closeTracker{Reader: bytes.NewReader(test.data)}
+		r, err := eioutil.NewDecompressingReader(underlying)
+		if !assert.NoError(t, err, name) {
+			continue
+		}
+		got, err := io.ReadAll(r)
+		assert.NoError(t, err, name)
+		if len(test.data) > 0 {
+			assert.Equal(t, content, got, name)
+		}
+		assert.NoError(t, r.Close())
+		assert.True(t, underlying.closed, name)
+	}
+}
+
+func TestNewDecompressingReaderCorruptHeader(t *testing.T) {
+	underlying := &closeTracker{Reader: bytes.NewReader([]byte{0x1f, 0x8b, 0x00})}
+	_, err := eioutil.NewDecompressingReader(underlying)
+	assert.Error(t, err)
+	assert.True(t, underlying.closed)
+}
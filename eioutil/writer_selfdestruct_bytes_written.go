@@ -4,6 +4,7 @@ package eioutil
 // maxBytes have been written to it. Any further writes will return an eioutil.ErrAlreadyClosed
 // if the underlying writer returns an error on Close(); that error will be returned on a write
 // which would have forced a file to be closed.
+// See writerfactory.WithRotation for rolling over to new files instead.
 func NewWriterCloserWithSelfDestructAfterMaxBytes(maxBytes int, wc WriteCloser) WriteCloser {
 	bytesWritten := 0
 	closed := false
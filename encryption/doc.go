@@ -0,0 +1,10 @@
+// Package encryption provides streaming AES-GCM encryption; data is split into fixed size chunks which are
+// authenticated individually so streams can be written and read without buffering them in full, while truncation,
+// reordering and tampering (including of the header) are detected when reading.
+//
+// The stream starts with a header; magic ("KVENC"), version, nonce scheme, chunk size, key id, a random salt
+// (32 bytes) and a random nonce prefix. The header is authenticated as additional data of every chunk. Each stream
+// is encrypted with its own key derived with HKDF-SHA256 from the key of the KeyProvider, the salt and the key id
+// (like Tink's AES-GCM-HKDF streaming AEAD). The nonce of each chunk is the nonce prefix (7 bytes), the chunk
+// counter (4 bytes, big endian) and a flag (1 byte) set on the last chunk only.
+package encryption
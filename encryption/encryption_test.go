@@ -0,0 +1,150 @@
+package encryption
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testKey = bytes.Repeat([]byte{0x42}, 32)
+
+type nopFlushCloser struct {
+	*bytes.Buffer
+	closed bool
+}
+
+func (n *nopFlushCloser) Close() error {
+	n.closed = true
+	return nil
+}
+
+func encrypt(t *testing.T, keys KeyProvider, chunkSize int, content []byte) []byte {
+	buf := &nopFlushCloser{Buffer: &bytes.Buffer{}}
+	w, err := NewWriterWithOptions(buf, keys, Options{ChunkSize: chunkSize})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	for rest := content; len(rest) > 0; {
+		n := len(rest)
+		if n > 7 {
+			n = 7
+		}
+		_, err := w.Write(rest[:n])
+		assert.NoError(t, err)
+		rest = rest[n:]
+	}
+	assert.NoError(t, w.Close())
+	assert.True(t, buf.closed, "Close should be cascaded to the underlying writer")
+	return buf.Bytes()
+}
+
+func decrypt(keys KeyProvider, data []byte) ([]byte, error) {
+	r, err := NewReader(io.NopCloser(bytes.NewReader(data)), keys)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func TestRoundTrip(t *testing.T) {
+	keys := NewStaticKeyProvider("key-1", testKey)
+	for _, size := range []int{0, 1, 15, 16, 17, 32, 1000} {
+		content := bytes.Repeat([]byte("a"), size)
+		data := encrypt(t, keys, 16, content)
+		assert.NotContains(t, string(data), "aaaa")
+
+		got, err := decrypt(keys, data)
+		assert.NoError(t, err, size)
+		assert.Equal(t, content, append([]byte{}, got...), size)
+	}
+
+	// Same content, different nonce prefix
+	assert.NotEqual(t, encrypt(t, keys, 0, []byte("hello")), encrypt(t, keys, 0, []byte("hello")))
+}
+
+func TestKeyRotation(t *testing.T) {
+	keys := &StaticKeys{CurrentID: "old", Keys: map[string][]byte{"old": testKey}}
+	old := encrypt(t, keys, 0, []byte("old data"))
+
+	keys.Keys["new"] = bytes.Repeat([]byte{0x01}, 16)
+	keys.CurrentID = "new"
+	current := encrypt(t, keys, 0, []byte("new data"))
+
+	r, err := NewReader(io.NopCloser(bytes.NewReader(old)), keys)
+	assert.NoError(t, err)
+	assert.Equal(t, "old", r.KeyID())
+	got, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "old data", string(got))
+
+	got, err = decrypt(keys, current)
+	assert.NoError(t, err)
+	assert.Equal(t, "new data", string(got))
+
+	_, err = decrypt(NewStaticKeyProvider("other", testKey), current)
+	assert.True(t, errors.Is(err, ErrUnknownKey))
+	_, err = decrypt(NewStaticKeyProvider("new", testKey), current)
+	assert.Equal(t, ErrTampered, err, "wrong key")
+}
+
+func TestTamperingAndTruncation(t *testing.T) {
+	keys := NewStaticKeyProvider("key-1", testKey)
+	content := bytes.Repeat([]byte("0123456789"), 10)
+	data := encrypt(t, keys, 16, content)
+	headerSize := len(magic) + 2 + 4 + 1 + len("key-1") + saltSize + noncePrefixSize
+	chunk := 16 + 16
+
+	for name, test := range map[string]struct {
+		data []byte
+		err  error
+	}{
+		"flipped ciphertext bit": {flip(data, headerSize+chunk+3), ErrTampered},
+		"flipped header bit":     {flip(data, headerSize-1), ErrTampered},
+		"flipped salt bit":       {flip(data, headerSize-noncePrefixSize-1), ErrTampered},
+		"truncated at chunk":     {data[:headerSize+2*chunk], ErrTruncated},
+		"truncated mid chunk":    {data[:headerSize+2*chunk+20], ErrTampered},
+		"truncated tag":          {data[:headerSize+10], ErrTruncated},
+		"last chunk removed":     {data[:len(data)-(len(content)%16+16)], ErrTruncated},
+		"swapped chunks": {append(append(append(append([]byte{}, data[:headerSize]...),
+			data[headerSize+chunk:headerSize+2*chunk]...), data[headerSize:headerSize+chunk]...),
+			data[headerSize+2*chunk:]...), ErrTampered},
+	} {
+		_, err := decrypt(keys, test.data)
+		assert.Equal(t, test.err, err, name)
+	}
+
+	_, err := decrypt(keys, []byte("not encrypted at all"))
+	assert.True(t, errors.Is(err, ErrInvalidHeader))
+	_, err = decrypt(keys, data[:headerSize])
+	assert.Equal(t, ErrTruncated, err)
+
+	v1 := append([]byte{}, data...)
+	v1[len(magic)] = 1
+	_, err = decrypt(keys, v1)
+	assert.True(t, errors.Is(err, ErrInvalidHeader), "streams without a salt aren't supported")
+}
+
+func TestWriterErrors(t *testing.T) {
+	_, err := NewWriter(&bytes.Buffer{}, NewStaticKeyProvider("", testKey))
+	assert.Equal(t, ErrInvalidKeyID, err)
+	_, err = NewWriter(&bytes.Buffer{}, NewStaticKeyProvider("short", []byte("too short")))
+	assert.Error(t, err)
+	_, err = NewWriter(&bytes.Buffer{}, &StaticKeys{CurrentID: "missing"})
+	assert.True(t, errors.Is(err, ErrUnknownKey))
+
+	w, err := NewWriter(&bytes.Buffer{}, NewStaticKeyProvider("key-1", testKey))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Abort())
+	_, err = w.Write([]byte("data"))
+	assert.Equal(t, ErrClosed, err)
+}
+
+func flip(data []byte, i int) []byte {
+	flipped := append([]byte{}, data...)
+	flipped[i] ^= 0x01
+	return flipped
+}
@@ -0,0 +1,21 @@
+package encryption
+
+import "errors"
+
+var (
+	// ErrUnknownKey is returned by a KeyProvider which doesn't have the requested key
+	ErrUnknownKey = errors.New("encryption: unknown key")
+	// ErrInvalidKeyID is returned when a key id is empty or longer than 255 bytes
+	ErrInvalidKeyID = errors.New("encryption: invalid key id")
+	// ErrInvalidHeader is returned when a stream doesn't start with a valid header
+	ErrInvalidHeader = errors.New("encryption: invalid header")
+	// ErrTampered is returned when a chunk fails authentication; the stream or its header has been modified or the
+	// wrong key was used
+	ErrTampered = errors.New("encryption: message authentication failed")
+	// ErrTruncated is returned when a stream ends without its last chunk
+	ErrTruncated = errors.New("encryption: stream is truncated")
+	// ErrStreamTooLarge is returned when a stream would exceed the number of chunks supported by the nonce scheme
+	ErrStreamTooLarge = errors.New("encryption: stream is too large")
+	// ErrClosed is returned when writing to a closed or aborted Writer
+	ErrClosed = errors.New("encryption: writer is closed")
+)
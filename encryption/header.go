@@ -0,0 +1,101 @@
+package encryption
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// DefaultChunkSize is the number of plaintext bytes per chunk
+	DefaultChunkSize = 64 << 10
+	// MaxChunkSize is the largest chunk size accepted; limiting the memory allocated by readers of untrusted streams
+	MaxChunkSize = 16 << 20
+
+	version           = 2
+	nonceSchemeSTREAM = 1 // nonce prefix || big endian chunk counter || last chunk flag
+	noncePrefixSize   = 7
+	saltSize          = 32
+)
+
+var magic = []byte("KVENC")
+
+// header is the plain text start of a stream
+type header struct {
+	chunkSize   int
+	keyID       string
+	salt        [saltSize]byte
+	noncePrefix [noncePrefixSize]byte
+}
+
+func (h header) marshal() []byte {
+	buf := append([]byte{}, magic...)
+	buf = append(buf, version, nonceSchemeSTREAM)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(h.chunkSize))
+	buf = append(buf, byte(len(h.keyID)))
+	buf = append(buf, h.keyID...)
+	buf = append(buf, h.salt[:]...)
+	return append(buf, h.noncePrefix[:]...)
+}
+
+// readHeader reads the header and returns it along with its raw bytes
+func readHeader(r *bufio.Reader) (header, []byte, error) {
+	h := header{}
+	fixed := make([]byte, len(magic)+2+4+1)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return h, nil, fmt.Errorf("%w: %v", ErrInvalidHeader, err)
+	}
+	if string(fixed[:len(magic)]) != string(magic) {
+		return h, nil, fmt.Errorf("%w: bad magic", ErrInvalidHeader)
+	}
+	if fixed[len(magic)] != version || fixed[len(magic)+1] != nonceSchemeSTREAM {
+		return h, nil, fmt.Errorf("%w: unsupported version %d or nonce scheme %d", ErrInvalidHeader, fixed[len(magic)], fixed[len(magic)+1])
+	}
+	h.chunkSize = int(binary.BigEndian.Uint32(fixed[len(magic)+2:]))
+	if h.chunkSize <= 0 || h.chunkSize > MaxChunkSize {
+		return h, nil, fmt.Errorf("%w: chunk size %d", ErrInvalidHeader, h.chunkSize)
+	}
+
+	keyIDSize := int(fixed[len(fixed)-1])
+	rest := make([]byte, keyIDSize+saltSize+noncePrefixSize)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return h, nil, fmt.Errorf("%w: %v", ErrInvalidHeader, err)
+	}
+	h.keyID = string(rest[:keyIDSize])
+	copy(h.salt[:], rest[keyIDSize:])
+	copy(h.noncePrefix[:], rest[keyIDSize+saltSize:])
+	return h, append(fixed, rest...), nil
+}
+
+// nonce returns the nonce of the chunk
+func (h header) nonce(dst []byte, counter uint32, last bool) []byte {
+	dst = append(dst[:0], h.noncePrefix[:]...)
+	dst = binary.BigEndian.AppendUint32(dst, counter)
+	if last {
+		return append(dst, 1)
+	}
+	return append(dst, 0)
+}
+
+// newAEAD returns AES-GCM with a key of the same size as key derived for the stream with HKDF-SHA256 from key and
+// the salt of the header (like Tink's AES-GCM-HKDF streaming AEAD) so nonces never repeat across streams.
+func newAEAD(key []byte, h header) (cipher.AEAD, error) {
+	if _, err := aes.NewCipher(key); err != nil {
+		return nil, err
+	}
+	derived := make([]byte, len(key))
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key, h.salt[:], []byte(h.keyID)), derived); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,38 @@
+package encryption
+
+import "fmt"
+
+// KeyProvider looks up AES keys (16, 24 or 32 bytes for AES-128, AES-192 or AES-256) by id; e.g. backed by a KMS
+type KeyProvider interface {
+	// CurrentKey returns the id and the key new streams are encrypted with
+	CurrentKey() (id string, key []byte, err error)
+	// Key returns the key with the id (as stored in the header of a stream); used for decryption
+	Key(id string) ([]byte, error)
+}
+
+// StaticKeys is an in-memory KeyProvider; new streams are encrypted with the key CurrentID while all keys can be
+// used to decrypt, which allows keys to be rotated
+type StaticKeys struct {
+	CurrentID string
+	Keys      map[string][]byte
+}
+
+// NewStaticKeyProvider returns a KeyProvider with a single key
+func NewStaticKeyProvider(id string, key []byte) *StaticKeys {
+	return &StaticKeys{CurrentID: id, Keys: map[string][]byte{id: key}}
+}
+
+// CurrentKey returns the key CurrentID
+func (s *StaticKeys) CurrentKey() (string, []byte, error) {
+	key, err := s.Key(s.CurrentID)
+	return s.CurrentID, key, err
+}
+
+// Key returns the key with the id or ErrUnknownKey
+func (s *StaticKeys) Key(id string) ([]byte, error) {
+	key, ok := s.Keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, id)
+	}
+	return key, nil
+}
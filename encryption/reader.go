@@ -0,0 +1,116 @@
+package encryption
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"io"
+)
+
+// Reader decrypts a stream written by a Writer; the key is looked up by the id in the header. Reads fail with
+// ErrTampered if a chunk fails authentication and with ErrTruncated if the stream ends without its last chunk.
+// Closing the Reader closes the underlying reader.
+type Reader struct {
+	r                *bufio.Reader
+	underlyingReader io.ReadCloser
+	aead             cipher.AEAD
+	header           header
+	rawHeader        []byte
+
+	buf     []byte
+	plain   []byte
+	pending []byte
+	nonce   []byte
+	counter uint32
+	done    bool
+	err     error
+}
+
+// NewReader reads the header of the r-stream and returns a Reader decrypting it with the key from keys
+func NewReader(r io.ReadCloser, keys KeyProvider) (*Reader, error) {
+	br := bufio.NewReader(r)
+	h, rawHeader, err := readHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	key, err := keys.Key(h.keyID)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key, h)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{
+		r:                br,
+		underlyingReader: r,
+		aead:             aead,
+		header:           h,
+		rawHeader:        rawHeader,
+		buf:              make([]byte, h.chunkSize+aead.Overhead()),
+		plain:            make([]byte, 0, h.chunkSize),
+	}, nil
+}
+
+// KeyID returns the id of the key the stream is encrypted with
+func (er *Reader) KeyID() string {
+	return er.header.keyID
+}
+
+// Read reads decrypted data; data is only returned once its chunk has been authenticated
+func (er *Reader) Read(p []byte) (int, error) {
+	for len(er.pending) == 0 {
+		if er.err != nil {
+			return 0, er.err
+		}
+		if er.done {
+			return 0, io.EOF
+		}
+		er.err = er.open()
+	}
+	n := copy(p, er.pending)
+	er.pending = er.pending[n:]
+	return n, nil
+}
+
+// Close closes the underlying reader
+func (er *Reader) Close() error {
+	return er.underlyingReader.Close()
+}
+
+// open reads, authenticates and decrypts the next chunk
+func (er *Reader) open() error {
+	n, err := io.ReadFull(er.r, er.buf)
+	last := false
+	switch err {
+	case nil:
+		if _, err := er.r.Peek(1); err == io.EOF {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	case io.EOF, io.ErrUnexpectedEOF:
+		last = true
+	default:
+		return err
+	}
+	if n < er.aead.Overhead() {
+		return ErrTruncated
+	}
+
+	er.nonce = er.header.nonce(er.nonce, er.counter, last)
+	plain, err := er.aead.Open(er.plain[:0], er.nonce, er.buf[:n], er.rawHeader)
+	if err != nil {
+		if last {
+			// a chunk which authenticates as an intermediate chunk means the following chunks are missing
+			er.nonce = er.header.nonce(er.nonce, er.counter, false)
+			if _, err := er.aead.Open(er.plain[:0], er.nonce, er.buf[:n], er.rawHeader); err == nil {
+				return ErrTruncated
+			}
+		}
+		return ErrTampered
+	}
+	er.counter++
+	er.pending = plain
+	er.done = last
+	return nil
+}
@@ -0,0 +1,148 @@
+package encryption
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"io"
+	"math"
+
+	"github.com/kvanticoss/goutils/v2/internal/layered"
+)
+
+// Options configures a Writer
+type Options struct {
+	// ChunkSize is the number of plaintext bytes per authenticated chunk; 0 uses DefaultChunkSize
+	ChunkSize int
+}
+
+// Writer encrypts a stream with the current key of a KeyProvider. Plaintext is buffered until a full chunk is
+// available; Close encrypts the last chunk and closes the underlying writer. Flush only flushes the underlying
+// writer since chunks are of fixed size.
+type Writer struct {
+	underlyingWriter io.Writer
+	aead             cipher.AEAD
+	header           header
+	rawHeader        []byte
+
+	buf         []byte
+	out         []byte
+	nonce       []byte
+	counter     uint32
+	wroteHeader bool
+	err         error
+}
+
+// NewWriter returns a Writer with the default options
+func NewWriter(w io.Writer, keys KeyProvider) (*Writer, error) {
+	return NewWriterWithOptions(w, keys, Options{})
+}
+
+// NewWriterWithOptions returns a Writer encrypting with the current key of keys. An error is returned if the key
+// can't be retrieved or is invalid.
+func NewWriterWithOptions(w io.Writer, keys KeyProvider, opts Options) (*Writer, error) {
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = DefaultChunkSize
+	} else if opts.ChunkSize > MaxChunkSize {
+		opts.ChunkSize = MaxChunkSize
+	}
+
+	keyID, key, err := keys.CurrentKey()
+	if err != nil {
+		return nil, err
+	}
+	if len(keyID) == 0 || len(keyID) > math.MaxUint8 {
+		return nil, ErrInvalidKeyID
+	}
+	h := header{chunkSize: opts.ChunkSize, keyID: keyID}
+	if _, err := rand.Read(h.salt[:]); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(h.noncePrefix[:]); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key, h)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{
+		underlyingWriter: w,
+		aead:             aead,
+		header:           h,
+		rawHeader:        h.marshal(),
+		buf:              make([]byte, 0, opts.ChunkSize),
+	}, nil
+}
+
+// Write buffers p and encrypts it once full chunks are available
+func (ew *Writer) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		if ew.err != nil {
+			return written, ew.err
+		}
+		if len(ew.buf) == cap(ew.buf) { // only sealed once more data follows; the last chunk is sealed by Close
+			ew.err = ew.seal(false)
+			continue
+		}
+		n := copy(ew.buf[len(ew.buf):cap(ew.buf)], p)
+		ew.buf = ew.buf[:len(ew.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, ew.err
+}
+
+// Flush flushes the underlying writer; buffered plaintext isn't written until a chunk is full or on Close
+func (ew *Writer) Flush() error {
+	if ew.err != nil {
+		return ew.err
+	}
+	return layered.Flush(ew.underlyingWriter)
+}
+
+// Close encrypts the last chunk and flushes and closes the underlying writer
+func (ew *Writer) Close() error {
+	if ew.err != nil {
+		return ew.err
+	}
+	if err := ew.seal(true); err != nil {
+		ew.err = err
+		return err
+	}
+	ew.err = ErrClosed
+
+	return layered.FlushAndClose(ew.underlyingWriter)
+}
+
+// Abort drops the unsealed chunk and aborts the underlying writer. Underlying writers which can't be aborted are
+// closed without the final chunk, which readers reject with ErrTruncated.
+func (ew *Writer) Abort() error {
+	ew.err = ErrClosed
+	ew.buf = ew.buf[:0]
+	return layered.Abort(ew.underlyingWriter)
+}
+
+// AddRecords reports the end of n records to the underlying writer; the records may still be in the unsealed chunk.
+func (ew *Writer) AddRecords(n int) {
+	layered.AddRecords(ew.underlyingWriter, n)
+}
+
+// seal encrypts the buffered chunk and writes it, preceded by the header for the first chunk
+func (ew *Writer) seal(last bool) error {
+	if !last && ew.counter == math.MaxUint32 {
+		return ErrStreamTooLarge
+	}
+
+	ew.out = ew.out[:0]
+	if !ew.wroteHeader {
+		ew.out = append(ew.out, ew.rawHeader...)
+		ew.wroteHeader = true
+	}
+	ew.nonce = ew.header.nonce(ew.nonce, ew.counter, last)
+	ew.out = ew.aead.Seal(ew.out, ew.nonce, ew.buf, ew.rawHeader)
+	ew.buf = ew.buf[:0]
+	ew.counter++
+
+	_, err := ew.underlyingWriter.Write(ew.out)
+	return err
+}
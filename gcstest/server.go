@@ -0,0 +1,358 @@
+// Package gcstest provides an in-process fake of the GCS JSON/XML APIs, enough for the storage client used by
+// writerfactory/gcswf and readerfactory/gcsrf; object uploads (multipart and resumable), reads, deletes and listings.
+package gcstest
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/option"
+)
+
+// Object is a stored object
+type Object struct {
+	Content     []byte
+	ContentType string
+	Metadata    map[string]string
+	Generation  int64
+	Updated     time.Time
+}
+
+// Server is a fake GCS service keeping objects in memory
+type Server struct {
+	*httptest.Server
+
+	// PageSize limits the number of items and prefixes per listing page; defaults to 1000
+	PageSize int
+	// CorruptUploads flips the last byte of uploads as if corrupted in transit; uploads with a CRC32C are rejected
+	// and others stored corrupted (for checksum tests)
+	CorruptUploads bool
+
+	mutex      sync.Mutex
+	objects    map[string]*Object // by bucket/name
+	sessions   map[string]*session
+	generation int64
+	requests   []string
+}
+
+// session is a resumable upload
+type session struct {
+	bucket   string
+	metadata objectResource
+	query    url.Values
+	content  []byte
+}
+
+// objectResource is the JSON representation of objects
+type objectResource struct {
+	Kind        string            `json:"kind,omitempty"`
+	Name        string            `json:"name"`
+	Bucket      string            `json:"bucket,omitempty"`
+	Generation  string            `json:"generation,omitempty"`
+	Size        string            `json:"size,omitempty"`
+	ContentType string            `json:"contentType,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	CRC32C      string            `json:"crc32c,omitempty"`
+	Updated     string            `json:"updated,omitempty"`
+}
+
+// NewServer starts a server; Close it when done
+func NewServer() *Server {
+	s := &Server{
+		PageSize: 1000,
+		objects:  map[string]*Object{},
+		sessions: map[string]*session{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Client returns a storage client using the server
+func (s *Server) Client(ctx context.Context) (*storage.Client, error) {
+	return storage.NewClient(ctx,
+		option.WithEndpoint(s.URL+"/storage/v1/"),
+		option.WithoutAuthentication(),
+		option.WithHTTPClient(s.Server.Client()),
+	)
+}
+
+// Object returns the object and if it exists
+func (s *Server) Object(bucket, name string) (Object, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	object, ok := s.objects[bucket+"/"+name]
+	if !ok {
+		return Object{}, false
+	}
+	return *object, true
+}
+
+// PutObject stores an object
+func (s *Server) PutObject(bucket, name string, content []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.store(bucket, objectResource{Name: name}, content)
+}
+
+// Requests returns the handled requests as "METHOD path?query"
+func (s *Server) Requests() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string{}, s.requests...)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.RequestURI())
+
+	switch path := r.URL.Path; {
+	case strings.HasPrefix(path, "/upload/storage/v1/b/"):
+		bucket := strings.TrimSuffix(strings.TrimPrefix(path, "/upload/storage/v1/b/"), "/o")
+		s.upload(w, r, bucket)
+	case strings.HasPrefix(path, "/storage/v1/b/"):
+		bucket, name, _ := strings.Cut(strings.TrimPrefix(path, "/storage/v1/b/"), "/o")
+		name = strings.TrimPrefix(name, "/")
+		switch {
+		case r.Method == http.MethodGet && name == "":
+			s.list(w, r, bucket)
+		case r.Method == http.MethodGet:
+			object, ok := s.objects[bucket+"/"+name]
+			if !ok {
+				writeError(w, http.StatusNotFound, "No such object")
+				return
+			}
+			writeJSON(w, resource(bucket, name, object))
+		case r.Method == http.MethodDelete:
+			object, ok := s.objects[bucket+"/"+name]
+			if !ok {
+				writeError(w, http.StatusNotFound, "No such object")
+				return
+			}
+			if gen := r.URL.Query().Get("ifGenerationMatch"); gen != "" && gen != strconv.FormatInt(object.Generation, 10) {
+				writeError(w, http.StatusPreconditionFailed, "Precondition Failed")
+				return
+			}
+			delete(s.objects, bucket+"/"+name)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			writeError(w, http.StatusNotImplemented, r.Method+" "+path)
+		}
+	case r.Method == http.MethodGet: // XML API reads; /{bucket}/{name}
+		object, ok := s.objects[strings.TrimPrefix(path, "/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", object.ContentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(object.Content)))
+		w.Header().Set("X-Goog-Generation", strconv.FormatInt(object.Generation, 10))
+		w.Header().Set("X-Goog-Hash", "crc32c="+encodeCRC32C(object.Content))
+		w.Write(object.Content)
+	default:
+		writeError(w, http.StatusNotImplemented, r.Method+" "+path)
+	}
+}
+
+func (s *Server) upload(w http.ResponseWriter, r *http.Request, bucket string) {
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodPost && query.Get("uploadType") == "multipart":
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		mr := multipart.NewReader(r.Body, params["boundary"])
+		metadata := objectResource{}
+		var content []byte
+		for index := 0; ; index++ {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			data, _ := io.ReadAll(part)
+			if index == 0 {
+				json.Unmarshal(data, &metadata)
+			} else {
+				content = data
+			}
+		}
+		s.finalize(w, bucket, metadata, query, content)
+	case r.Method == http.MethodDelete && query.Get("upload_id") != "":
+		delete(s.sessions, query.Get("upload_id"))
+		w.WriteHeader(499)
+	case query.Get("upload_id") != "": // chunks are PUT or POST
+		session, ok := s.sessions[query.Get("upload_id")]
+		if !ok {
+			writeError(w, http.StatusNotFound, "No such upload")
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		session.content = append(session.content, data...)
+		// Content-Range: bytes {first}-{last}/{total or *}; the total is known with the last chunk
+		if total := r.Header.Get("Content-Range"); strings.HasSuffix(total, "/*") {
+			w.Header().Set("Range", fmt.Sprintf("bytes=0-%d", len(session.content)-1))
+			if r.Header.Get("X-GUploader-No-308") == "yes" {
+				w.Header().Set("X-Http-Status-Code-Override", "308")
+			} else {
+				w.WriteHeader(http.StatusPermanentRedirect)
+			}
+			return
+		}
+		delete(s.sessions, query.Get("upload_id"))
+		s.finalize(w, session.bucket, session.metadata, session.query, session.content)
+	case r.Method == http.MethodPost && query.Get("uploadType") == "resumable":
+		metadata := objectResource{}
+		json.NewDecoder(r.Body).Decode(&metadata)
+		id := strconv.Itoa(len(s.sessions) + 1)
+		s.sessions[id] = &session{bucket: bucket, metadata: metadata, query: query}
+		w.Header().Set("Location", fmt.Sprintf("%s/upload/storage/v1/b/%s/o?uploadType=resumable&upload_id=%s", s.URL, bucket, id))
+	default:
+		writeError(w, http.StatusNotImplemented, r.Method+" "+r.URL.Path)
+	}
+}
+
+// finalize checks the preconditions and checksum and stores the object
+func (s *Server) finalize(w http.ResponseWriter, bucket string, metadata objectResource, query url.Values, content []byte) {
+	if metadata.Name == "" {
+		metadata.Name = query.Get("name")
+	}
+	existing, exists := s.objects[bucket+"/"+metadata.Name]
+	if gen := query.Get("ifGenerationMatch"); gen != "" {
+		if (gen == "0" && exists) || (gen != "0" && (!exists || gen != strconv.FormatInt(existing.Generation, 10))) {
+			writeError(w, http.StatusPreconditionFailed, "At least one of the pre-conditions you specified did not hold.")
+			return
+		}
+	}
+	if s.CorruptUploads && len(content) > 0 {
+		content = append([]byte{}, content...)
+		content[len(content)-1] ^= 0xff
+	}
+	if metadata.CRC32C != "" && metadata.CRC32C != encodeCRC32C(content) {
+		writeError(w, http.StatusBadRequest, "Provided CRC32C doesn't match calculated CRC32C")
+		return
+	}
+	writeJSON(w, resource(bucket, metadata.Name, s.store(bucket, metadata, content)))
+}
+
+func (s *Server) store(bucket string, metadata objectResource, content []byte) *Object {
+	s.generation++
+	object := &Object{
+		Content:     content,
+		ContentType: metadata.ContentType,
+		Metadata:    metadata.Metadata,
+		Generation:  s.generation,
+		Updated:     time.Unix(s.generation, 0).UTC(),
+	}
+	if object.ContentType == "" {
+		object.ContentType = "application/octet-stream"
+	}
+	s.objects[bucket+"/"+metadata.Name] = object
+	return object
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request, bucket string) {
+	query := r.URL.Query()
+	prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+
+	names := []string{}
+	for key := range s.objects {
+		if name, ok := strings.CutPrefix(key, bucket+"/"); ok && strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	// Entries are objects or prefixes; the page token is the index of the first entry of the page
+	type entry struct {
+		name     string
+		isPrefix bool
+	}
+	entries := []entry{}
+	for _, name := range names {
+		if delimiter != "" {
+			if index := strings.Index(name[len(prefix):], delimiter); index >= 0 {
+				common := name[:len(prefix)+index+len(delimiter)]
+				if len(entries) == 0 || entries[len(entries)-1].name != common {
+					entries = append(entries, entry{common, true})
+				}
+				continue
+			}
+		}
+		entries = append(entries, entry{name, false})
+	}
+
+	start, _ := strconv.Atoi(query.Get("pageToken"))
+	result := struct {
+		Kind          string           `json:"kind"`
+		Items         []objectResource `json:"items,omitempty"`
+		Prefixes      []string         `json:"prefixes,omitempty"`
+		NextPageToken string           `json:"nextPageToken,omitempty"`
+	}{Kind: "storage#objects"}
+	end := start + s.PageSize
+	if end < len(entries) {
+		result.NextPageToken = strconv.Itoa(end)
+	} else {
+		end = len(entries)
+	}
+	for _, e := range entries[start:end] {
+		if e.isPrefix {
+			result.Prefixes = append(result.Prefixes, e.name)
+		} else {
+			result.Items = append(result.Items, resource(bucket, e.name, s.objects[bucket+"/"+e.name]))
+		}
+	}
+	writeJSON(w, result)
+}
+
+func resource(bucket, name string, object *Object) objectResource {
+	return objectResource{
+		Kind:        "storage#object",
+		Name:        name,
+		Bucket:      bucket,
+		Generation:  strconv.FormatInt(object.Generation, 10),
+		Size:        strconv.Itoa(len(object.Content)),
+		ContentType: object.ContentType,
+		Metadata:    object.Metadata,
+		CRC32C:      encodeCRC32C(object.Content),
+		Updated:     object.Updated.Format(time.RFC3339Nano),
+	}
+}
+
+func encodeCRC32C(content []byte) string {
+	sum := crc32.Checksum(content, crc32.MakeTable(crc32.Castagnoli))
+	return base64.StdEncoding.EncodeToString(binary.BigEndian.AppendUint32(nil, sum))
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{"code": status, "message": message},
+	})
+}
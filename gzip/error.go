@@ -0,0 +1,10 @@
+package gzip
+
+import "errors"
+
+var (
+	// ErrClosed is returned when writing to a ParallelWriter which has been closed or aborted
+	ErrClosed = errors.New("gzip: writer is closed")
+	// ErrRecordOutOfRange is returned when seeking beyond the last record of an indexed gzip stream
+	ErrRecordOutOfRange = errors.New("gzip: record out of range")
+)
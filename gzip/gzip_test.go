@@ -0,0 +1,113 @@
+package gzip
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type nopFlushCloser struct {
+	*bytes.Buffer
+	closed bool
+}
+
+func (n *nopFlushCloser) Close() error {
+	n.closed = true
+	return nil
+}
+
+func compress(t *testing.T, level int, header Header, content string) []byte {
+	buf := &nopFlushCloser{Buffer: &bytes.Buffer{}}
+	w, err := NewWriterWithHeader(buf, level, header)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	_, err = w.Write([]byte(content))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	assert.True(t, buf.closed, "Close should be cascaded to the underlying writer")
+	return buf.Bytes()
+}
+
+func TestWriterLevelAndHeader(t *testing.T) {
+	content := string(bytes.Repeat([]byte("compress me please "), 1000))
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	fast := compress(t, BestSpeed, Header{Name: "fast.txt", ModTime: modTime, Comment: "a comment"}, content)
+	none := compress(t, NoCompression, Header{}, content)
+	assert.Less(t, len(fast), len(none))
+	assert.Equal(t, byte(255), fast[9], "the OS defaults to unknown, like compress/gzip")
+	assert.Equal(t, byte(3), compress(t, BestSpeed, Header{OS: 3}, content)[9])
+
+	r, err := NewReader(io.NopCloser(bytes.NewReader(fast)))
+	assert.NoError(t, err)
+	assert.Equal(t, "fast.txt", r.Name)
+	assert.Equal(t, "a comment", r.Comment)
+	assert.True(t, modTime.Equal(r.ModTime))
+	got, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, content, string(got))
+
+	_, err = NewWriterLevel(&bytes.Buffer{}, 42)
+	assert.Error(t, err)
+}
+
+func TestMultiMember(t *testing.T) {
+	first := compress(t, DefaultCompression, Header{Name: "first"}, "hello ")
+	second := compress(t, BestCompression, Header{Name: "second"}, "")
+	third := compress(t, BestSpeed, Header{Name: "third"}, "world")
+	stream := append(append(append([]byte{}, first...), second...), third...)
+
+	// Transparently as one stream
+	r, err := NewReader(io.NopCloser(bytes.NewReader(stream)))
+	assert.NoError(t, err)
+	got, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello world", string(got))
+
+	// Member by member
+	mr, err := NewMemberReader(io.NopCloser(bytes.NewReader(stream)))
+	assert.NoError(t, err)
+	got, err = io.ReadAll(mr)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello ", string(got))
+	assert.Equal(t, Member{Header: mr.Member().Header, CompressedSize: int64(len(first)), Size: 6}, mr.Member())
+	assert.Equal(t, "first", mr.Member().Name)
+
+	assert.NoError(t, mr.Next())
+	assert.NoError(t, mr.Next(), "unread members are skipped")
+	assert.Equal(t, "third", mr.Member().Name)
+	got, err = io.ReadAll(mr)
+	assert.NoError(t, err)
+	assert.Equal(t, "world", string(got))
+	assert.Equal(t, io.EOF, mr.Next())
+	assert.NoError(t, mr.Close())
+
+	// Boundaries
+	members, err := Members(bytes.NewReader(stream))
+	assert.NoError(t, err)
+	if assert.Len(t, members, 3) {
+		offset := int64(0)
+		for i, member := range [][]byte{first, second, third} {
+			assert.Equal(t, i, members[i].Index)
+			assert.Equal(t, offset, members[i].Offset)
+			assert.Equal(t, int64(len(member)), members[i].CompressedSize)
+			offset += int64(len(member))
+		}
+		assert.Equal(t, []int64{6, 0, 5}, []int64{members[0].Size, members[1].Size, members[2].Size})
+	}
+
+	// A member can be decompressed on its own from its offset
+	single, err := NewReader(io.NopCloser(bytes.NewReader(stream[members[2].Offset:])))
+	assert.NoError(t, err)
+	got, err = io.ReadAll(single)
+	assert.NoError(t, err)
+	assert.Equal(t, "world", string(got))
+
+	members, err = Members(bytes.NewReader(nil))
+	assert.NoError(t, err)
+	assert.Empty(t, members)
+}
@@ -0,0 +1,70 @@
+package gzip
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+)
+
+// IndexExtension is appended to the path of an indexed gzip file to get the path of its index
+const IndexExtension = ".idx"
+
+// Index maps records and uncompressed offsets of an indexed gzip stream to the members they are stored in;
+// written as JSON by an IndexedWriter
+type Index struct {
+	Members []IndexEntry `json:"members"`
+	// Records is the total number of records
+	Records int64 `json:"records"`
+	// Size is the total number of uncompressed bytes
+	Size int64 `json:"size"`
+	// CompressedSize is the total number of compressed bytes
+	CompressedSize int64 `json:"compressed_size"`
+}
+
+// IndexEntry describes where an independently decompressible member starts
+type IndexEntry struct {
+	// Record is the number (starting at 0) of the first record in the member
+	Record int64 `json:"record"`
+	// Offset is the uncompressed offset of the member
+	Offset int64 `json:"offset"`
+	// CompressedOffset is the offset of the member in the compressed stream
+	CompressedOffset int64 `json:"compressed_offset"`
+}
+
+// ReadIndex decodes an Index written by an IndexedWriter
+func ReadIndex(r io.Reader) (*Index, error) {
+	index := &Index{}
+	if err := json.NewDecoder(r).Decode(index); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// WriteTo writes the index as JSON
+func (idx *Index) WriteTo(w io.Writer) (int64, error) {
+	b, err := json.Marshal(idx)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(b)
+	return int64(n), err
+}
+
+// memberOfRecord returns the last member starting at or before the record
+func (idx *Index) memberOfRecord(record int64) IndexEntry {
+	i := sort.Search(len(idx.Members), func(i int) bool { return idx.Members[i].Record > record })
+	return idx.Members[maxInt(i-1, 0)]
+}
+
+// memberOfOffset returns the last member starting at or before the uncompressed offset
+func (idx *Index) memberOfOffset(offset int64) IndexEntry {
+	i := sort.Search(len(idx.Members), func(i int) bool { return idx.Members[i].Offset > offset })
+	return idx.Members[maxInt(i-1, 0)]
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,134 @@
+package gzip
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+)
+
+// IndexedReader reads an indexed gzip stream written by an IndexedWriter. It implements io.ReadSeeker over the
+// uncompressed data and SeekRecord positions it at the start of a new line delimited record (e.g. NDJSON); only the
+// member containing the target is decompressed up to the target.
+type IndexedReader struct {
+	r     io.ReadSeeker
+	index *Index
+
+	gz     *gzip.Reader
+	dec    *bufio.Reader
+	offset int64 // uncompressed offset of the next byte returned by Read
+	valid  bool  // dec is positioned at offset
+}
+
+// NewIndexedReader returns an IndexedReader reading the gzip stream r described by index. Close closes r if it
+// is an io.Closer.
+func NewIndexedReader(r io.ReadSeeker, index *Index) *IndexedReader {
+	return &IndexedReader{r: r, index: index}
+}
+
+// Read reads uncompressed data from the current offset
+func (ir *IndexedReader) Read(p []byte) (int, error) {
+	if ir.offset >= ir.index.Size {
+		return 0, io.EOF
+	}
+	if !ir.valid {
+		if err := ir.position(ir.index.memberOfOffset(ir.offset), ir.offset); err != nil {
+			return 0, err
+		}
+	}
+	n, err := ir.dec.Read(p)
+	ir.offset += int64(n)
+	return n, err
+}
+
+// Seek sets the uncompressed offset of the next Read; the stream is repositioned lazily on the next Read
+func (ir *IndexedReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += ir.offset
+	case io.SeekEnd:
+		offset += ir.index.Size
+	}
+	if offset < 0 {
+		return 0, errors.New("gzip: negative position")
+	}
+	if offset != ir.offset {
+		ir.offset = offset
+		ir.valid = false
+	}
+	return offset, nil
+}
+
+// SeekRecord positions the reader at the start of the record (starting at 0) where records are delimited by new
+// lines. Seeking to the number of records positions the reader at the end; beyond that ErrRecordOutOfRange is
+// returned.
+func (ir *IndexedReader) SeekRecord(record int64) error {
+	if record < 0 || record > ir.index.Records {
+		return ErrRecordOutOfRange
+	}
+	if record == ir.index.Records {
+		ir.offset, ir.valid = ir.index.Size, false
+		return nil
+	}
+
+	member := ir.index.memberOfRecord(record)
+	if err := ir.position(member, member.Offset); err != nil {
+		return err
+	}
+	for skip := record - member.Record; skip > 0; {
+		line, err := ir.dec.ReadSlice('\n')
+		ir.offset += int64(len(line))
+		if err == bufio.ErrBufferFull {
+			continue
+		} else if err == io.EOF {
+			return ErrRecordOutOfRange
+		} else if err != nil {
+			return err
+		}
+		skip--
+	}
+	return nil
+}
+
+// Close closes the gzip reader as well as the underlying reader if it is an io.Closer
+func (ir *IndexedReader) Close() error {
+	if ir.gz != nil {
+		if err := ir.gz.Close(); err != nil {
+			return err
+		}
+	}
+	if closer, ok := ir.r.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// position starts decompressing at the member and discards data up to the uncompressed offset
+func (ir *IndexedReader) position(member IndexEntry, offset int64) error {
+	ir.valid = false
+	if _, err := ir.r.Seek(member.CompressedOffset, io.SeekStart); err != nil {
+		return err
+	}
+
+	var err error
+	if ir.gz == nil {
+		ir.gz, err = gzip.NewReader(bufio.NewReader(ir.r))
+	} else {
+		err = ir.gz.Reset(bufio.NewReader(ir.r))
+	}
+	if err != nil {
+		return err
+	}
+	if ir.dec == nil {
+		ir.dec = bufio.NewReader(ir.gz)
+	} else {
+		ir.dec.Reset(ir.gz)
+	}
+
+	if _, err := ir.dec.Discard(int(offset - member.Offset)); err != nil {
+		return err
+	}
+	ir.offset = offset
+	ir.valid = true
+	return nil
+}
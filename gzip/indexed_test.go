@@ -0,0 +1,125 @@
+package gzip
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeIndexed(t *testing.T, records int, opts IndexOptions) ([]byte, *Index, []byte) {
+	data, index := &nopFlushCloser{Buffer: &bytes.Buffer{}}, &nopFlushCloser{Buffer: &bytes.Buffer{}}
+	w, err := NewIndexedWriter(data, index, opts)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	var content bytes.Buffer
+	for i := 0; i < records; i++ {
+		line := fmt.Sprintf(`{"id":%d,"padding":"%s"}`+"\n", i, bytes.Repeat([]byte("x"), i%50))
+		content.WriteString(line)
+		_, err := w.Write([]byte(line))
+		assert.NoError(t, err)
+		w.AddRecords(1)
+	}
+	assert.NoError(t, w.Close())
+	assert.True(t, data.closed)
+	assert.True(t, index.closed)
+
+	idx, err := ReadIndex(index)
+	assert.NoError(t, err)
+	return data.Bytes(), idx, content.Bytes()
+}
+
+func TestIndexedWriter(t *testing.T) {
+	data, index, content := writeIndexed(t, 1000, IndexOptions{MemberSize: 4096})
+
+	assert.Equal(t, int64(1000), index.Records)
+	assert.Equal(t, int64(len(content)), index.Size)
+	assert.Equal(t, int64(len(data)), index.CompressedSize)
+	assert.Greater(t, len(index.Members), 5)
+	assert.Equal(t, byte(255), data[9], "the OS defaults to unknown, like compress/gzip")
+
+	// Readable as a regular gzip file
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	assert.NoError(t, err)
+	got, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, content, got)
+
+	// Every member is independently decompressible and starts at a record
+	for _, member := range index.Members {
+		r, err := gzip.NewReader(bytes.NewReader(data[member.CompressedOffset:]))
+		assert.NoError(t, err)
+		line, err := bufio.NewReader(r).ReadString('\n')
+		assert.NoError(t, err)
+		assert.Contains(t, line, fmt.Sprintf(`{"id":%d,`, member.Record))
+		assert.Equal(t, content[member.Offset:member.Offset+int64(len(line))], []byte(line))
+	}
+}
+
+func TestIndexedWriterNoCompression(t *testing.T) {
+	data, index, content := writeIndexed(t, 100, IndexOptions{Level: OptionsNoCompression})
+	assert.Greater(t, len(data), len(content), "members should be stored")
+	assert.Equal(t, int64(len(content)), index.Size)
+
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	assert.NoError(t, err)
+	got, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, content, got)
+}
+
+func TestIndexedReader(t *testing.T) {
+	data, index, content := writeIndexed(t, 1000, IndexOptions{MemberSize: 4096, Level: BestSpeed})
+	r := NewIndexedReader(bytes.NewReader(data), index)
+
+	for _, record := range []int64{0, 1, 517, 999, 3, 999} {
+		assert.NoError(t, r.SeekRecord(record))
+		line, err := bufio.NewReader(r).ReadString('\n')
+		assert.NoError(t, err)
+		assert.Contains(t, line, fmt.Sprintf(`{"id":%d,`, record))
+	}
+
+	assert.NoError(t, r.SeekRecord(1000))
+	_, err := r.Read(make([]byte, 1))
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, ErrRecordOutOfRange, r.SeekRecord(1001))
+
+	// byte ranges
+	for _, offset := range []int64{0, 5000, int64(len(content)) - 10} {
+		pos, err := r.Seek(offset, io.SeekStart)
+		assert.NoError(t, err)
+		assert.Equal(t, offset, pos)
+		got := make([]byte, 10)
+		_, err = io.ReadFull(r, got)
+		assert.NoError(t, err)
+		assert.Equal(t, content[offset:offset+10], got)
+	}
+	pos, err := r.Seek(-20, io.SeekEnd)
+	assert.NoError(t, err)
+	got, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, content[pos:], got)
+	assert.NoError(t, r.Close())
+}
+
+func TestIndexedWriterEmpty(t *testing.T) {
+	data, index, _ := writeIndexed(t, 0, IndexOptions{})
+	assert.Len(t, index.Members, 1)
+
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	assert.NoError(t, err)
+	got, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+
+	ir := NewIndexedReader(bytes.NewReader(data), index)
+	assert.NoError(t, ir.SeekRecord(0))
+	_, err = ir.Read(make([]byte, 1))
+	assert.Equal(t, io.EOF, err)
+}
@@ -0,0 +1,169 @@
+package gzip
+
+import (
+	"compress/gzip"
+	"io"
+
+	"github.com/kvanticoss/goutils/v2/internal/layered"
+)
+
+// DefaultMemberSize is the number of uncompressed bytes after which an IndexedWriter starts a new member
+const DefaultMemberSize = 1 << 20
+
+// IndexOptions configures an IndexedWriter
+type IndexOptions struct {
+	// Level is the compression level; 0 uses DefaultCompression and OptionsNoCompression selects NoCompression
+	Level int
+	// MemberSize is the number of uncompressed bytes after which a new member is started at the next record
+	// boundary; 0 uses DefaultMemberSize
+	MemberSize int
+	// Header is written as the header of every member; an OS of 0 is written as 255 (unknown)
+	Header Header
+}
+
+// IndexedWriter writes a multi-member gzip stream where members are independently decompressible and start at
+// record boundaries; the end of each record is reported through AddRecords (as done by recordwriter). An Index of
+// the members is written to the index writer on Close which lets an IndexedReader seek to a record or an uncompressed
+// offset without decompressing the stream from the start. The stream is readable by any gzip reader.
+type IndexedWriter struct {
+	underlyingWriter io.Writer
+	indexWriter      io.Writer
+	counter          *countingWriter
+	gz               *gzip.Writer
+	opts             IndexOptions
+
+	index       Index
+	memberOpen  bool
+	memberStart int64
+	err         error
+}
+
+// NewIndexedWriter returns an IndexedWriter writing the gzip stream to w and the index to index. Close and Flush
+// calls are cascaded to both. An error is returned if the level is invalid.
+func NewIndexedWriter(w io.Writer, index io.Writer, opts IndexOptions) (*IndexedWriter, error) {
+	opts.Level = optionsLevel(opts.Level)
+	opts.Header = withDefaultOS(opts.Header)
+	if opts.MemberSize <= 0 {
+		opts.MemberSize = DefaultMemberSize
+	}
+	counter := &countingWriter{w: w}
+	gzWriter, err := gzip.NewWriterLevel(counter, opts.Level)
+	if err != nil {
+		return nil, err
+	}
+	return &IndexedWriter{
+		underlyingWriter: w,
+		indexWriter:      index,
+		counter:          counter,
+		gz:               gzWriter,
+		opts:             opts,
+		index:            Index{Members: []IndexEntry{}},
+	}, nil
+}
+
+// Write writes data to the current member, starting one if needed
+func (iw *IndexedWriter) Write(p []byte) (int, error) {
+	if iw.err != nil {
+		return 0, iw.err
+	}
+	if !iw.memberOpen {
+		iw.startMember()
+	}
+	n, err := iw.gz.Write(p)
+	iw.index.Size += int64(n)
+	if err != nil {
+		iw.err = err
+	}
+	return n, err
+}
+
+// AddRecords marks the end of n records; a new member is started if the current member has reached MemberSize.
+// The count is forwarded to the underlying writer if it keeps track of records.
+func (iw *IndexedWriter) AddRecords(n int) {
+	iw.index.Records += int64(n)
+	if iw.memberOpen && iw.index.Size-iw.memberStart >= int64(iw.opts.MemberSize) && iw.err == nil {
+		iw.err = iw.gz.Close()
+		iw.memberOpen = false
+	}
+	layered.AddRecords(iw.underlyingWriter, n)
+}
+
+// Flush flushes the current member AND the underlying writer
+func (iw *IndexedWriter) Flush() error {
+	if iw.err != nil {
+		return iw.err
+	}
+	if iw.memberOpen {
+		if err := iw.gz.Flush(); err != nil {
+			return err
+		}
+	}
+	return layered.Flush(iw.underlyingWriter)
+}
+
+// Close ends the current member, closes the underlying writer and then writes and closes the index; an index
+// therefore only exists for complete streams.
+func (iw *IndexedWriter) Close() error {
+	if iw.err != nil {
+		return iw.err
+	}
+	if !iw.memberOpen && len(iw.index.Members) == 0 {
+		iw.startMember() // an empty member keeps empty streams valid gzip
+	}
+	if iw.memberOpen {
+		if err := iw.gz.Close(); err != nil {
+			return err
+		}
+		iw.memberOpen = false
+	}
+	iw.index.CompressedSize = iw.counter.n
+	iw.err = ErrClosed
+
+	if err := layered.FlushAndClose(iw.underlyingWriter); err != nil {
+		return err
+	}
+	if _, err := iw.index.WriteTo(iw.indexWriter); err != nil {
+		return err
+	}
+	return layered.FlushAndClose(iw.indexWriter)
+}
+
+// Abort discards the stream and the index; aborting the underlying writers if they support it, otherwise they are
+// closed.
+func (iw *IndexedWriter) Abort() error {
+	iw.err = ErrClosed
+	err := layered.Abort(iw.underlyingWriter)
+	if err2 := layered.Abort(iw.indexWriter); err == nil {
+		err = err2
+	}
+	return err
+}
+
+// Index returns the index of the members written so far
+func (iw *IndexedWriter) Index() Index {
+	return iw.index
+}
+
+func (iw *IndexedWriter) startMember() {
+	iw.gz.Reset(iw.counter)
+	iw.gz.Header = iw.opts.Header
+	iw.memberOpen = true
+	iw.memberStart = iw.index.Size
+	iw.index.Members = append(iw.index.Members, IndexEntry{
+		Record:           iw.index.Records,
+		Offset:           iw.index.Size,
+		CompressedOffset: iw.counter.n,
+	})
+}
+
+// countingWriter keeps track of the number of bytes written
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,136 @@
+package gzip
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+)
+
+// Member describes one member of a (multi-member) gzip stream, e.g. one of several concatenated gzip files
+type Member struct {
+	Header
+	// Index is the position of the member in the stream, starting at 0
+	Index int
+	// Offset is the position of the first byte of the member in the compressed stream
+	Offset int64
+	// CompressedSize is the number of bytes of the member in the compressed stream, header and trailer included.
+	// Only set once the member has been read to the end.
+	CompressedSize int64
+	// Size is the number of decompressed bytes read from the member so far
+	Size int64
+}
+
+// MemberReader reads a multi-member gzip stream one member at the time; Read returns io.EOF at the end of each
+// member and Next advances to the following member. Use Reader to read all members as one stream.
+type MemberReader struct {
+	gz               *gzip.Reader
+	counter          *countingReader
+	underlyingReader io.ReadCloser
+	member           Member
+	memberDone       bool
+}
+
+// NewMemberReader treats the r-stream as a multi-member gzip stream and returns a MemberReader positioned at the
+// first member. io.EOF is returned if r is empty.
+func NewMemberReader(r io.ReadCloser) (*MemberReader, error) {
+	counter := &countingReader{r: bufio.NewReader(r)}
+	gzReader, err := gzip.NewReader(counter)
+	if err != nil {
+		return nil, err
+	}
+	gzReader.Multistream(false)
+	return &MemberReader{
+		gz:               gzReader,
+		counter:          counter,
+		underlyingReader: r,
+		member:           Member{Header: gzReader.Header},
+	}, nil
+}
+
+// Member returns the description of the current member
+func (mr *MemberReader) Member() Member {
+	return mr.member
+}
+
+// Read reads decompressed data of the current member; io.EOF is returned at the end of the member
+func (mr *MemberReader) Read(p []byte) (int, error) {
+	n, err := mr.gz.Read(p)
+	mr.member.Size += int64(n)
+	if err == io.EOF && !mr.memberDone {
+		mr.memberDone = true
+		mr.member.CompressedSize = mr.counter.n - mr.member.Offset
+	}
+	return n, err
+}
+
+// Next skips the remainder of the current member and advances to the next; io.EOF is returned if there are no
+// more members
+func (mr *MemberReader) Next() error {
+	if !mr.memberDone {
+		if _, err := io.Copy(io.Discard, mr); err != nil {
+			return err
+		}
+	}
+
+	offset := mr.counter.n
+	if err := mr.gz.Reset(mr.counter); err != nil {
+		return err
+	}
+	mr.gz.Multistream(false)
+	mr.member = Member{Header: mr.gz.Header, Index: mr.member.Index + 1, Offset: offset}
+	mr.memberDone = false
+	return nil
+}
+
+// Close closes the gzip reader as well as the underlying reader
+func (mr *MemberReader) Close() error {
+	if err := mr.gz.Close(); err != nil {
+		return err
+	}
+	return mr.underlyingReader.Close()
+}
+
+// Members reads the whole gzip stream and returns the boundaries of its members; e.g. to split the decompression
+// of a large multi-member file across workers which each read one member starting at its Offset.
+func Members(r io.Reader) ([]Member, error) {
+	mr, err := NewMemberReader(io.NopCloser(r))
+	if err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	members := []Member{}
+	for {
+		if _, err := io.Copy(io.Discard, mr); err != nil {
+			return members, err
+		}
+		members = append(members, mr.Member())
+		if err := mr.Next(); err == io.EOF {
+			return members, nil
+		} else if err != nil {
+			return members, err
+		}
+	}
+}
+
+// countingReader keeps track of the number of bytes consumed. It implements io.ByteReader so that the gzip
+// reader doesn't add buffering of its own, which would make the count run ahead of the member boundaries.
+type countingReader struct {
+	r *bufio.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
@@ -0,0 +1,323 @@
+package gzip
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/kvanticoss/goutils/v2/internal/layered"
+)
+
+const (
+	// DefaultBlockSize is the number of uncompressed bytes compressed as one unit by a ParallelWriter
+	DefaultBlockSize = 1 << 20
+
+	// dictSize is the deflate window; each block is primed with the last dictSize bytes of the previous block
+	dictSize = 32 << 10
+)
+
+// OptionsNoCompression is the Level of ParallelOptions and IndexOptions selecting NoCompression, as a zero Level
+// selects DefaultCompression
+const OptionsNoCompression = -3
+
+// finalBlock is an empty, final, fixed huffman deflate block which terminates the deflate stream
+var finalBlock = []byte{0x03, 0x00}
+
+// ParallelOptions configures a ParallelWriter
+type ParallelOptions struct {
+	// Level is the compression level; 0 uses DefaultCompression and OptionsNoCompression selects NoCompression
+	Level int
+	// BlockSize is the number of uncompressed bytes compressed per goroutine; 0 uses DefaultBlockSize
+	BlockSize int
+	// Concurrency is the max number of blocks compressed in parallel; 0 uses GOMAXPROCS
+	Concurrency int
+	// Header is written as the gzip header; an OS of 0 is written as 255 (unknown)
+	Header Header
+}
+
+// ParallelWriter is a gzip writer which compresses fixed size blocks on multiple goroutines (like pigz). Each block
+// is primed with the tail of the previous block so the compression ratio is close to that of a Writer. The output is
+// a single, standard gzip member readable by any gzip reader. Close and Flush are cascaded to the underlying writer.
+type ParallelWriter struct {
+	underlyingWriter io.Writer
+	opts             ParallelOptions
+
+	buf  []byte
+	dict []byte
+	crc  uint32
+	size uint32
+
+	started  bool
+	closed   bool
+	sem      chan struct{}
+	pending  chan *block
+	finished chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+type block struct {
+	out     []byte
+	err     error
+	done    chan struct{}
+	written chan struct{} // closed once out has been written; only set on Flush
+}
+
+// NewParallelWriter returns a ParallelWriter with the options; an error is returned if the level is invalid
+func NewParallelWriter(w io.Writer, opts ParallelOptions) (*ParallelWriter, error) {
+	opts.Level = optionsLevel(opts.Level)
+	opts.Header = withDefaultOS(opts.Header)
+	if _, err := flate.NewWriter(io.Discard, opts.Level); err != nil {
+		return nil, err
+	}
+	if opts.BlockSize <= 0 {
+		opts.BlockSize = DefaultBlockSize
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = runtime.GOMAXPROCS(0)
+	}
+	return &ParallelWriter{
+		underlyingWriter: w,
+		opts:             opts,
+		buf:              make([]byte, 0, opts.BlockSize),
+	}, nil
+}
+
+// Write buffers p and compresses it in the background once a full block is available. Errors from the background
+// compression or from the underlying writer are returned by subsequent calls.
+func (pw *ParallelWriter) Write(p []byte) (int, error) {
+	if err := pw.getErr(); err != nil {
+		return 0, err
+	}
+	if pw.closed {
+		return 0, ErrClosed
+	}
+
+	written := 0
+	for len(p) > 0 {
+		n := copy(pw.buf[len(pw.buf):cap(pw.buf)], p)
+		pw.buf = pw.buf[:len(pw.buf)+n]
+		p = p[n:]
+		written += n
+		if len(pw.buf) == cap(pw.buf) {
+			pw.dispatch(false, nil)
+		}
+	}
+	return written, pw.getErr()
+}
+
+// Flush compresses any buffered data, waits for all blocks to be written and flushes the underlying writer.
+// Data written prior to Flush can be decompressed from the output.
+func (pw *ParallelWriter) Flush() error {
+	if pw.closed {
+		return ErrClosed
+	}
+	written := make(chan struct{})
+	pw.dispatch(false, written)
+	<-written
+
+	if err := pw.getErr(); err != nil {
+		return err
+	}
+	return layered.Flush(pw.underlyingWriter)
+}
+
+// Close compresses any buffered data, writes the gzip trailer and closes the underlying writer
+func (pw *ParallelWriter) Close() error {
+	if pw.closed {
+		return pw.getErr()
+	}
+	pw.dispatch(true, nil)
+	pw.closed = true
+	close(pw.pending)
+	<-pw.finished
+
+	if err := pw.getErr(); err != nil {
+		return err
+	}
+	return layered.FlushAndClose(pw.underlyingWriter)
+}
+
+// Abort stops the background compression, dropping blocks which haven't been written yet, and aborts the
+// underlying writer (or closes it if it can't be aborted).
+func (pw *ParallelWriter) Abort() error {
+	pw.setErr(ErrClosed)
+	if !pw.closed {
+		pw.closed = true
+		if pw.started {
+			close(pw.pending)
+			<-pw.finished
+		}
+	}
+
+	return layered.Abort(pw.underlyingWriter)
+}
+
+// AddRecords reports the end of n records to the underlying writer; the records may still be in the block being
+// filled or compressed.
+func (pw *ParallelWriter) AddRecords(n int) {
+	layered.AddRecords(pw.underlyingWriter, n)
+}
+
+// dispatch queues the buffered data for compression; blocking while Concurrency blocks are being compressed.
+// written, if set, is closed once the block has been written to the underlying writer.
+func (pw *ParallelWriter) dispatch(lastBlock bool, written chan struct{}) {
+	pw.start()
+
+	data, dict := pw.buf, pw.dict
+	pw.buf = make([]byte, 0, pw.opts.BlockSize)
+	pw.crc = crc32.Update(pw.crc, crc32.IEEETable, data)
+	pw.size += uint32(len(data))
+	if len(data) >= dictSize {
+		pw.dict = data[len(data)-dictSize:]
+	} else if len(data) > 0 {
+		pw.dict = append(append([]byte{}, dict...), data...)
+		if len(pw.dict) > dictSize {
+			pw.dict = pw.dict[len(pw.dict)-dictSize:]
+		}
+	}
+
+	b := &block{done: make(chan struct{}), written: written}
+	pw.sem <- struct{}{}
+	pw.pending <- b
+	go func(crc, size uint32) {
+		defer func() { <-pw.sem }()
+		defer close(b.done)
+		b.out, b.err = compressBlock(data, dict, pw.opts.Level)
+		if lastBlock && b.err == nil {
+			b.out = append(b.out, finalBlock...)
+			b.out = binary.LittleEndian.AppendUint32(b.out, crc)
+			b.out = binary.LittleEndian.AppendUint32(b.out, size)
+		}
+	}(pw.crc, pw.size)
+}
+
+// start writes the header and starts the goroutine writing the compressed blocks in order
+func (pw *ParallelWriter) start() {
+	if pw.started {
+		return
+	}
+	pw.started = true
+	pw.sem = make(chan struct{}, pw.opts.Concurrency)
+	pw.pending = make(chan *block, pw.opts.Concurrency)
+	pw.finished = make(chan struct{})
+
+	header := &block{out: appendHeader(nil, pw.opts.Header, pw.opts.Level), done: make(chan struct{})}
+	close(header.done)
+	pw.pending <- header
+
+	go func() {
+		defer close(pw.finished)
+		for b := range pw.pending {
+			<-b.done
+			if b.err != nil {
+				pw.setErr(b.err)
+			} else if pw.getErr() == nil {
+				if _, err := pw.underlyingWriter.Write(b.out); err != nil {
+					pw.setErr(err)
+				}
+			}
+			if b.written != nil {
+				close(b.written)
+			}
+		}
+	}()
+}
+
+func (pw *ParallelWriter) getErr() error {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	return pw.err
+}
+
+func (pw *ParallelWriter) setErr(err error) {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	if pw.err == nil {
+		pw.err = err
+	}
+}
+
+// compressBlock deflates data primed with dict and ends with a sync flush so blocks can be concatenated
+func compressBlock(data, dict []byte, level int) ([]byte, error) {
+	var out bytes.Buffer
+	fw, err := flate.NewWriterDict(&out, level, dict)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := fw.Flush(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// optionsLevel maps the Level of ParallelOptions and IndexOptions to a compress/flate level
+func optionsLevel(level int) int {
+	switch level {
+	case 0:
+		return DefaultCompression
+	case OptionsNoCompression:
+		return NoCompression
+	}
+	return level
+}
+
+// appendHeader appends the gzip member header (RFC 1952)
+func appendHeader(buf []byte, h Header, level int) []byte {
+	var flags byte
+	if h.Extra != nil {
+		flags |= 0x04
+	}
+	if h.Name != "" {
+		flags |= 0x08
+	}
+	if h.Comment != "" {
+		flags |= 0x10
+	}
+	var mtime uint32
+	if h.ModTime.After(time.Unix(0, 0)) {
+		mtime = uint32(h.ModTime.Unix())
+	}
+	var xfl byte
+	switch level {
+	case BestCompression:
+		xfl = 2
+	case BestSpeed:
+		xfl = 4
+	}
+
+	buf = append(buf, 0x1f, 0x8b, 8, flags)
+	buf = binary.LittleEndian.AppendUint32(buf, mtime)
+	buf = append(buf, xfl, h.OS)
+	if h.Extra != nil {
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(len(h.Extra)))
+		buf = append(buf, h.Extra...)
+	}
+	if h.Name != "" {
+		buf = appendLatin1(buf, h.Name)
+	}
+	if h.Comment != "" {
+		buf = appendLatin1(buf, h.Comment)
+	}
+	return buf
+}
+
+// appendLatin1 appends the zero terminated ISO 8859-1 string; characters outside of Latin-1 are replaced by '?'
+func appendLatin1(buf []byte, s string) []byte {
+	for _, r := range s {
+		if r == 0 || r > 0xff {
+			r = '?'
+		}
+		buf = append(buf, byte(r))
+	}
+	return append(buf, 0)
+}
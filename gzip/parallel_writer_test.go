@@ -0,0 +1,130 @@
+package gzip
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ndjson(lines int) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < lines; i++ {
+		fmt.Fprintf(&buf, `{"id":%d,"name":"record %d","value":%d}`+"\n", i, i*7%13, i*i%1009)
+	}
+	return buf.Bytes()
+}
+
+func TestParallelWriterRoundTrip(t *testing.T) {
+	content := ndjson(50000)
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	for _, opts := range []ParallelOptions{
+		{},
+		{Level: BestSpeed, BlockSize: 16 << 10, Concurrency: 4},
+		{Level: BestCompression, BlockSize: 64 << 10, Concurrency: 2, Header: Header{Name: "data.ndjson", ModTime: modTime}},
+	} {
+		buf := &nopFlushCloser{Buffer: &bytes.Buffer{}}
+		w, err := NewParallelWriter(buf, opts)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		// uneven writes crossing block boundaries
+		for rest := content; len(rest) > 0; {
+			n := len(rest)
+			if n > 777 {
+				n = 777
+			}
+			_, err := w.Write(rest[:n])
+			assert.NoError(t, err)
+			rest = rest[n:]
+		}
+		assert.NoError(t, w.Close())
+		assert.True(t, buf.closed, "Close should be cascaded to the underlying writer")
+
+		// must be readable by the standard library as a single member
+		r, err := gzip.NewReader(bytes.NewReader(buf.Bytes()))
+		if !assert.NoError(t, err) {
+			continue
+		}
+		r.Multistream(false)
+		got, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		assert.Equal(t, content, got)
+		assert.Equal(t, opts.Header.Name, r.Name)
+		assert.True(t, opts.Header.ModTime.Equal(r.ModTime) || opts.Header.ModTime.IsZero())
+		assert.Equal(t, byte(255), buf.Bytes()[9], "the OS defaults to unknown, like compress/gzip")
+		assert.Less(t, buf.Len(), len(content)/4, "the dictionary of the previous block should keep the ratio high")
+	}
+}
+
+func TestParallelWriterNoCompression(t *testing.T) {
+	content := ndjson(1000)
+	buf := &nopFlushCloser{Buffer: &bytes.Buffer{}}
+	w, err := NewParallelWriter(buf, ParallelOptions{Level: OptionsNoCompression, BlockSize: 1 << 10})
+	assert.NoError(t, err)
+	w.Write(content)
+	assert.NoError(t, w.Close())
+
+	assert.Greater(t, buf.Len(), len(content), "blocks should be stored")
+	r, err := gzip.NewReader(bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	got, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, content, got)
+}
+
+func TestParallelWriterFlush(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w, err := NewParallelWriter(buf, ParallelOptions{BlockSize: 10})
+	assert.NoError(t, err)
+
+	_, err = w.Write([]byte("hello parallel world"))
+	assert.NoError(t, err)
+	_, err = w.Write([]byte("!"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Flush())
+
+	// Everything written prior to Flush is available even though the stream isn't terminated
+	r, err := gzip.NewReader(bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	got := make([]byte, 21)
+	_, err = io.ReadFull(r, got)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello parallel world!", string(got))
+
+	assert.NoError(t, w.Close())
+	_, err = w.Write([]byte("more"))
+	assert.Equal(t, ErrClosed, err)
+}
+
+func TestParallelWriterEmptyAndErrors(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w, err := NewParallelWriter(buf, ParallelOptions{})
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	r, err := gzip.NewReader(bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	got, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+
+	_, err = NewParallelWriter(buf, ParallelOptions{Level: 42})
+	assert.Error(t, err)
+
+	w, err = NewParallelWriter(failingWriter{}, ParallelOptions{BlockSize: 4})
+	assert.NoError(t, err)
+	w.Write([]byte("some data"))
+	assert.Equal(t, io.ErrShortWrite, w.Close())
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, io.ErrShortWrite
+}
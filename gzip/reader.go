@@ -11,7 +11,8 @@ type Reader struct {
 	underlyingReader io.ReadCloser
 }
 
-// NewReader treats the r-stream as a gzip stream and returns a Reader
+// NewReader treats the r-stream as a gzip stream and returns a Reader. Concatenated multi-member gzip streams (e.g.
+// appended shards) are read transparently as one stream; see MemberReader to read them member by member.
 func NewReader(r io.ReadCloser) (*Reader, error) {
 	gzReader, err := gzip.NewReader(r)
 	if err != nil {
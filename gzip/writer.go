@@ -3,8 +3,33 @@ package gzip
 import (
 	"compress/gzip"
 	"io"
+
+	"github.com/kvanticoss/goutils/v2/internal/layered"
+)
+
+// Compression levels; see compress/gzip
+const (
+	NoCompression      = gzip.NoCompression
+	BestSpeed          = gzip.BestSpeed
+	BestCompression    = gzip.BestCompression
+	DefaultCompression = gzip.DefaultCompression
+	HuffmanOnly        = gzip.HuffmanOnly
 )
 
+// Header is the gzip member header (name, comment, mtime etc); see compress/gzip
+type Header = gzip.Header
+
+// unknownOS is the OS byte of headers which don't set it, as written by compress/gzip
+const unknownOS = 255
+
+// withDefaultOS returns h with the OS set to unknown if it is zero
+func withDefaultOS(h Header) Header {
+	if h.OS == 0 {
+		h.OS = unknownOS
+	}
+	return h
+}
+
 // Writer creates a gzip file which closes the underlying stream as well as the gzip stream on close
 type Writer struct {
 	*gzip.Writer
@@ -20,6 +45,26 @@ func NewWriter(w io.Writer) *Writer {
 	}
 }
 
+// NewWriterLevel is like NewWriter but with the compression level instead of DefaultCompression. An error is returned
+// if the level is invalid.
+func NewWriterLevel(w io.Writer, level int) (*Writer, error) {
+	return NewWriterWithHeader(w, level, Header{})
+}
+
+// NewWriterWithHeader is like NewWriterLevel but where the name, comment, mtime etc of the gzip header are set. An
+// OS of 0 is written as 255 (unknown), like compress/gzip does by default.
+func NewWriterWithHeader(w io.Writer, level int, header Header) (*Writer, error) {
+	gzWriter, err := gzip.NewWriterLevel(w, level)
+	if err != nil {
+		return nil, err
+	}
+	gzWriter.Header = withDefaultOS(header)
+	return &Writer{
+		Writer:           gzWriter,
+		underlyingWriter: w,
+	}, nil
+}
+
 // Write writes data to the gzip stream
 func (gz *Writer) Write(p []byte) (int, error) {
 	return gz.Writer.Write(p)
@@ -31,12 +76,7 @@ func (gz *Writer) Flush() error {
 		return err
 	}
 
-	if flusher, ok := gz.underlyingWriter.(flusher); ok {
-		if err := flusher.Flush(); err != nil {
-			return err
-		}
-	}
-	return nil
+	return layered.Flush(gz.underlyingWriter)
 }
 
 // Close flushes and closes the gzip writer AND the underlying writer
@@ -49,19 +89,17 @@ func (gz *Writer) Close() error {
 		return err
 	}
 
-	if closer, ok := gz.underlyingWriter.(closer); ok {
-		if err := closer.Close(); err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return layered.Close(gz.underlyingWriter)
 }
 
-type flusher interface {
-	Flush() error
+// Abort stops compressing without writing the gzip trailer and aborts the underlying writer (e.g. removing the temp
+// file of an atomic file); underlying writers which can't be aborted are closed with the truncated stream.
+func (gz *Writer) Abort() error {
+	return layered.Abort(gz.underlyingWriter)
 }
 
-type closer interface {
-	Close() error
+// AddRecords reports the end of n records to the underlying writer (e.g. for manifests); the compressed bytes of the
+// records may still be buffered by the compressor.
+func (gz *Writer) AddRecords(n int) {
+	layered.AddRecords(gz.underlyingWriter, n)
 }
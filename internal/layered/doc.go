@@ -0,0 +1,3 @@
+// Package layered holds the optional interfaces of writers which are layered on top of another writer (compression,
+// encryption, atomic files, retries...) and helpers forwarding calls to the writer below when it supports them.
+package layered
@@ -0,0 +1,73 @@
+package layered
+
+import "io"
+
+// Flusher is implemented by writers buffering data
+type Flusher interface {
+	Flush() error
+}
+
+// Aborter is implemented by writers which can be discarded without making their content visible
+type Aborter interface {
+	Abort() error
+}
+
+// RecordCounter is implemented by writers which keep track of the number of records written to them (e.g. for
+// manifests); record writers report each encoded record.
+type RecordCounter interface {
+	AddRecords(n int)
+}
+
+// Rotator is implemented by writers splitting their stream across several files; writers wrapping another writer
+// forward it so the splitting can be detected through them
+type Rotator interface {
+	Rotates() bool
+}
+
+// Flush flushes w if it buffers data
+func Flush(w io.Writer) error {
+	if flusher, ok := w.(Flusher); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+// Close closes w if it is an io.Closer
+func Close(w io.Writer) error {
+	if closer, ok := w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// FlushAndClose flushes and then closes w if it supports it
+func FlushAndClose(w io.Writer) error {
+	if err := Flush(w); err != nil {
+		return err
+	}
+	return Close(w)
+}
+
+// Abort discards w if it is an Aborter; otherwise it is closed. Aborting a nil writer is a no-op.
+func Abort(w io.Writer) error {
+	if w == nil {
+		return nil
+	}
+	if aborter, ok := w.(Aborter); ok {
+		return aborter.Abort()
+	}
+	return Close(w)
+}
+
+// AddRecords reports n records to w if it keeps track of them
+func AddRecords(w io.Writer, n int) {
+	if counter, ok := w.(RecordCounter); ok {
+		counter.AddRecords(n)
+	}
+}
+
+// Rotates tells if w splits its stream across several files
+func Rotates(w io.Writer) bool {
+	rotator, ok := w.(Rotator)
+	return ok && rotator.Rotates()
+}
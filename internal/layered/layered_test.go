@@ -0,0 +1,43 @@
+package layered
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingWriter struct {
+	bytes.Buffer
+	calls   []string
+	records int
+}
+
+func (w *recordingWriter) Flush() error     { w.calls = append(w.calls, "flush"); return nil }
+func (w *recordingWriter) Close() error     { w.calls = append(w.calls, "close"); return nil }
+func (w *recordingWriter) AddRecords(n int) { w.records += n }
+
+type abortingWriter struct {
+	recordingWriter
+}
+
+func (w *abortingWriter) Abort() error { w.calls = append(w.calls, "abort"); return nil }
+
+func TestForwarding(t *testing.T) {
+	w := &recordingWriter{}
+	assert.NoError(t, FlushAndClose(w))
+	AddRecords(w, 2)
+	assert.NoError(t, Abort(w), "writers which can't be aborted are closed")
+	assert.Equal(t, []string{"flush", "close", "close"}, w.calls)
+	assert.Equal(t, 2, w.records)
+
+	a := &abortingWriter{}
+	assert.NoError(t, Abort(a))
+	assert.Equal(t, []string{"abort"}, a.calls)
+
+	var plain bytes.Buffer
+	assert.NoError(t, FlushAndClose(&plain))
+	assert.NoError(t, Abort(&plain))
+	AddRecords(&plain, 1)
+	assert.NoError(t, Abort(nil))
+}
@@ -0,0 +1,69 @@
+package recordwriter
+
+import (
+	"io"
+
+	"github.com/kvanticoss/goutils/v2/codec"
+	"github.com/kvanticoss/goutils/v2/internal/layered"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// encoderCache keeps one Encoder per path so that stateful codecs (csv headers, gob type definitions etc)
+// are only initialized once per path. Writer factories are free to return a new writer for each call
+// (the Encoder always writes to the latest writer returned for the path) but note that if the factory
+// starts a new file for the same path (e.g. writercache.Cache after closing an idle writer) the new file
+// will not get any headers; prefer stateless codecs such as codec.JSON in those cases.
+// Encoders buffering records (e.g. CSV) are flushed after each record since the factory may close the writer
+// at any time; the CSV header is thereby derived from the first record of each path.
+type encoderCache struct {
+	codec    codec.Codec
+	encoders map[string]*cachedEncoder
+}
+
+type cachedEncoder struct {
+	io.Writer
+	encoder codec.Encoder
+}
+
+func newEncoderCache(c codec.Codec) *encoderCache {
+	return &encoderCache{
+		codec:    c,
+		encoders: map[string]*cachedEncoder{},
+	}
+}
+
+func (ec *encoderCache) get(path string, w io.Writer) codec.Encoder {
+	cached, ok := ec.encoders[path]
+	if !ok {
+		cached = &cachedEncoder{}
+		cached.encoder = ec.codec.NewEncoder(cached)
+		ec.encoders[path] = cached
+	}
+	cached.Writer = w
+	return cached.encoder
+}
+
+// encode writes the record to the encoder of the path and flushes any buffered data to w
+func (ec *encoderCache) encode(path string, w io.Writer, record interface{}) error {
+	enc := ec.get(path, w)
+	if err := enc.Encode(record); err != nil {
+		return err
+	}
+	if flusher, ok := enc.(layered.Flusher); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+// Close closes all the encoders (flushing any buffered data); the writers are not closed.
+func (ec *encoderCache) Close() error {
+	var allErrors *multierror.Error
+	for path, cached := range ec.encoders {
+		if err := cached.encoder.Close(); err != nil {
+			allErrors = multierror.Append(allErrors, err)
+		}
+		delete(ec.encoders, path)
+	}
+	return allErrors.ErrorOrNil()
+}
@@ -4,11 +4,10 @@ import (
 	"fmt"
 	"path"
 
+	"github.com/kvanticoss/goutils/v2/codec"
 	"github.com/kvanticoss/goutils/v2/internal/iterator"
 	"github.com/kvanticoss/goutils/v2/keyvaluelist"
 	"github.com/kvanticoss/goutils/v2/writerfactory"
-
-	jsoniter "github.com/json-iterator/go"
 )
 
 // NewLineJSONClustered extracts possible partitions from the records yielded by the sorted iterator
@@ -19,34 +18,56 @@ func NewLineJSONClustered(
 	it iterator.LesserIteratorClustered,
 	wf writerfactory.WriterFactory,
 	pathBuilder func(record interface{}, partition int) string,
+) error {
+	if pathBuilder == nil {
+		pathBuilder = DefaultClusteredPathbuilder
+	}
+	return Clustered(it, wf, pathBuilder, codec.JSON)
+}
+
+// Clustered works like NewLineJSONClustered but encodes the records with the codec. The default
+// path builder uses the extension of the codec.
+func Clustered(
+	it iterator.LesserIteratorClustered,
+	wf writerfactory.WriterFactory,
+	pathBuilder func(record interface{}, partition int) string,
+	c codec.Codec,
 ) error {
 	var cluster int
 	var record iterator.Lesser
 	var err error
 
 	if pathBuilder == nil {
-		pathBuilder = DefaultClusteredPathbuilder
+		pathBuilder = ClusteredPathbuilderWithExtension(c.Extension())
 	}
 
+	encoders := newEncoderCache(c)
 	for cluster, record, err = it(); err == nil; cluster, record, err = it() {
-		d, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(record)
-		if err != nil {
-			return err
-		}
-
 		path := pathBuilder(record, cluster)
 		writer, err := wf(path)
 		if err != nil {
 			return err
 		}
-		if _, err := writer.Write(append(d, jsonRecordDelimiter...)); err != nil {
+		if err := encoders.encode(path, writer, record); err != nil {
 			return err
 		}
 	}
+
+	if err2 := encoders.Close(); err2 != nil {
+		return err2
+	}
 	return err
 }
 
-// DefaultClusteredPathbuilder builds a path from the GetPartitions + an incremntal partition id.
+// DefaultClusteredPathbuilder builds a path from the GetPartitions + an incremntal partition id; files keep the .json
+// extension regardless of codec.JSON (.ndjson) which is only used by Clustered without a path builder
 func DefaultClusteredPathbuilder(record interface{}, partition int) string {
-	return path.Join(keyvaluelist.MaybePartitions(record), fmt.Sprintf("sorted_records_p%04d_s{suffix}.json", partition))
+	return ClusteredPathbuilderWithExtension(".json")(record, partition)
+}
+
+// ClusteredPathbuilderWithExtension returns a path builder like DefaultClusteredPathbuilder but with the given file extension
+func ClusteredPathbuilderWithExtension(extension string) func(record interface{}, partition int) string {
+	return func(record interface{}, partition int) string {
+		return path.Join(keyvaluelist.MaybePartitions(record), fmt.Sprintf("sorted_records_p%04d_s{suffix}%s", partition, extension))
+	}
 }
@@ -4,11 +4,10 @@ import (
 	"fmt"
 	"path"
 
+	"github.com/kvanticoss/goutils/v2/codec"
 	"github.com/kvanticoss/goutils/v2/internal/iterator"
 	"github.com/kvanticoss/goutils/v2/keyvaluelist"
 	"github.com/kvanticoss/goutils/v2/writerfactory"
-
-	jsoniter "github.com/json-iterator/go"
 )
 
 // NewLineJSONPartitioned extracts possible partitions from the records yielded by the iterator
@@ -17,33 +16,56 @@ func NewLineJSONPartitioned(
 	it iterator.RecordIterator,
 	wf writerfactory.WriterFactory,
 	pathBuilder func(record interface{}) string,
+) error {
+	if pathBuilder == nil {
+		pathBuilder = DefaultPathbuilder
+	}
+	return Partitioned(it, wf, pathBuilder, codec.JSON)
+}
+
+// Partitioned extracts possible partitions from the records yielded by the iterator and writes them
+// encoded with the codec to the cache (and underlying writer) under the key
+// {record.GetPartitions().ToPartitionKey()}/unsorted_records_s{suffix}{codec.Extension()}
+func Partitioned(
+	it iterator.RecordIterator,
+	wf writerfactory.WriterFactory,
+	pathBuilder func(record interface{}) string,
+	c codec.Codec,
 ) error {
 	var record interface{}
 	var err error
 
 	if pathBuilder == nil {
-		pathBuilder = DefaultPathbuilder
+		pathBuilder = PathbuilderWithExtension(c.Extension())
 	}
 
+	encoders := newEncoderCache(c)
 	for record, err = it(); err == nil; record, err = it() {
-		d, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(record)
-		if err != nil {
-			return err
-		}
-
 		path := pathBuilder(record)
 		writer, err := wf(path)
 		if err != nil {
 			return err
 		}
-		if _, err := writer.Write(append(d, jsonRecordDelimiter...)); err != nil {
+		if err := encoders.encode(path, writer, record); err != nil {
 			return err
 		}
 	}
+
+	if err2 := encoders.Close(); err2 != nil {
+		return err2
+	}
 	return err
 }
 
-// DefaultPathbuilder builds a path from the GetPartitions
+// DefaultPathbuilder builds a path from the GetPartitions; files keep the .json extension regardless of
+// codec.JSON (.ndjson) which is only used by Partitioned without a path builder
 func DefaultPathbuilder(record interface{}) string {
-	return path.Join(keyvaluelist.MaybePartitions(record), fmt.Sprintf("unsorted_records_s{suffix}.json"))
+	return PathbuilderWithExtension(".json")(record)
+}
+
+// PathbuilderWithExtension returns a path builder like DefaultPathbuilder but with the given file extension
+func PathbuilderWithExtension(extension string) func(record interface{}) string {
+	return func(record interface{}) string {
+		return path.Join(keyvaluelist.MaybePartitions(record), fmt.Sprintf("unsorted_records_s{suffix}%s", extension))
+	}
 }
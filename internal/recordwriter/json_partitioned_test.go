@@ -0,0 +1,115 @@
+package recordwriter_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/kvanticoss/goutils/v2/codec"
+	"github.com/kvanticoss/goutils/v2/internal/iterator"
+	"github.com/kvanticoss/goutils/v2/internal/iterator/test_utils"
+	"github.com/kvanticoss/goutils/v2/internal/recordwriter"
+	"github.com/kvanticoss/goutils/v2/keyvaluelist"
+	"github.com/kvanticoss/goutils/v2/writerfactory"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func partitionedRecords() iterator.RecordIterator {
+	records := []*test_utils.SortableStruct{
+		{Val: 1, Partitions: keyvaluelist.KeyValues{{Key: "p", Value: "a"}}},
+		{Val: 2, Partitions: keyvaluelist.KeyValues{{Key: "p", Value: "b"}}},
+		{Val: 3, Partitions: keyvaluelist.KeyValues{{Key: "p", Value: "a"}}},
+	}
+	return func() (interface{}, error) {
+		if len(records) == 0 {
+			return nil, iterator.ErrIteratorStop
+		}
+		r := records[0]
+		records = records[1:]
+		return r, nil
+	}
+}
+
+func TestPartitionedWithCodec(t *testing.T) {
+	db, wf := writerfactory.GetMemoryWriterFactory()
+	it := partitionedRecords()
+
+	assert.Equal(t, iterator.ErrIteratorStop, recordwriter.Partitioned(it, wf, nil, codec.CSV))
+
+	assert.Len(t, db, 2)
+	assert.Equal(t, "Val,Partitions\n"+
+		`1,"[{""Key"":""p"",""Value"":""a""}]"`+"\n"+
+		`3,"[{""Key"":""p"",""Value"":""a""}]"`+"\n",
+		db["p=a/unsorted_records_s{suffix}.csv"].String(), "header should only be written once per file")
+	assert.Equal(t, "Val,Partitions\n"+
+		`2,"[{""Key"":""p"",""Value"":""b""}]"`+"\n",
+		db["p=b/unsorted_records_s{suffix}.csv"].String())
+}
+
+// evictingWriter rejects writes once closed, like the writers of writercache.Cache
+type evictingWriter struct {
+	buf    *bytes.Buffer
+	closed bool
+}
+
+func (w *evictingWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	return w.buf.Write(p)
+}
+
+func (w *evictingWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func TestPartitionedWithEvictedWriters(t *testing.T) {
+	// Keeps a single writer open; opening a new path closes the previous writer and reopening it starts a new file
+	db := map[string]*bytes.Buffer{}
+	var open *evictingWriter
+	var openPath string
+	wf := func(path string) (io.WriteCloser, error) {
+		if open != nil && openPath == path {
+			return open, nil
+		}
+		if open != nil {
+			open.Close()
+		}
+		file := fmt.Sprintf("%s#%d", path, len(db))
+		db[file] = &bytes.Buffer{}
+		open, openPath = &evictingWriter{buf: db[file]}, path
+		return open, nil
+	}
+
+	assert.Equal(t, iterator.ErrIteratorStop, recordwriter.Partitioned(partitionedRecords(), wf, nil, codec.CSV))
+	assert.Len(t, db, 3)
+	assert.Equal(t, "Val,Partitions\n"+
+		`1,"[{""Key"":""p"",""Value"":""a""}]"`+"\n",
+		db["p=a/unsorted_records_s{suffix}.csv#0"].String(), "rows must be written before the writer is closed")
+	assert.Equal(t, "Val,Partitions\n"+
+		`2,"[{""Key"":""p"",""Value"":""b""}]"`+"\n",
+		db["p=b/unsorted_records_s{suffix}.csv#1"].String())
+	assert.Equal(t, `3,"[{""Key"":""p"",""Value"":""a""}]"`+"\n",
+		db["p=a/unsorted_records_s{suffix}.csv#2"].String())
+}
+
+func TestNewLineJSONPartitionedExtension(t *testing.T) {
+	jsonDB, jsonWF := writerfactory.GetMemoryWriterFactory()
+	codecDB, codecWF := writerfactory.GetMemoryWriterFactory()
+
+	assert.Equal(t, iterator.ErrIteratorStop, recordwriter.NewLineJSONPartitioned(partitionedRecords(), jsonWF, nil))
+	assert.Equal(t, iterator.ErrIteratorStop, recordwriter.Partitioned(partitionedRecords(), codecWF, nil, codec.JSON))
+
+	assert.Contains(t, jsonDB, "p=a/unsorted_records_s{suffix}.json", "the default path builder keeps the .json extension")
+	assert.Contains(t, codecDB, "p=a/unsorted_records_s{suffix}.ndjson")
+	for path, buf := range codecDB {
+		jsonPath := strings.TrimSuffix(path, ".ndjson") + ".json"
+		assert.Equal(t, buf.String(), jsonDB[jsonPath].String(), "both entry points should write the same content")
+	}
+	assert.Equal(t, len(codecDB), len(jsonDB))
+}
@@ -0,0 +1,30 @@
+package iterator
+
+import (
+	"io"
+
+	"github.com/kvanticoss/goutils/v2/codec"
+)
+
+// CodecRecordIterator returns a RecordIterator based on a stream of records encoded with the codec.
+// Once the stream is exhausted the reader is closed (if it is an io.Closer) and ErrIteratorStop is returned.
+// @new - creator to allocate a new struct for each record; used to allow concurrent use of records yielded
+// @r - byte stream reader containing the encoded data
+// @c - the codec used to decode the stream; e.g. codec.JSON
+func CodecRecordIterator[T any](new func() T, r io.Reader, c codec.Codec) RecordIterator[T] {
+	dec := c.NewDecoder(r)
+	return func() (T, error) {
+		dst := new()
+		if err := dec.Decode(dst); err != nil {
+			if err == io.EOF {
+				if closer, ok := r.(io.Closer); ok {
+					closer.Close()
+				}
+				var empty T
+				return empty, ErrIteratorStop
+			}
+			return dst, err
+		}
+		return dst, nil
+	}
+}
@@ -0,0 +1,114 @@
+package iterator_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/kvanticoss/goutils/v2/codec"
+	"github.com/kvanticoss/goutils/v2/eioutil"
+	"github.com/kvanticoss/goutils/v2/gzip"
+	"github.com/kvanticoss/goutils/v2/iterator"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodecRecordIterator(t *testing.T) {
+	for _, c := range []codec.Codec{codec.JSON, codec.CSV, codec.Gob} {
+		t.Run(c.Name(), func(t *testing.T) {
+			var buf bytes.Buffer
+			enc := c.NewEncoder(&buf)
+			for i := 1; i <= 3; i++ {
+				assert.NoError(t, enc.Encode(SortableStruct{i}))
+			}
+			assert.NoError(t, enc.Close())
+
+			closed := false
+			r := eioutil.NewReadCloser(&buf, func() error {
+				closed = true
+				return nil
+			})
+
+			it := iterator.CodecRecordIterator(func() *SortableStruct { return &SortableStruct{} }, r, c)
+			expected := 1
+			var rec *SortableStruct
+			var err error
+			for rec, err = it(); err == nil; rec, err = it() {
+				assert.Equal(t, expected, rec.Val)
+				expected++
+			}
+			assert.Equal(t, iterator.ErrIteratorStop, err)
+			assert.Equal(t, 4, expected)
+			assert.True(t, closed, "reader should be closed once exhausted")
+		})
+	}
+}
+
+func TestJSONRecordIterator(t *testing.T) {
+	r := bytes.NewBufferString("{\"Val\":1}\n{\"Val\":2}\n\n")
+	it := iterator.JSONRecordIterator(func() *SortableStruct { return &SortableStruct{} }, io.Reader(r))
+
+	rec, err := it()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, rec.Val)
+	rec, err = it()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, rec.Val)
+	_, err = it()
+	assert.Equal(t, iterator.ErrIteratorStop, err)
+}
+
+func TestJSONRecordIteratorMalformedRecord(t *testing.T) {
+	r := bytes.NewBufferString("{\"Val\":1}\n{\"Val\":tru}\n{\"Val\":3}\n")
+	it := iterator.JSONRecordIterator(func() *SortableStruct { return &SortableStruct{} }, io.Reader(r))
+
+	_, err := it()
+	assert.NoError(t, err)
+	_, err = it()
+	assert.Error(t, err)
+	assert.NotEqual(t, iterator.ErrIteratorStop, err)
+	_, err = it()
+	assert.NotEqual(t, iterator.ErrIteratorStop, err, "records after a malformed one aren't silently dropped")
+}
+
+func TestJSONRecordIteratorDecompresses(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte("{\"Val\":1}\n{\"Val\":2}\n"))
+	assert.NoError(t, gz.Close())
+
+	closed := false
+	r := eioutil.NewReadCloser(&buf, func() error {
+		closed = true
+		return nil
+	})
+	it := iterator.JSONRecordIterator(func() *SortableStruct { return &SortableStruct{} }, r)
+
+	rec, err := it()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, rec.Val)
+	rec, err = it()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, rec.Val)
+	_, err = it()
+	assert.Equal(t, iterator.ErrIteratorStop, err)
+	assert.True(t, closed, "reader should be closed once exhausted")
+
+	_, err = iterator.JSONRecordIterator(func() *SortableStruct { return &SortableStruct{} }, bytes.NewReader([]byte{0x1f, 0x8b}))()
+	assert.Error(t, err)
+}
+
+func TestJSONRecordIteratorDetectsLazily(t *testing.T) {
+	r, w := io.Pipe()
+	it := iterator.JSONRecordIterator(func() *SortableStruct { return &SortableStruct{} }, r) // must not block on r
+
+	go func() {
+		w.Write([]byte("{\"Val\":1}\n"))
+		w.Close()
+	}()
+	rec, err := it()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, rec.Val)
+	_, err = it()
+	assert.Equal(t, iterator.ErrIteratorStop, err)
+}
@@ -1,24 +1,34 @@
 package iterator
 
 import (
-	"encoding/json"
 	"io"
+
+	"github.com/kvanticoss/goutils/v2/codec"
+	"github.com/kvanticoss/goutils/v2/eioutil"
 )
 
-// JSONRecordIterator returns a RecordIterator based on a JSON stream of data, NewLine delimited
+// JSONRecordIterator returns a RecordIterator based on a JSON stream of data, NewLine delimited. Gzip, zstd and
+// framed snappy compressed streams are detected by their magic bytes and decompressed transparently; the detection
+// reads from r on the first call of the iterator, not when it is created.
 // @new - creator to allocate a new struct for each record; used to allow concurrent use of records yielded
 // @r - byte stream reader containing new line delimited json data
 func JSONRecordIterator[T any](new func() T, r io.Reader) RecordIterator[T] {
-	dec := json.NewDecoder(r)
+	rc, ok := r.(io.ReadCloser)
+	if !ok {
+		rc = io.NopCloser(r)
+	}
+	var it RecordIterator[T]
 	return func() (T, error) {
-		dst := new()
-		if !dec.More() {
-			if closer, ok := r.(io.Closer); ok {
-				closer.Close()
+		if it == nil {
+			if decompressed, err := eioutil.NewDecompressingReader(rc); err != nil {
+				it = func() (T, error) {
+					var empty T
+					return empty, err
+				}
+			} else {
+				it = CodecRecordIterator(new, decompressed, codec.JSON)
 			}
-			var empty T
-			return empty, ErrIteratorStop
 		}
-		return dst, dec.Decode(dst)
+		return it()
 	}
 }
@@ -0,0 +1,18 @@
+package iterator
+
+import (
+	"io"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/kvanticoss/goutils/v2/codec"
+)
+
+// ProtoRecordIterator returns a RecordIterator based on a stream of varint length-delimited protobuf messages.
+// Gzipped streams can be read by wrapping r with gzip.NewReader.
+// @new - creator to allocate a new message for each record; e.g. func() *pb.Event { return &pb.Event{} }
+// @r - byte stream reader containing the length-delimited messages
+// @maxMessageSize - messages larger than this yield codec.ErrMessageTooLarge; <= 0 means codec.DefaultMaxProtoMessageSize
+func ProtoRecordIterator[T proto.Message](new func() T, r io.Reader, maxMessageSize int) RecordIterator[T] {
+	return CodecRecordIterator(new, r, codec.NewProto(maxMessageSize))
+}
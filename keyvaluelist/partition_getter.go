@@ -16,3 +16,9 @@ func MaybePartitions(record interface{}) string {
 	}
 	return ""
 }
+
+// PartitionSetter is implemented by records that want to receive the partitions they were read from
+// (e.g. by the dataset reader).
+type PartitionSetter interface {
+	SetPartitions(KeyValues)
+}
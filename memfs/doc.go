@@ -0,0 +1,5 @@
+// Package memfs is a thread-safe in-memory filesystem exposing the WriterFactory, ReaderFactory, Lister, DirLister
+// and Remover abstractions of this module; e.g. to test code writing from multiple goroutines. Open writers are
+// tracked, the behaviour when opening an existing path is configurable and Snapshot/Diff make it easy to assert
+// what a piece of code wrote.
+package memfs
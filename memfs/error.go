@@ -0,0 +1,14 @@
+package memfs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+var (
+	// ErrFileExists is returned when opening an existing path for writing with the FailIfExists mode
+	ErrFileExists = fmt.Errorf("memfs: %w", fs.ErrExist)
+	// ErrClosed is returned when writing to or closing a closed writer
+	ErrClosed = errors.New("memfs: writer is closed")
+)
@@ -0,0 +1,197 @@
+package memfs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kvanticoss/goutils/v2/commit"
+	"github.com/kvanticoss/goutils/v2/iterator"
+	"github.com/kvanticoss/goutils/v2/readerfactory"
+	"github.com/kvanticoss/goutils/v2/writerfactory"
+)
+
+// Mode decides what happens when a path that already exists is opened for writing
+type Mode int
+
+const (
+	// Truncate discards the previous content; like os.Create
+	Truncate Mode = iota
+	// Append keeps the previous content and appends to it
+	Append
+	// FailIfExists returns ErrFileExists
+	FailIfExists
+)
+
+// FS is a thread-safe in-memory filesystem. Files are created when opened for writing and written data is visible
+// to readers immediately; as with local files.
+type FS struct {
+	mu    sync.RWMutex
+	mode  Mode
+	files map[string]*file
+	now   func() time.Time
+}
+
+type file struct {
+	data    []byte
+	modTime time.Time
+	writers int
+}
+
+// New returns an empty FS where paths are opened for writing with the mode
+func New(mode Mode) *FS {
+	return &FS{mode: mode, files: map[string]*file{}, now: time.Now}
+}
+
+// WriterFactory returns a WriterFactory creating files in the FS
+func (m *FS) WriterFactory() writerfactory.WriterFactory {
+	return func(path string) (io.WriteCloser, error) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		f, ok := m.files[path]
+		switch {
+		case !ok:
+			f = &file{}
+			m.files[path] = f
+		case m.mode == FailIfExists:
+			return nil, fmt.Errorf("%w: %s", ErrFileExists, path)
+		case m.mode == Truncate:
+			f.data = nil
+		}
+		f.modTime = m.now()
+		f.writers++
+		return &writer{fs: m, file: f}, nil
+	}
+}
+
+// ReaderFactory returns a ReaderFactory reading files of the FS; readers see the content at the time of opening.
+// Missing paths yield errors wrapping fs.ErrNotExist.
+func (m *FS) ReaderFactory() readerfactory.ReaderFactory {
+	return func(path string) (io.ReadCloser, error) {
+		data, err := m.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+}
+
+// Lister returns a Lister of the files in the FS
+func (m *FS) Lister() readerfactory.Lister {
+	return func(prefix string) iterator.RecordIterator[readerfactory.ObjectInfo] {
+		return readerfactory.ListObjects(m.objects(), prefix)
+	}
+}
+
+// DirLister returns a DirLister of the files in the FS treating "/" as directory separator
+func (m *FS) DirLister() readerfactory.DirLister {
+	return func(dir string) iterator.RecordIterator[readerfactory.ObjectInfo] {
+		return readerfactory.ListDir(m.objects(), dir)
+	}
+}
+
+// Remover returns a commit.Remover deleting files of the FS
+func (m *FS) Remover() commit.Remover {
+	return m.Remove
+}
+
+// Remove deletes the file at path; removing a missing path is not an error. Writers of the file keep writing to
+// the removed file.
+func (m *FS) Remove(path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.files, path)
+	return nil
+}
+
+// ReadFile returns a copy of the content of the file at path
+func (m *FS) ReadFile(path string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	f, ok := m.files[path]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", fs.ErrNotExist, path)
+	}
+	return append([]byte{}, f.data...), nil
+}
+
+// WriteFile sets the content of the file at path, regardless of the Mode
+func (m *FS) WriteFile(path string, data []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.files[path]
+	if !ok {
+		f = &file{}
+		m.files[path] = f
+	}
+	f.data = append([]byte{}, data...)
+	f.modTime = m.now()
+}
+
+// IsOpen returns true if the file at path has writers which haven't been closed
+func (m *FS) IsOpen(path string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	f, ok := m.files[path]
+	return ok && f.writers > 0
+}
+
+// OpenFiles returns the sorted paths of the files with writers which haven't been closed; e.g. to assert that
+// code closes all its writers
+func (m *FS) OpenFiles() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	paths := []string{}
+	for path, f := range m.files {
+		if f.writers > 0 {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func (m *FS) objects() []readerfactory.ObjectInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	objects := make([]readerfactory.ObjectInfo, 0, len(m.files))
+	for path, f := range m.files {
+		objects = append(objects, readerfactory.ObjectInfo{Path: path, Size: int64(len(f.data)), ModTime: f.modTime})
+	}
+	return objects
+}
+
+type writer struct {
+	fs     *FS
+	file   *file
+	closed bool
+}
+
+// Write appends p to the file
+func (w *writer) Write(p []byte) (int, error) {
+	w.fs.mu.Lock()
+	defer w.fs.mu.Unlock()
+	if w.closed {
+		return 0, ErrClosed
+	}
+	w.file.data = append(w.file.data, p...)
+	w.file.modTime = w.fs.now()
+	return len(p), nil
+}
+
+// Close marks the writer as closed; closing twice returns ErrClosed
+func (w *writer) Close() error {
+	w.fs.mu.Lock()
+	defer w.fs.mu.Unlock()
+	if w.closed {
+		return ErrClosed
+	}
+	w.closed = true
+	w.file.writers--
+	return nil
+}
@@ -0,0 +1,158 @@
+package memfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kvanticoss/goutils/v2/iterator"
+	"github.com/kvanticoss/goutils/v2/readerfactory"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func write(t *testing.T, m *FS, path, content string) {
+	w, err := m.WriterFactory()(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = w.Write([]byte(content))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+}
+
+func listPaths(t *testing.T, it iterator.RecordIterator[readerfactory.ObjectInfo]) []string {
+	paths := []string{}
+	for info, err := it(); err != iterator.ErrIteratorStop; info, err = it() {
+		if !assert.NoError(t, err) {
+			break
+		}
+		paths = append(paths, info.Path)
+	}
+	return paths
+}
+
+func TestModes(t *testing.T) {
+	truncating := New(Truncate)
+	write(t, truncating, "a", "first")
+	write(t, truncating, "a", "second")
+	assert.Equal(t, map[string]string{"a": "second"}, truncating.Snapshot().Strings())
+
+	appending := New(Append)
+	write(t, appending, "a", "first")
+	write(t, appending, "a", "second")
+	assert.Equal(t, map[string]string{"a": "firstsecond"}, appending.Snapshot().Strings())
+
+	failing := New(FailIfExists)
+	write(t, failing, "a", "first")
+	_, err := failing.WriterFactory()("a")
+	assert.True(t, errors.Is(err, ErrFileExists))
+	assert.True(t, errors.Is(err, fs.ErrExist))
+}
+
+func TestOpenState(t *testing.T) {
+	m := New(Truncate)
+	w1, err := m.WriterFactory()("dir/a")
+	assert.NoError(t, err)
+	w2, err := m.WriterFactory()("dir/b")
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"dir/a", "dir/b"}, m.OpenFiles())
+	assert.True(t, m.IsOpen("dir/a"))
+
+	_, err = w1.Write([]byte("visible before close"))
+	assert.NoError(t, err)
+	data, err := m.ReadFile("dir/a")
+	assert.NoError(t, err)
+	assert.Equal(t, "visible before close", string(data))
+
+	assert.NoError(t, w1.Close())
+	assert.Equal(t, ErrClosed, w1.Close())
+	_, err = w1.Write([]byte("more"))
+	assert.Equal(t, ErrClosed, err)
+	assert.False(t, m.IsOpen("dir/a"))
+	assert.Equal(t, []string{"dir/b"}, m.OpenFiles())
+
+	assert.NoError(t, w2.Close())
+	assert.Empty(t, m.OpenFiles())
+}
+
+func TestReadersAndListing(t *testing.T) {
+	m := New(Truncate)
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	write(t, m, "data/b/2", "two")
+	write(t, m, "data/a/1", "one")
+	write(t, m, "data/plain", "plain")
+	m.WriteFile("other", []byte("other"))
+
+	assert.Equal(t, []string{"data/a/1", "data/b/2", "data/plain"}, listPaths(t, m.Lister()("data/")))
+	assert.Equal(t, []string{"data/a/", "data/b/", "data/plain"}, listPaths(t, m.DirLister()("data")))
+	info, err := m.Lister()("other")()
+	assert.NoError(t, err)
+	assert.Equal(t, readerfactory.ObjectInfo{Path: "other", Size: 5, ModTime: now}, info)
+
+	r, err := m.ReaderFactory().WithPrefix("data")("a/1")
+	assert.NoError(t, err)
+	content, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "one", string(content))
+
+	assert.NoError(t, m.Remover()("data/a/1"))
+	assert.NoError(t, m.Remove("missing"))
+	_, err = m.ReaderFactory()("data/a/1")
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+}
+
+func TestSnapshotDiff(t *testing.T) {
+	m := New(Truncate)
+	write(t, m, "kept", "same")
+	write(t, m, "modified", "before")
+	write(t, m, "removed", "gone")
+	before := m.Snapshot()
+	assert.True(t, Diff(before, m.Snapshot()).Empty())
+
+	write(t, m, "modified", "after")
+	write(t, m, "added", "new")
+	assert.NoError(t, m.Remove("removed"))
+
+	assert.Equal(t, Changes{
+		Added:    []string{"added"},
+		Modified: []string{"modified"},
+		Removed:  []string{"removed"},
+	}, Diff(before, m.Snapshot()))
+	assert.Equal(t, "before", string(before["modified"]), "snapshots are copies")
+}
+
+func TestConcurrentWriters(t *testing.T) {
+	m := New(Append)
+	wf := m.WriterFactory()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w, err := wf(fmt.Sprintf("file-%d", i%4))
+			assert.NoError(t, err)
+			for j := 0; j < 100; j++ {
+				w.Write([]byte("x"))
+				listPaths(t, m.Lister()(""))
+			}
+			assert.NoError(t, w.Close())
+		}(i)
+	}
+	wg.Wait()
+
+	snapshot := m.Snapshot()
+	assert.Len(t, snapshot, 4)
+	for _, data := range snapshot {
+		assert.Len(t, data, 500)
+	}
+	assert.Empty(t, m.OpenFiles())
+}
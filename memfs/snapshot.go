@@ -0,0 +1,62 @@
+package memfs
+
+import (
+	"bytes"
+	"sort"
+)
+
+// Snapshot is a copy of the content of all files of an FS at a point in time
+type Snapshot map[string][]byte
+
+// Changes are the sorted paths which differ between two snapshots
+type Changes struct {
+	Added    []string
+	Modified []string
+	Removed  []string
+}
+
+// Snapshot returns a copy of the content of all files
+func (m *FS) Snapshot() Snapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	snapshot := make(Snapshot, len(m.files))
+	for path, f := range m.files {
+		snapshot[path] = append([]byte{}, f.data...)
+	}
+	return snapshot
+}
+
+// Strings returns the content of the files as strings; convenient for assertions
+func (s Snapshot) Strings() map[string]string {
+	res := make(map[string]string, len(s))
+	for path, data := range s {
+		res[path] = string(data)
+	}
+	return res
+}
+
+// Diff returns the paths added, modified and removed between the snapshots before and after
+func Diff(before, after Snapshot) Changes {
+	changes := Changes{Added: []string{}, Modified: []string{}, Removed: []string{}}
+	for path, data := range after {
+		if previous, ok := before[path]; !ok {
+			changes.Added = append(changes.Added, path)
+		} else if !bytes.Equal(previous, data) {
+			changes.Modified = append(changes.Modified, path)
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			changes.Removed = append(changes.Removed, path)
+		}
+	}
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Modified)
+	sort.Strings(changes.Removed)
+	return changes
+}
+
+// Empty returns true if there are no changes
+func (c Changes) Empty() bool {
+	return len(c.Added) == 0 && len(c.Modified) == 0 && len(c.Removed) == 0
+}
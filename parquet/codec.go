@@ -0,0 +1,64 @@
+package parquet
+
+import (
+	"io"
+
+	"github.com/kvanticoss/goutils/v2/codec"
+	"github.com/kvanticoss/goutils/v2/iterator"
+	"github.com/kvanticoss/goutils/v2/recordwriter"
+	"github.com/kvanticoss/goutils/v2/writerfactory"
+)
+
+// NewCodec returns a codec.Codec writing parquet files with the schema and options; e.g. for use with
+// recordwriter.PartitionedBySize. Row groups are buffered in memory, so files are rotated at row group
+// boundaries (see WriterOptions.RowGroupSize). Decoding isn't supported; the Decoder returns ErrDecodeNotSupported.
+func NewCodec(schema *Schema, opts WriterOptions) codec.Codec {
+	return parquetCodec{schema: schema, opts: opts}
+}
+
+type parquetCodec struct {
+	schema *Schema
+	opts   WriterOptions
+}
+
+func (parquetCodec) Name() string {
+	return "parquet"
+}
+
+func (parquetCodec) Extension() string {
+	return ".parquet"
+}
+
+func (c parquetCodec) NewEncoder(w io.Writer) codec.Encoder {
+	return NewWriter(w, c.schema, c.opts)
+}
+
+func (parquetCodec) NewDecoder(r io.Reader) codec.Decoder {
+	return unsupportedDecoder{}
+}
+
+type unsupportedDecoder struct{}
+
+func (unsupportedDecoder) Decode(v interface{}) error {
+	return ErrDecodeNotSupported
+}
+
+// Write writes all records from the iterator as a single parquet file created by the WriterFactory at path.
+// Returns iterator.ErrIteratorStop once all records have been written, otherwise the first error encountered.
+func Write[T any](
+	it iterator.RecordIterator[T],
+	wf writerfactory.WriterFactory,
+	path string,
+	schema *Schema,
+	opts WriterOptions,
+) error {
+	w, err := wf(path)
+	if err != nil {
+		return err
+	}
+	err = recordwriter.Encode(it, w, NewCodec(schema, opts))
+	if closeErr := w.Close(); closeErr != nil && err == iterator.ErrIteratorStop {
+		return closeErr
+	}
+	return err
+}
@@ -0,0 +1,155 @@
+package parquet
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// column buffers the levels and PLAIN encoded values of one leaf until they are written as a data page
+type column struct {
+	node *node
+
+	values    []byte
+	boolBits  int // number of bits used in the last byte of values (boolean columns)
+	defLevels []int
+	repLevels []int
+	nulls     int64
+
+	// pages of the current row group (compressed, including page headers)
+	chunk            []byte
+	chunkValues      int64
+	chunkNulls       int64
+	uncompressedSize int64
+}
+
+func (c *column) addNull(rep, def int) {
+	c.repLevels = append(c.repLevels, rep)
+	c.defLevels = append(c.defLevels, def)
+	c.nulls++
+}
+
+func (c *column) addValue(rep int, v interface{}) error {
+	var err error
+	switch c.node.physicalType {
+	case typeBoolean:
+		var b bool
+		if b, err = toBool(v); err == nil {
+			if c.boolBits == 0 {
+				c.values = append(c.values, 0)
+			}
+			if b {
+				c.values[len(c.values)-1] |= 1 << c.boolBits
+			}
+			c.boolBits = (c.boolBits + 1) % 8
+		}
+	case typeInt32:
+		var n int64
+		if n, err = toDate(v); err == nil {
+			c.values = binary.LittleEndian.AppendUint32(c.values, uint32(int32(n)))
+		}
+	case typeInt64:
+		var n int64
+		if n, err = c.toInt64(v); err == nil {
+			c.values = binary.LittleEndian.AppendUint64(c.values, uint64(n))
+		}
+	case typeDouble:
+		var f float64
+		if f, err = toDouble(v); err == nil {
+			c.values = binary.LittleEndian.AppendUint64(c.values, math.Float64bits(f))
+		}
+	case typeByteArray:
+		var b []byte
+		if b, err = c.toBytes(v); err == nil {
+			c.values = binary.LittleEndian.AppendUint32(c.values, uint32(len(b)))
+			c.values = append(c.values, b...)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	c.repLevels = append(c.repLevels, rep)
+	c.defLevels = append(c.defLevels, c.node.defLevel)
+	return nil
+}
+
+// columnState is used to roll back the values added by a record which failed to encode
+type columnState struct {
+	values, boolBits, levels int
+	nulls                    int64
+}
+
+func (c *column) state() columnState {
+	return columnState{values: len(c.values), boolBits: c.boolBits, levels: len(c.defLevels), nulls: c.nulls}
+}
+
+func (c *column) restore(s columnState) {
+	c.values = c.values[:s.values]
+	c.boolBits = s.boolBits
+	if s.boolBits > 0 {
+		c.values[len(c.values)-1] &= byte(1)<<s.boolBits - 1
+	}
+	c.defLevels = c.defLevels[:s.levels]
+	c.repLevels = c.repLevels[:s.levels]
+	c.nulls = s.nulls
+}
+
+// bufferedSize is the approximate size of the values and levels not yet written to a page
+func (c *column) bufferedSize() int {
+	return len(c.values) + len(c.defLevels)/4 + len(c.repLevels)/4
+}
+
+// pageData returns the uncompressed data page content; repetition levels, definition levels and values
+func (c *column) pageData() []byte {
+	var data []byte
+	if c.node.repLevel > 0 {
+		data = appendLevels(data, c.repLevels, c.node.repLevel)
+	}
+	if c.node.defLevel > 0 {
+		data = appendLevels(data, c.defLevels, c.node.defLevel)
+	}
+	return append(data, c.values...)
+}
+
+func (c *column) resetPage() {
+	c.values = c.values[:0]
+	c.boolBits = 0
+	c.defLevels = c.defLevels[:0]
+	c.repLevels = c.repLevels[:0]
+	c.nulls = 0
+}
+
+func (c *column) resetChunk() {
+	c.chunk = c.chunk[:0]
+	c.chunkValues = 0
+	c.chunkNulls = 0
+	c.uncompressedSize = 0
+}
+
+// appendLevels appends levels in the RLE/bit-packing hybrid encoding prefixed by the 4 byte length.
+// Only RLE runs are used which keeps the encoder simple and is compact for the typical long runs of equal levels.
+func appendLevels(buf []byte, levels []int, maxLevel int) []byte {
+	byteWidth := (bitWidth(maxLevel) + 7) / 8
+	start := len(buf)
+	buf = append(buf, 0, 0, 0, 0)
+	for i := 0; i < len(levels); {
+		run := 1
+		for i+run < len(levels) && levels[i+run] == levels[i] {
+			run++
+		}
+		buf = binary.AppendUvarint(buf, uint64(run)<<1)
+		for b := 0; b < byteWidth; b++ {
+			buf = append(buf, byte(levels[i]>>(8*b)))
+		}
+		i += run
+	}
+	binary.LittleEndian.PutUint32(buf[start:], uint32(len(buf)-start-4))
+	return buf
+}
+
+func bitWidth(maxLevel int) int {
+	width := 0
+	for ; maxLevel > 0; maxLevel >>= 1 {
+		width++
+	}
+	return width
+}
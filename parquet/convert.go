@@ -0,0 +1,247 @@
+package parquet
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/kvanticoss/goutils/v2/iterator/bigquery_schema"
+)
+
+// timestampLayouts are the formats accepted for TIMESTAMP and DATETIME values given as strings; values without zone are UTC
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// Values are converted leniently since records usually originate from JSON where e.g. integers and timestamps
+// are represented as strings (matching how the schema is inferred by bigquery_schema.InferBQSchema).
+
+func toBool(v interface{}) (bool, error) {
+	switch t := v.(type) {
+	case bool:
+		return t, nil
+	case string:
+		b, err := strconv.ParseBool(t)
+		if err != nil {
+			return false, fmt.Errorf("%w: %q is not a boolean", ErrInvalidValue, t)
+		}
+		return b, nil
+	}
+	return false, fmt.Errorf("%w: can't write %T as BOOLEAN", ErrInvalidValue, v)
+}
+
+func toDouble(v interface{}) (float64, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), nil
+	case reflect.String: // including json.Number
+		f, err := strconv.ParseFloat(rv.String(), 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q is not a number", ErrInvalidValue, rv.String())
+		}
+		return f, nil
+	}
+	return 0, fmt.Errorf("%w: can't write %T as FLOAT", ErrInvalidValue, v)
+}
+
+func toInteger(v interface{}) (int64, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Uint() > math.MaxInt64 {
+			return 0, fmt.Errorf("%w: %d overflows INTEGER", ErrInvalidValue, rv.Uint())
+		}
+		return int64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		if f := rv.Float(); f == math.Trunc(f) && f >= math.MinInt64 && f <= math.MaxInt64 {
+			return int64(f), nil
+		}
+		return 0, fmt.Errorf("%w: %v is not an integer", ErrInvalidValue, rv.Float())
+	case reflect.String:
+		if n, err := strconv.ParseInt(rv.String(), 10, 64); err == nil {
+			return n, nil
+		}
+		if f, err := strconv.ParseFloat(rv.String(), 64); err == nil && f == math.Trunc(f) {
+			return int64(f), nil
+		}
+		return 0, fmt.Errorf("%w: %q is not an integer", ErrInvalidValue, rv.String())
+	}
+	return 0, fmt.Errorf("%w: can't write %T as INTEGER", ErrInvalidValue, v)
+}
+
+func toTime(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case *time.Time:
+		return *t, nil
+	case string:
+		for _, layout := range timestampLayouts {
+			if parsed, err := time.Parse(layout, t); err == nil {
+				return parsed, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("%w: can't parse %q as time", ErrInvalidValue, t)
+	}
+	return time.Time{}, fmt.Errorf("%w: can't write %T as time", ErrInvalidValue, v)
+}
+
+// toDate returns the number of days since the unix epoch of the calendar date
+func toDate(v interface{}) (int64, error) {
+	t, err := toTime(v)
+	if err != nil {
+		return 0, err
+	}
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / (24 * 60 * 60), nil
+}
+
+func (c *column) toInt64(v interface{}) (int64, error) {
+	switch c.node.bqType {
+	case bigquery_schema.TimestampFieldType:
+		if n, err := toInteger(v); err == nil { // micros since epoch
+			return n, nil
+		}
+		t, err := toTime(v)
+		return t.UnixMicro(), err
+	case bigquery_schema.DateTimeFieldType:
+		t, err := toTime(v)
+		if err != nil {
+			return 0, err
+		}
+		// DATETIME is a wall clock time; keep the local fields and drop the zone
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC).UnixMicro(), nil
+	case bigquery_schema.TimeFieldType:
+		var t time.Time
+		switch tv := v.(type) {
+		case time.Duration:
+			return tv.Microseconds(), nil
+		case string:
+			parsed, err := time.Parse("15:04:05.999999999", tv)
+			if err != nil {
+				return 0, fmt.Errorf("%w: can't parse %q as TIME", ErrInvalidValue, tv)
+			}
+			t = parsed
+		case time.Time:
+			t = tv
+		default:
+			return 0, fmt.Errorf("%w: can't write %T as TIME", ErrInvalidValue, v)
+		}
+		h, m, s := t.Clock()
+		return (int64(h*60+m)*60+int64(s))*1e6 + int64(t.Nanosecond()/1e3), nil
+	}
+	return toInteger(v)
+}
+
+func (c *column) toBytes(v interface{}) ([]byte, error) {
+	switch c.node.bqType {
+	case bigquery_schema.JSONFieldType:
+		return jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(v)
+	case bigquery_schema.BytesFieldType:
+		switch t := v.(type) {
+		case []byte:
+			return t, nil
+		case string: // base64 as in BigQuery JSON (and encoding/json for []byte)
+			b, err := base64.StdEncoding.DecodeString(t)
+			if err != nil {
+				return nil, fmt.Errorf("%w: BYTES must be base64 encoded: %v", ErrInvalidValue, err)
+			}
+			return b, nil
+		}
+		return nil, fmt.Errorf("%w: can't write %T as BYTES", ErrInvalidValue, v)
+	case bigquery_schema.NumericFieldType, bigquery_schema.BigNumericFieldType:
+		return toDecimal(v, c.node.scale)
+	}
+
+	switch t := v.(type) {
+	case string:
+		return []byte(t), nil
+	case []byte:
+		return t, nil
+	case json.Number:
+		return []byte(t), nil
+	case bool:
+		return []byte(strconv.FormatBool(t)), nil
+	case time.Time:
+		return []byte(t.Format(time.RFC3339Nano)), nil
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+		return jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(v)
+	}
+	return []byte(fmt.Sprint(v)), nil
+}
+
+// toDecimal returns the two's-complement big-endian representation of the value scaled by 10^scale
+func toDecimal(v interface{}, scale int) ([]byte, error) {
+	r := new(big.Rat)
+	switch t := v.(type) {
+	case big.Rat:
+		r.Set(&t)
+	case *big.Rat:
+		r.Set(t)
+	case float32, float64:
+		f, _ := toDouble(t)
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("%w: %v is not a decimal", ErrInvalidValue, f)
+		}
+		r.SetString(strconv.FormatFloat(f, 'f', -1, 64))
+	case string:
+		if _, ok := r.SetString(strings.TrimSpace(t)); !ok {
+			return nil, fmt.Errorf("%w: %q is not a decimal", ErrInvalidValue, t)
+		}
+	case json.Number:
+		if _, ok := r.SetString(string(t)); !ok {
+			return nil, fmt.Errorf("%w: %q is not a decimal", ErrInvalidValue, t)
+		}
+	default:
+		n, err := toInteger(v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: can't write %T as NUMERIC", ErrInvalidValue, v)
+		}
+		r.SetInt64(n)
+	}
+
+	// unscaled = round(r * 10^scale)
+	num := new(big.Int).Mul(r.Num(), new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil))
+	unscaled, rem := new(big.Int).QuoRem(num, r.Denom(), new(big.Int))
+	if rem.Sign() != 0 && new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2)).Cmp(r.Denom()) >= 0 {
+		unscaled.Add(unscaled, big.NewInt(int64(num.Sign())))
+	}
+	return twosComplement(unscaled), nil
+}
+
+func twosComplement(n *big.Int) []byte {
+	if n.Sign() >= 0 {
+		b := n.Bytes()
+		if len(b) == 0 || b[0]&0x80 != 0 {
+			b = append([]byte{0}, b...)
+		}
+		return b
+	}
+	size := new(big.Int).Not(n).BitLen()/8 + 1
+	b := new(big.Int).Add(n, new(big.Int).Lsh(big.NewInt(1), uint(size*8))).Bytes()
+	for len(b) < size {
+		b = append([]byte{0xff}, b...)
+	}
+	return b
+}
@@ -0,0 +1,4 @@
+// Package parquet writes Apache Parquet files with schemas derived from (inferred) BigQuery schemas.
+// Nested RECORD and REPEATED fields are supported; pages are PLAIN encoded and can be compressed
+// with snappy, gzip or zstd. Files are written through any io.Writer, e.g. from a writerfactory.WriterFactory.
+package parquet
@@ -0,0 +1,16 @@
+package parquet
+
+import "errors"
+
+var (
+	// ErrInvalidSchema is returned when a BigQuery schema can't be converted to a parquet schema
+	ErrInvalidSchema = errors.New("invalid parquet schema")
+	// ErrUnsupportedType is returned for BigQuery types without parquet mapping
+	ErrUnsupportedType = errors.New("unsupported BigQuery type")
+	// ErrInvalidValue is returned when a record doesn't match the schema
+	ErrInvalidValue = errors.New("value does not match parquet schema")
+	// ErrUnsupportedCompression is returned for unknown page compressions
+	ErrUnsupportedCompression = errors.New("unsupported parquet compression")
+	// ErrDecodeNotSupported is returned by the Decoder of the parquet codec; the package only writes parquet files
+	ErrDecodeNotSupported = errors.New("parquet decoding is not supported")
+)
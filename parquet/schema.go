@@ -0,0 +1,311 @@
+package parquet
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kvanticoss/goutils/v2/iterator/bigquery_schema"
+)
+
+// Parquet physical types
+const (
+	typeBoolean   = 0
+	typeInt32     = 1
+	typeInt64     = 2
+	typeDouble    = 5
+	typeByteArray = 6
+)
+
+// Parquet field repetition types
+const (
+	repetitionRequired = 0
+	repetitionOptional = 1
+	repetitionRepeated = 2
+)
+
+// Parquet converted types; written alongside the logical types for older readers
+const (
+	convertedNone            = -1
+	convertedUTF8            = 0
+	convertedDecimal         = 5
+	convertedDate            = 6
+	convertedTimeMicros      = 8
+	convertedTimestampMicros = 10
+	convertedJSON            = 19
+)
+
+// DefaultNumericPrecision and DefaultNumericScale are used for NUMERIC columns without explicit precision;
+// BIGNUMERIC columns default to DefaultBigNumericPrecision and DefaultBigNumericScale.
+const (
+	DefaultNumericPrecision    = 38
+	DefaultNumericScale        = 9
+	DefaultBigNumericPrecision = 76
+	DefaultBigNumericScale     = 38
+)
+
+// Schema is a parquet message schema derived from a BigQuery schema
+type Schema struct {
+	root   *node
+	leaves []*node
+}
+
+// node is a group or leaf of the schema tree
+type node struct {
+	name       string
+	bqType     bigquery_schema.FieldType
+	repetition int
+	children   []*node  // groups only
+	path       []string // leaves only
+	leafIndex  int      // leaves only; index of the column
+
+	physicalType int
+	convertedTyp int
+	precision    int
+	scale        int
+
+	// maximum definition and repetition levels of values at this node
+	defLevel int
+	repLevel int
+}
+
+// NewSchema converts an (inferred) BigQuery schema to a parquet schema. RECORD fields become groups,
+// REPEATED fields repeated fields and NULLABLE (or unset) modes optional fields.
+// Repeated fields are written without LIST annotations which BigQuery loads as REPEATED columns.
+//
+// Type mapping:
+//
+//	STRING, GEOGRAPHY, INTERVAL -> BYTE_ARRAY (STRING)
+//	JSON                        -> BYTE_ARRAY (JSON)
+//	BYTES                       -> BYTE_ARRAY
+//	INTEGER                     -> INT64
+//	FLOAT                       -> DOUBLE
+//	BOOLEAN                     -> BOOLEAN
+//	TIMESTAMP                   -> INT64 (TIMESTAMP(MICROS, UTC))
+//	DATETIME                    -> INT64 (TIMESTAMP(MICROS, local))
+//	DATE                        -> INT32 (DATE)
+//	TIME                        -> INT64 (TIME(MICROS))
+//	NUMERIC, BIGNUMERIC         -> BYTE_ARRAY (DECIMAL)
+func NewSchema(bq bigquery_schema.TableFieldSchema) (*Schema, error) {
+	s := &Schema{root: &node{name: "schema", bqType: bigquery_schema.RecordFieldType, repetition: repetitionRequired}}
+	if len(bq.Fields) == 0 {
+		return nil, fmt.Errorf("%w: no fields", ErrInvalidSchema)
+	}
+	if err := s.addChildren(s.root, bq.Fields, nil); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Schema) addChildren(parent *node, fields []*bigquery_schema.TableFieldSchema, path []string) error {
+	names := map[string]bool{}
+	for _, field := range fields {
+		if field.Name == "" {
+			return fmt.Errorf("%w: field without name in %s", ErrInvalidSchema, strings.Join(path, "."))
+		}
+		if names[field.Name] {
+			return fmt.Errorf("%w: duplicate field %s", ErrInvalidSchema, strings.Join(append(path, field.Name), "."))
+		}
+		names[field.Name] = true
+
+		n := &node{
+			name:         field.Name,
+			bqType:       bigquery_schema.FieldType(strings.ToUpper(field.Type)),
+			convertedTyp: convertedNone,
+			defLevel:     parent.defLevel,
+			repLevel:     parent.repLevel,
+		}
+		switch strings.ToUpper(field.Mode) {
+		case "REQUIRED":
+			n.repetition = repetitionRequired
+		case "REPEATED":
+			n.repetition = repetitionRepeated
+			n.defLevel++
+			n.repLevel++
+		default:
+			n.repetition = repetitionOptional
+			n.defLevel++
+		}
+		parent.children = append(parent.children, n)
+		fieldPath := append(append([]string{}, path...), field.Name)
+
+		switch n.bqType {
+		case bigquery_schema.RecordFieldType, "STRUCT":
+			n.bqType = bigquery_schema.RecordFieldType
+			if len(field.Fields) == 0 {
+				return fmt.Errorf("%w: RECORD %s without fields", ErrInvalidSchema, strings.Join(fieldPath, "."))
+			}
+			if err := s.addChildren(n, field.Fields, fieldPath); err != nil {
+				return err
+			}
+			continue
+		case bigquery_schema.StringFieldType, bigquery_schema.GeographyFieldType, bigquery_schema.IntervalFieldType, bigquery_schema.NOFieldType:
+			n.bqType = bigquery_schema.StringFieldType
+			n.physicalType, n.convertedTyp = typeByteArray, convertedUTF8
+		case bigquery_schema.JSONFieldType:
+			n.physicalType, n.convertedTyp = typeByteArray, convertedJSON
+		case bigquery_schema.BytesFieldType:
+			n.physicalType = typeByteArray
+		case bigquery_schema.IntegerFieldType, "INT64":
+			n.bqType = bigquery_schema.IntegerFieldType
+			n.physicalType = typeInt64
+		case bigquery_schema.FloatFieldType, "FLOAT64":
+			n.bqType = bigquery_schema.FloatFieldType
+			n.physicalType = typeDouble
+		case bigquery_schema.BooleanFieldType, "BOOL":
+			n.bqType = bigquery_schema.BooleanFieldType
+			n.physicalType = typeBoolean
+		case bigquery_schema.TimestampFieldType:
+			n.physicalType, n.convertedTyp = typeInt64, convertedTimestampMicros
+		case bigquery_schema.DateTimeFieldType:
+			n.physicalType = typeInt64
+		case bigquery_schema.DateFieldType:
+			n.physicalType, n.convertedTyp = typeInt32, convertedDate
+		case bigquery_schema.TimeFieldType:
+			n.physicalType, n.convertedTyp = typeInt64, convertedTimeMicros
+		case bigquery_schema.NumericFieldType, bigquery_schema.BigNumericFieldType:
+			n.physicalType, n.convertedTyp = typeByteArray, convertedDecimal
+			n.precision, n.scale = int(field.Precision), int(field.Scale)
+			if n.precision == 0 {
+				n.precision, n.scale = DefaultNumericPrecision, DefaultNumericScale
+				if n.bqType == bigquery_schema.BigNumericFieldType {
+					n.precision, n.scale = DefaultBigNumericPrecision, DefaultBigNumericScale
+				}
+			}
+		default:
+			return fmt.Errorf("%w: BigQuery type %s of field %s", ErrUnsupportedType, field.Type, strings.Join(fieldPath, "."))
+		}
+
+		n.path = fieldPath
+		n.leafIndex = len(s.leaves)
+		s.leaves = append(s.leaves, n)
+	}
+	return nil
+}
+
+func (n *node) isGroup() bool {
+	return n.bqType == bigquery_schema.RecordFieldType
+}
+
+// String returns the schema in the textual message format used by the parquet tools
+func (s *Schema) String() string {
+	var sb strings.Builder
+	sb.WriteString("message schema {\n")
+	for _, child := range s.root.children {
+		child.format(&sb, "  ")
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+func (n *node) format(sb *strings.Builder, indent string) {
+	sb.WriteString(indent)
+	sb.WriteString([]string{"required", "optional", "repeated"}[n.repetition])
+	if n.isGroup() {
+		fmt.Fprintf(sb, " group %s {\n", n.name)
+		for _, child := range n.children {
+			child.format(sb, indent+"  ")
+		}
+		sb.WriteString(indent + "}\n")
+		return
+	}
+
+	physical := map[int]string{
+		typeBoolean:   "boolean",
+		typeInt32:     "int32",
+		typeInt64:     "int64",
+		typeDouble:    "double",
+		typeByteArray: "binary",
+	}[n.physicalType]
+	fmt.Fprintf(sb, " %s %s", physical, n.name)
+	if logical := n.logicalTypeName(); logical != "" {
+		fmt.Fprintf(sb, " (%s)", logical)
+	}
+	sb.WriteString(";\n")
+}
+
+func (n *node) logicalTypeName() string {
+	switch n.bqType {
+	case bigquery_schema.StringFieldType:
+		return "STRING"
+	case bigquery_schema.JSONFieldType:
+		return "JSON"
+	case bigquery_schema.TimestampFieldType:
+		return "TIMESTAMP(MICROS,true)"
+	case bigquery_schema.DateTimeFieldType:
+		return "TIMESTAMP(MICROS,false)"
+	case bigquery_schema.DateFieldType:
+		return "DATE"
+	case bigquery_schema.TimeFieldType:
+		return "TIME(MICROS,false)"
+	case bigquery_schema.NumericFieldType, bigquery_schema.BigNumericFieldType:
+		return fmt.Sprintf("DECIMAL(%d,%d)", n.precision, n.scale)
+	}
+	return ""
+}
+
+// writeSchemaElements appends the flattened (depth first) schema elements of the footer
+func (n *node) writeSchemaElements(t *thriftWriter, elements *[]func(), isRoot bool) {
+	*elements = append(*elements, func() {
+		if !n.isGroup() {
+			t.fieldI32(1, int32(n.physicalType))
+		}
+		if !isRoot {
+			t.fieldI32(3, int32(n.repetition))
+		}
+		t.fieldBinary(4, []byte(n.name))
+		if n.isGroup() {
+			t.fieldI32(5, int32(len(n.children)))
+		}
+		if n.convertedTyp != convertedNone && !n.isGroup() {
+			t.fieldI32(6, int32(n.convertedTyp))
+		}
+		if n.bqType == bigquery_schema.NumericFieldType || n.bqType == bigquery_schema.BigNumericFieldType {
+			t.fieldI32(7, int32(n.scale))
+			t.fieldI32(8, int32(n.precision))
+		}
+		if !n.isGroup() {
+			n.writeLogicalType(t)
+		}
+	})
+	for _, child := range n.children {
+		child.writeSchemaElements(t, elements, false)
+	}
+}
+
+func (n *node) writeLogicalType(t *thriftWriter) {
+	empty := func() {}
+	micros := func() { t.fieldStruct(2, empty) }
+	var write func()
+	switch n.bqType {
+	case bigquery_schema.StringFieldType:
+		write = func() { t.fieldStruct(1, empty) }
+	case bigquery_schema.JSONFieldType:
+		write = func() { t.fieldStruct(12, empty) }
+	case bigquery_schema.NumericFieldType, bigquery_schema.BigNumericFieldType:
+		write = func() {
+			t.fieldStruct(5, func() {
+				t.fieldI32(1, int32(n.scale))
+				t.fieldI32(2, int32(n.precision))
+			})
+		}
+	case bigquery_schema.DateFieldType:
+		write = func() { t.fieldStruct(6, empty) }
+	case bigquery_schema.TimeFieldType:
+		write = func() {
+			t.fieldStruct(7, func() {
+				t.fieldBool(1, false)
+				t.fieldStruct(2, micros)
+			})
+		}
+	case bigquery_schema.TimestampFieldType, bigquery_schema.DateTimeFieldType:
+		write = func() {
+			t.fieldStruct(8, func() {
+				t.fieldBool(1, n.bqType == bigquery_schema.TimestampFieldType)
+				t.fieldStruct(2, micros)
+			})
+		}
+	default:
+		return
+	}
+	t.fieldStruct(10, write)
+}
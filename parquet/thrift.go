@@ -0,0 +1,97 @@
+package parquet
+
+import (
+	"encoding/binary"
+)
+
+// Thrift compact protocol field types
+const (
+	thriftBoolTrue  = 1
+	thriftBoolFalse = 2
+	thriftI32       = 5
+	thriftI64       = 6
+	thriftBinary    = 8
+	thriftList      = 9
+	thriftStruct    = 12
+)
+
+// thriftWriter serializes the parquet metadata structures with the thrift compact protocol.
+// Only the subset of the protocol needed for writing the footer and page headers is implemented.
+type thriftWriter struct {
+	buf     []byte
+	lastIDs []int16 // field id stack; one entry per open struct
+}
+
+func (t *thriftWriter) fieldHeader(id int16, fieldType byte) {
+	last := t.lastIDs[len(t.lastIDs)-1]
+	if delta := id - last; delta > 0 && delta <= 15 {
+		t.buf = append(t.buf, byte(delta)<<4|fieldType)
+	} else {
+		t.buf = append(t.buf, fieldType)
+		t.buf = binary.AppendVarint(t.buf, int64(id))
+	}
+	t.lastIDs[len(t.lastIDs)-1] = id
+}
+
+func (t *thriftWriter) structBegin() {
+	t.lastIDs = append(t.lastIDs, 0)
+}
+
+func (t *thriftWriter) structEnd() {
+	t.buf = append(t.buf, 0)
+	t.lastIDs = t.lastIDs[:len(t.lastIDs)-1]
+}
+
+func (t *thriftWriter) fieldStruct(id int16, write func()) {
+	t.fieldHeader(id, thriftStruct)
+	t.structBegin()
+	write()
+	t.structEnd()
+}
+
+func (t *thriftWriter) fieldBool(id int16, v bool) {
+	if v {
+		t.fieldHeader(id, thriftBoolTrue)
+	} else {
+		t.fieldHeader(id, thriftBoolFalse)
+	}
+}
+
+func (t *thriftWriter) fieldI32(id int16, v int32) {
+	t.fieldHeader(id, thriftI32)
+	t.buf = binary.AppendVarint(t.buf, int64(v))
+}
+
+func (t *thriftWriter) fieldI64(id int16, v int64) {
+	t.fieldHeader(id, thriftI64)
+	t.buf = binary.AppendVarint(t.buf, v)
+}
+
+func (t *thriftWriter) fieldBinary(id int16, v []byte) {
+	t.fieldHeader(id, thriftBinary)
+	t.binary(v)
+}
+
+func (t *thriftWriter) binary(v []byte) {
+	t.buf = binary.AppendUvarint(t.buf, uint64(len(v)))
+	t.buf = append(t.buf, v...)
+}
+
+func (t *thriftWriter) fieldList(id int16, elemType byte, size int, writeElem func(index int)) {
+	t.fieldHeader(id, thriftList)
+	if size < 15 {
+		t.buf = append(t.buf, byte(size)<<4|elemType)
+	} else {
+		t.buf = append(t.buf, 0xf0|elemType)
+		t.buf = binary.AppendUvarint(t.buf, uint64(size))
+	}
+	for index := 0; index < size; index++ {
+		if elemType == thriftStruct {
+			t.structBegin()
+			writeElem(index)
+			t.structEnd()
+		} else {
+			writeElem(index)
+		}
+	}
+}
@@ -0,0 +1,438 @@
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is the codec used to compress data pages
+type Compression int
+
+// Supported page compressions; the values match the parquet CompressionCodec enum
+const (
+	Uncompressed Compression = 0
+	Snappy       Compression = 1
+	Gzip         Compression = 2
+	Zstd         Compression = 6
+)
+
+// Defaults used for zero values of WriterOptions
+const (
+	DefaultRowGroupSize = 64 * 1024 * 1024
+	DefaultPageSize     = 1024 * 1024
+)
+
+const (
+	pageTypeData  = 0
+	encodingPlain = 0
+	encodingRLE   = 3
+	createdBy     = "github.com/kvanticoss/goutils/v2/parquet"
+)
+
+var magic = []byte("PAR1")
+
+// WriterOptions configures a Writer
+type WriterOptions struct {
+	// Compression of the data pages; defaults to Uncompressed (use Snappy for the common parquet default)
+	Compression Compression
+	// RowGroupSize is the approximate (uncompressed) size of each row group; defaults to DefaultRowGroupSize.
+	// Row groups are buffered in memory until written.
+	RowGroupSize int
+	// PageSize is the approximate (uncompressed) size of each data page; defaults to DefaultPageSize
+	PageSize int
+	// Metadata is added as key value metadata to the footer
+	Metadata map[string]string
+}
+
+// Writer writes records as a parquet file. Records are buffered in memory and written one row group at the time;
+// the footer is written on Close.
+type Writer struct {
+	w       io.Writer
+	schema  *Schema
+	opts    WriterOptions
+	columns []*column
+
+	offset        int64
+	numRows       int64
+	rowGroupRows  int64
+	rowGroupBytes int
+	rowGroups     []rowGroup
+
+	zstdEncoder *zstd.Encoder
+}
+
+type rowGroup struct {
+	numRows       int64
+	totalByteSize int64
+	columns       []columnChunk
+}
+
+type columnChunk struct {
+	offset           int64
+	numValues        int64
+	nulls            int64
+	compressedSize   int64
+	uncompressedSize int64
+}
+
+// NewWriter returns a Writer writing a parquet file with the schema to w
+func NewWriter(w io.Writer, schema *Schema, opts WriterOptions) *Writer {
+	if opts.RowGroupSize <= 0 {
+		opts.RowGroupSize = DefaultRowGroupSize
+	}
+	if opts.PageSize <= 0 {
+		opts.PageSize = DefaultPageSize
+	}
+	columns := make([]*column, len(schema.leaves))
+	for index, leaf := range schema.leaves {
+		columns[index] = &column{node: leaf}
+	}
+	return &Writer{
+		w:       w,
+		schema:  schema,
+		opts:    opts,
+		columns: columns,
+	}
+}
+
+// Encode adds a record to the current row group. Records are maps with string keys or structs (which are converted
+// through their JSON representation). Fields not in the schema are ignored.
+func (w *Writer) Encode(v interface{}) error {
+	switch w.opts.Compression {
+	case Uncompressed, Snappy, Gzip, Zstd:
+	default:
+		return fmt.Errorf("%w: %d", ErrUnsupportedCompression, w.opts.Compression)
+	}
+
+	record, err := toRecord(v)
+	if err != nil {
+		return err
+	}
+
+	// A record failing half way is rolled back so it doesn't leave a partial row behind
+	sizes := make([]int, len(w.columns))
+	states := make([]columnState, len(w.columns))
+	for index, c := range w.columns {
+		sizes[index] = c.bufferedSize()
+		states[index] = c.state()
+	}
+	if err := w.writeGroup(w.schema.root, record, 0); err != nil {
+		for index, c := range w.columns {
+			c.restore(states[index])
+		}
+		return err
+	}
+
+	w.numRows++
+	w.rowGroupRows++
+	for index, c := range w.columns {
+		w.rowGroupBytes += c.bufferedSize() - sizes[index]
+		if c.bufferedSize() >= w.opts.PageSize {
+			if err := w.flushPage(c); err != nil {
+				return err
+			}
+		}
+	}
+
+	if w.rowGroupBytes >= w.opts.RowGroupSize {
+		return w.Flush()
+	}
+	return nil
+}
+
+// Flush writes the buffered records as a row group
+func (w *Writer) Flush() error {
+	if w.rowGroupRows == 0 {
+		return nil
+	}
+	if w.offset == 0 {
+		if err := w.write(magic); err != nil {
+			return err
+		}
+	}
+
+	group := rowGroup{numRows: w.rowGroupRows}
+	for _, c := range w.columns {
+		if err := w.flushPage(c); err != nil {
+			return err
+		}
+		chunk := columnChunk{
+			offset:           w.offset,
+			numValues:        c.chunkValues,
+			nulls:            c.chunkNulls,
+			compressedSize:   int64(len(c.chunk)),
+			uncompressedSize: c.uncompressedSize,
+		}
+		if err := w.write(c.chunk); err != nil {
+			return err
+		}
+		group.totalByteSize += chunk.uncompressedSize
+		group.columns = append(group.columns, chunk)
+		c.resetChunk()
+	}
+
+	w.rowGroups = append(w.rowGroups, group)
+	w.rowGroupRows = 0
+	w.rowGroupBytes = 0
+	return nil
+}
+
+// Close writes the last row group and the footer. The underlying writer is not closed.
+func (w *Writer) Close() error {
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if w.offset == 0 {
+		if err := w.write(magic); err != nil {
+			return err
+		}
+	}
+
+	footer := w.footer()
+	footer = binary.LittleEndian.AppendUint32(footer, uint32(len(footer)))
+	return w.write(append(footer, magic...))
+}
+
+func (w *Writer) write(p []byte) error {
+	n, err := w.w.Write(p)
+	w.offset += int64(n)
+	return err
+}
+
+// writeGroup shreds the fields of a group value into the columns (see the Dremel paper for the level semantics)
+func (w *Writer) writeGroup(n *node, value map[string]interface{}, rep int) error {
+	for _, child := range n.children {
+		if err := w.writeField(child, value[child.name], rep); err != nil {
+			return fmt.Errorf("%s: %w", child.name, err)
+		}
+	}
+	return nil
+}
+
+func (w *Writer) writeField(n *node, value interface{}, rep int) error {
+	value = indirect(value)
+
+	if n.repetition == repetitionRepeated {
+		rv := reflect.ValueOf(value)
+		if value == nil || ((rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && rv.Len() == 0) {
+			w.addNulls(n, rep, n.defLevel-1)
+			return nil
+		}
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			return fmt.Errorf("%w: REPEATED field requires a list; got %T", ErrInvalidValue, value)
+		}
+		if _, isBytes := value.([]byte); isBytes {
+			return fmt.Errorf("%w: REPEATED field requires a list; got []byte", ErrInvalidValue)
+		}
+		for index := 0; index < rv.Len(); index++ {
+			itemRep := rep
+			if index > 0 {
+				itemRep = n.repLevel
+			}
+			item := indirect(rv.Index(index).Interface())
+			if item == nil {
+				return fmt.Errorf("%w: null element in REPEATED field", ErrInvalidValue)
+			}
+			if err := w.writeValue(n, item, itemRep); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if value == nil {
+		if n.repetition == repetitionRequired {
+			return fmt.Errorf("%w: missing REQUIRED field", ErrInvalidValue)
+		}
+		w.addNulls(n, rep, n.defLevel-1)
+		return nil
+	}
+	return w.writeValue(n, value, rep)
+}
+
+func (w *Writer) writeValue(n *node, value interface{}, rep int) error {
+	if !n.isGroup() {
+		return w.columns[n.leafIndex].addValue(rep, value)
+	}
+	record, err := toRecord(value)
+	if err != nil {
+		return err
+	}
+	return w.writeGroup(n, record, rep)
+}
+
+// addNulls adds a null (with the definition level of the closest defined ancestor) to all leaves below n
+func (w *Writer) addNulls(n *node, rep, def int) {
+	if !n.isGroup() {
+		w.columns[n.leafIndex].addNull(rep, def)
+		return
+	}
+	for _, child := range n.children {
+		w.addNulls(child, rep, def)
+	}
+}
+
+func indirect(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	for rv.IsValid() && (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface) {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil
+	}
+	return rv.Interface()
+}
+
+// toRecord returns the value as a map[string]interface{}; other maps and structs are converted through JSON
+func toRecord(v interface{}) (map[string]interface{}, error) {
+	if record, ok := v.(map[string]interface{}); ok {
+		return record, nil
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct && (rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String) {
+		return nil, fmt.Errorf("%w: records must be maps or structs; got %T", ErrInvalidValue, v)
+	}
+
+	data, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	record := map[string]interface{}{}
+	decoder := jsoniter.ConfigCompatibleWithStandardLibrary.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return record, decoder.Decode(&record)
+}
+
+// flushPage compresses the buffered values of the column into a data page of the current chunk
+func (w *Writer) flushPage(c *column) error {
+	numValues := len(c.defLevels)
+	if numValues == 0 {
+		return nil
+	}
+
+	data := c.pageData()
+	compressed, err := w.compress(data)
+	if err != nil {
+		return err
+	}
+
+	t := &thriftWriter{}
+	t.structBegin()
+	t.fieldI32(1, pageTypeData)
+	t.fieldI32(2, int32(len(data)))
+	t.fieldI32(3, int32(len(compressed)))
+	t.fieldStruct(5, func() {
+		t.fieldI32(1, int32(numValues))
+		t.fieldI32(2, encodingPlain)
+		t.fieldI32(3, encodingRLE)
+		t.fieldI32(4, encodingRLE)
+		t.fieldStruct(5, func() {
+			t.fieldI64(3, c.nulls)
+		})
+	})
+	t.structEnd()
+
+	c.chunk = append(c.chunk, t.buf...)
+	c.chunk = append(c.chunk, compressed...)
+	c.uncompressedSize += int64(len(t.buf) + len(data))
+	c.chunkValues += int64(numValues)
+	c.chunkNulls += c.nulls
+	c.resetPage()
+	return nil
+}
+
+func (w *Writer) compress(data []byte) ([]byte, error) {
+	switch w.opts.Compression {
+	case Snappy:
+		return snappy.Encode(nil, data), nil
+	case Gzip:
+		var buf bytes.Buffer
+		gw := gzip.NewWriter(&buf)
+		if _, err := gw.Write(data); err != nil {
+			return nil, err
+		}
+		if err := gw.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case Zstd:
+		if w.zstdEncoder == nil {
+			encoder, err := zstd.NewWriter(nil)
+			if err != nil {
+				return nil, err
+			}
+			w.zstdEncoder = encoder
+		}
+		return w.zstdEncoder.EncodeAll(data, nil), nil
+	}
+	return data, nil
+}
+
+// footer returns the thrift encoded FileMetaData
+func (w *Writer) footer() []byte {
+	t := &thriftWriter{}
+	t.structBegin()
+	t.fieldI32(1, 1) // version
+
+	elements := []func(){}
+	w.schema.root.writeSchemaElements(t, &elements, true)
+	t.fieldList(2, thriftStruct, len(elements), func(index int) { elements[index]() })
+
+	t.fieldI64(3, w.numRows)
+	t.fieldList(4, thriftStruct, len(w.rowGroups), func(index int) {
+		group := w.rowGroups[index]
+		t.fieldList(1, thriftStruct, len(group.columns), func(columnIndex int) {
+			chunk := group.columns[columnIndex]
+			c := w.schema.leaves[columnIndex]
+			t.fieldI64(2, chunk.offset)
+			t.fieldStruct(3, func() {
+				t.fieldI32(1, int32(c.physicalType))
+				encodings := []int32{encodingPlain, encodingRLE}
+				t.fieldList(2, thriftI32, len(encodings), func(i int) {
+					t.buf = binary.AppendVarint(t.buf, int64(encodings[i]))
+				})
+				t.fieldList(3, thriftBinary, len(c.path), func(i int) { t.binary([]byte(c.path[i])) })
+				t.fieldI32(4, int32(w.opts.Compression))
+				t.fieldI64(5, chunk.numValues)
+				t.fieldI64(6, chunk.uncompressedSize)
+				t.fieldI64(7, chunk.compressedSize)
+				t.fieldI64(9, chunk.offset)
+				t.fieldStruct(12, func() {
+					t.fieldI64(3, chunk.nulls)
+				})
+			})
+		})
+		t.fieldI64(2, group.totalByteSize)
+		t.fieldI64(3, group.numRows)
+	})
+
+	if len(w.opts.Metadata) > 0 {
+		keys := make([]string, 0, len(w.opts.Metadata))
+		for key := range w.opts.Metadata {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		t.fieldList(5, thriftStruct, len(keys), func(index int) {
+			t.fieldBinary(1, []byte(keys[index]))
+			t.fieldBinary(2, []byte(w.opts.Metadata[keys[index]]))
+		})
+	}
+	t.fieldBinary(6, []byte(createdBy))
+	t.structEnd()
+	return t.buf
+}
@@ -0,0 +1,216 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/kvanticoss/goutils/v2/iterator"
+	"github.com/kvanticoss/goutils/v2/iterator/bigquery_schema"
+	"github.com/kvanticoss/goutils/v2/iterator/test_utils"
+	"github.com/kvanticoss/goutils/v2/writerfactory"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testSchema(t *testing.T) *Schema {
+	schema, err := NewSchema(bigquery_schema.TableFieldSchema{Fields: []*bigquery_schema.TableFieldSchema{
+		{Name: "id", Type: "INTEGER", Mode: "REQUIRED"},
+		{Name: "name", Type: "STRING"},
+		{Name: "items", Type: "RECORD", Mode: "REPEATED", Fields: []*bigquery_schema.TableFieldSchema{
+			{Name: "sku", Type: "STRING"},
+			{Name: "qty", Type: "INTEGER", Mode: "REPEATED"},
+		}},
+	}})
+	assert.NoError(t, err)
+	return schema
+}
+
+func TestNewSchema(t *testing.T) {
+	assert.Equal(t, `message schema {
+  required int64 id;
+  optional binary name (STRING);
+  repeated group items {
+    optional binary sku (STRING);
+    repeated int64 qty;
+  }
+}
+`, testSchema(t).String())
+
+	_, err := NewSchema(bigquery_schema.TableFieldSchema{Fields: []*bigquery_schema.TableFieldSchema{{Name: "a", Type: "UNKNOWN"}}})
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+	_, err = NewSchema(bigquery_schema.TableFieldSchema{})
+	assert.ErrorIs(t, err, ErrInvalidSchema)
+}
+
+func TestWriterShredsNestedRecords(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, testSchema(t), WriterOptions{})
+	assert.NoError(t, w.Encode(map[string]interface{}{
+		"id":   1,
+		"name": "a",
+		"items": []interface{}{
+			map[string]interface{}{"sku": "s1", "qty": []interface{}{1, 2}},
+			map[string]interface{}{"sku": "s2"},
+		},
+	}))
+	assert.NoError(t, w.Encode(map[string]interface{}{"id": "2"}))
+	assert.ErrorIs(t, w.Encode(map[string]interface{}{"name": "missing id"}), ErrInvalidValue)
+
+	// Levels and values as they would be written to the pages
+	id, name, sku, qty := w.columns[0], w.columns[1], w.columns[2], w.columns[3]
+	assert.Equal(t, []int{0, 0}, id.repLevels)
+	assert.Equal(t, []int{1, 0}, name.defLevels)
+	assert.Equal(t, []int{0, 1, 0}, sku.repLevels)
+	assert.Equal(t, []int{2, 2, 0}, sku.defLevels)
+	assert.Equal(t, []int{0, 2, 1, 0}, qty.repLevels)
+	assert.Equal(t, []int{2, 2, 1, 0}, qty.defLevels)
+	assert.Equal(t, []byte{1, 0, 0, 0, 0, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0}, id.values)
+	assert.Equal(t, int64(2), qty.nulls)
+
+	assert.NoError(t, w.Close())
+	data := buf.Bytes()
+	assert.Equal(t, magic, data[:4])
+	assert.Equal(t, magic, data[len(data)-4:])
+
+	footerSize := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footer := readThriftStruct(t, data[len(data)-8-footerSize:len(data)-8])
+	assert.Equal(t, int64(2), footer[3], "num_rows")
+	assert.Len(t, footer[2], 6, "root, id, name, items, sku, qty")
+	assert.Len(t, footer[4], 1, "row groups")
+}
+
+func TestWriterRowGroupsAndCompression(t *testing.T) {
+	for _, compression := range []Compression{Uncompressed, Snappy, Gzip, Zstd} {
+		var buf bytes.Buffer
+		w := NewWriter(&buf, testSchema(t), WriterOptions{Compression: compression, RowGroupSize: 32})
+		for i := 0; i < 10; i++ {
+			assert.NoError(t, w.Encode(map[string]interface{}{"id": i, "name": "some name"}))
+		}
+		assert.NoError(t, w.Close())
+
+		data := buf.Bytes()
+		footerSize := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+		footer := readThriftStruct(t, data[len(data)-8-footerSize:len(data)-8])
+		assert.Equal(t, int64(10), footer[3])
+		assert.Len(t, footer[4], 5, "a row group every 2 records")
+
+		rowGroup := footer[4].([]interface{})[0].(map[int16]interface{})
+		chunk := rowGroup[1].([]interface{})[0].(map[int16]interface{})
+		meta := chunk[3].(map[int16]interface{})
+		assert.Equal(t, int64(compression), meta[4], "codec")
+		assert.Equal(t, int64(4), meta[9], "first data page follows the magic")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	db, wf := writerfactory.GetMemoryWriterFactory()
+	records := []map[string]interface{}{{"id": 1}, {"id": 2}}
+	err := Write(test_utils.NewDummyIteratorFromArr(records), wf, "out.parquet", testSchema(t), WriterOptions{})
+	assert.Equal(t, iterator.ErrIteratorStop, err)
+	assert.Equal(t, magic, db["out.parquet"].Bytes()[:4])
+}
+
+// readThriftStruct decodes a thrift compact protocol struct into field id -> value; used to inspect the footer
+func readThriftStruct(t *testing.T, data []byte) map[int16]interface{} {
+	r := &thriftReader{buf: data}
+	res := r.readStruct()
+	assert.Equal(t, len(data), r.pos, "footer fully consumed")
+	return res
+}
+
+type thriftReader struct {
+	buf []byte
+	pos int
+}
+
+func (r *thriftReader) varint() int64 {
+	n, size := binary.Varint(r.buf[r.pos:])
+	r.pos += size
+	return n
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	n, size := binary.Uvarint(r.buf[r.pos:])
+	r.pos += size
+	return n
+}
+
+func (r *thriftReader) readStruct() map[int16]interface{} {
+	res := map[int16]interface{}{}
+	var id int16
+	for {
+		header := r.buf[r.pos]
+		r.pos++
+		if header == 0 {
+			return res
+		}
+		if delta := int16(header >> 4); delta != 0 {
+			id += delta
+		} else {
+			id = int16(r.varint())
+		}
+		res[id] = r.readValue(header & 0x0f)
+	}
+}
+
+func (r *thriftReader) readValue(fieldType byte) interface{} {
+	switch fieldType {
+	case thriftBoolTrue:
+		return true
+	case thriftBoolFalse:
+		return false
+	case thriftI32, thriftI64:
+		return r.varint()
+	case thriftBinary:
+		size := int(r.uvarint())
+		r.pos += size
+		return string(r.buf[r.pos-size : r.pos])
+	case thriftList:
+		header := r.buf[r.pos]
+		r.pos++
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(r.uvarint())
+		}
+		items := []interface{}{}
+		for i := 0; i < size; i++ {
+			if header&0x0f == thriftStruct {
+				items = append(items, r.readStruct())
+			} else {
+				items = append(items, r.readValue(header&0x0f))
+			}
+		}
+		return items
+	case thriftStruct:
+		return r.readStruct()
+	}
+	panic("unsupported thrift type")
+}
+
+func TestWriterConvertsJSONValues(t *testing.T) {
+	schema, err := NewSchema(bigquery_schema.TableFieldSchema{Fields: []*bigquery_schema.TableFieldSchema{
+		{Name: "ts", Type: "TIMESTAMP", Mode: "REQUIRED"},
+		{Name: "day", Type: "DATE", Mode: "REQUIRED"},
+		{Name: "price", Type: "NUMERIC", Mode: "REQUIRED", Precision: 5, Scale: 2},
+		{Name: "ok", Type: "BOOLEAN", Mode: "REPEATED"},
+	}})
+	assert.NoError(t, err)
+
+	w := NewWriter(&bytes.Buffer{}, schema, WriterOptions{})
+	assert.NoError(t, w.Encode(map[string]interface{}{
+		"ts":    "1970-01-01T00:00:01.5Z",
+		"day":   "1970-01-03",
+		"price": "-1.005",
+		"ok":    []interface{}{true, "false", true},
+	}))
+	assert.Equal(t, []byte{0x60, 0xe3, 0x16, 0, 0, 0, 0, 0}, w.columns[0].values, "1500000 micros")
+	assert.Equal(t, []byte{2, 0, 0, 0}, w.columns[1].values, "2 days")
+	assert.Equal(t, []byte{1, 0, 0, 0, 0x9b}, w.columns[2].values, "length prefixed -101 (rounded half away from zero)")
+	assert.Equal(t, []byte{0b101}, w.columns[3].values, "bit packed booleans")
+
+	// A failing record doesn't leave partial values behind
+	assert.ErrorIs(t, w.Encode(map[string]interface{}{"ts": 1, "day": "not a date"}), ErrInvalidValue)
+	assert.Equal(t, []byte{0b101}, w.columns[3].values)
+	assert.Len(t, w.columns[0].defLevels, 1)
+}
@@ -0,0 +1,53 @@
+package readerfactory
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/kvanticoss/goutils/v2/checksum"
+)
+
+// VerifyChecksums re-reads the file at path and compares its digests with the sidecars written by
+// writerfactory.WithChecksums (SHA256 if no algorithms are given), as well as its size if the sidecars hold it. A
+// mismatch is returned as checksum.ErrMismatch.
+func VerifyChecksums(rf ReaderFactory, path string, algos ...checksum.Algorithm) (checksum.Checksums, error) {
+	if len(algos) == 0 {
+		algos = []checksum.Algorithm{checksum.SHA256}
+	}
+
+	expected := checksum.Checksums{Path: path, Bytes: -1, Digests: map[checksum.Algorithm]string{}}
+	for _, algo := range algos {
+		sidecarPath := checksum.SidecarPath(path, algo)
+		content, err := readAll(rf, sidecarPath)
+		if err != nil {
+			return checksum.Checksums{}, fmt.Errorf("%s: %w", sidecarPath, err)
+		}
+		digest, size, err := checksum.ParseSidecar(content)
+		if err != nil {
+			return checksum.Checksums{}, fmt.Errorf("%s: %w", sidecarPath, err)
+		}
+		if size >= 0 {
+			if expected.Bytes >= 0 && expected.Bytes != size {
+				return checksum.Checksums{}, fmt.Errorf("%s: %w: %d bytes while other sidecars have %d", sidecarPath, checksum.ErrMismatch, size, expected.Bytes)
+			}
+			expected.Bytes = size
+		}
+		expected.Digests[algo] = digest
+	}
+
+	r, err := rf(path)
+	if err != nil {
+		return checksum.Checksums{}, err
+	}
+	defer r.Close()
+	return checksum.Verify(r, expected)
+}
+
+func readAll(rf ReaderFactory, path string) ([]byte, error) {
+	r, err := rf(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
@@ -0,0 +1,49 @@
+package readerfactory
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/kvanticoss/goutils/v2/checksum"
+	"github.com/kvanticoss/goutils/v2/writerfactory"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyChecksums(t *testing.T) {
+	buffers, wf := writerfactory.GetMemoryWriterFactory()
+	rf := GetMemoryReaderFactory(buffers)
+
+	write(t, wf.WithChecksums(checksum.SHA256, checksum.CRC32C).WithGzip(), "data/file", "some content")
+
+	checksums, err := VerifyChecksums(rf, "data/file.gz", checksum.SHA256, checksum.CRC32C)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(buffers["data/file.gz"].Len()), checksums.Bytes)
+	_, err = VerifyChecksums(rf, "data/file.gz")
+	assert.NoError(t, err)
+
+	_, err = VerifyChecksums(rf, "data/file.gz", checksum.MD5)
+	assert.True(t, errors.Is(err, fs.ErrNotExist), "no md5 sidecar was written")
+
+	buffers["data/file.gz"].WriteString("appended")
+	_, err = VerifyChecksums(rf, "data/file.gz")
+	assert.True(t, errors.Is(err, checksum.ErrMismatch))
+}
+
+func TestVerifyChecksumsSize(t *testing.T) {
+	buffers, wf := writerfactory.GetMemoryWriterFactory()
+	rf := GetMemoryReaderFactory(buffers)
+	write(t, wf.WithChecksums(checksum.SHA256, checksum.MD5), "file", "some content")
+
+	sidecar := buffers["file.sha256"].String()
+	assert.Contains(t, sidecar, "# size: 12\n")
+
+	buffers["file.sha256"].Reset()
+	buffers["file.sha256"].WriteString(strings.Replace(sidecar, "# size: 12", "# size: 13", 1))
+	_, err := VerifyChecksums(rf, "file", checksum.SHA256)
+	assert.True(t, errors.Is(err, checksum.ErrMismatch), "the size is verified even when the digest matches")
+	_, err = VerifyChecksums(rf, "file", checksum.SHA256, checksum.MD5)
+	assert.True(t, errors.Is(err, checksum.ErrMismatch), "sidecars disagreeing on the size")
+}
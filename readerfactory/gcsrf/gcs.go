@@ -0,0 +1,98 @@
+package gcsrf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+
+	goiterator "github.com/kvanticoss/goutils/v2/iterator"
+	"github.com/kvanticoss/goutils/v2/readerfactory"
+)
+
+// GetGCSReaderFactory returns a readerfactory, pointing to the root of the GCS bucket.
+// Missing objects yield errors wrapping both fs.ErrNotExist and storage.ErrObjectNotExist.
+func GetGCSReaderFactory(ctx context.Context, bucket *storage.BucketHandle) readerfactory.ReaderFactory {
+	return func(path string) (io.ReadCloser, error) {
+		r, err := bucket.Object(path).NewReader(ctx)
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil, fmt.Errorf("%w: %w", fs.ErrNotExist, err)
+		}
+		return r, err
+	}
+}
+
+// GetGCSLister returns a lister of the objects in the GCS bucket
+func GetGCSLister(ctx context.Context, bucket *storage.BucketHandle) readerfactory.Lister {
+	return func(prefix string) goiterator.RecordIterator[readerfactory.ObjectInfo] {
+		query := &storage.Query{Prefix: prefix}
+		if err := query.SetAttrSelection([]string{"Name", "Size", "Updated"}); err != nil {
+			return func() (readerfactory.ObjectInfo, error) { return readerfactory.ObjectInfo{}, err }
+		}
+
+		it := bucket.Objects(ctx, query)
+		return func() (readerfactory.ObjectInfo, error) {
+			attrs, err := it.Next()
+			if err == iterator.Done {
+				return readerfactory.ObjectInfo{}, goiterator.ErrIteratorStop
+			} else if err != nil {
+				return readerfactory.ObjectInfo{}, err
+			}
+			return readerfactory.ObjectInfo{
+				Path:    attrs.Name,
+				Size:    attrs.Size,
+				ModTime: attrs.Updated,
+			}, nil
+		}
+	}
+}
+
+// GetGCSDirLister returns a dir lister of the GCS bucket treating "/" as directory separator
+func GetGCSDirLister(ctx context.Context, bucket *storage.BucketHandle) readerfactory.DirLister {
+	return func(dir string) goiterator.RecordIterator[readerfactory.ObjectInfo] {
+		if dir != "" && !strings.HasSuffix(dir, "/") {
+			dir += "/"
+		}
+		query := &storage.Query{Prefix: dir, Delimiter: "/"}
+		if err := query.SetAttrSelection([]string{"Name", "Size", "Updated"}); err != nil {
+			return func() (readerfactory.ObjectInfo, error) { return readerfactory.ObjectInfo{}, err }
+		}
+
+		// Each page of the response lists its objects before its prefixes; merge them page by page
+		it := bucket.Objects(ctx, query)
+		page := []readerfactory.ObjectInfo{}
+		return func() (readerfactory.ObjectInfo, error) {
+			if len(page) == 0 {
+				for remaining := 1; remaining > 0; remaining-- {
+					attrs, err := it.Next()
+					if err == iterator.Done {
+						break
+					} else if err != nil {
+						return readerfactory.ObjectInfo{}, err
+					}
+					if len(page) == 0 {
+						remaining += it.PageInfo().Remaining() // the rest of the fetched page
+					}
+					if attrs.Prefix != "" {
+						page = append(page, readerfactory.ObjectInfo{Path: attrs.Prefix, IsDir: true})
+					} else {
+						page = append(page, readerfactory.ObjectInfo{Path: attrs.Name, Size: attrs.Size, ModTime: attrs.Updated})
+					}
+				}
+				if len(page) == 0 {
+					return readerfactory.ObjectInfo{}, goiterator.ErrIteratorStop
+				}
+				sort.Slice(page, func(i, j int) bool { return page[i].Path < page[j].Path })
+			}
+			info := page[0]
+			page = page[1:]
+			return info, nil
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package gcsrf
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+
+	"cloud.google.com/go/storage"
+
+	"github.com/kvanticoss/goutils/v2/gcstest"
+	goiterator "github.com/kvanticoss/goutils/v2/iterator"
+	"github.com/kvanticoss/goutils/v2/readerfactory"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func listPaths(t *testing.T, it goiterator.RecordIterator[readerfactory.ObjectInfo]) []string {
+	paths := []string{}
+	for info, err := it(); err != goiterator.ErrIteratorStop; info, err = it() {
+		if !assert.NoError(t, err) {
+			break
+		}
+		paths = append(paths, info.Path)
+	}
+	return paths
+}
+
+func TestGCSReaderFactory(t *testing.T) {
+	server := gcstest.NewServer()
+	defer server.Close()
+	client, err := server.Client(context.Background())
+	assert.NoError(t, err)
+	server.PutObject("bucket", "dir/file.txt", []byte("content"))
+	rf := GetGCSReaderFactory(context.Background(), client.Bucket("bucket"))
+
+	r, err := rf("dir/file.txt")
+	assert.NoError(t, err)
+	content, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "content", string(content))
+	assert.NoError(t, r.Close())
+
+	_, err = rf("missing")
+	assert.True(t, errors.Is(err, fs.ErrNotExist) && errors.Is(err, storage.ErrObjectNotExist))
+}
+
+func TestGCSListers(t *testing.T) {
+	server := gcstest.NewServer()
+	defer server.Close()
+	client, err := server.Client(context.Background())
+	assert.NoError(t, err)
+	server.PageSize = 3 // force paging
+	for _, name := range []string{"a/0/0", "a/1", "a/b/2", "a/c/3", "a/c/4", "a/d", "a/e/5", "b"} {
+		server.PutObject("bucket", name, []byte(name))
+	}
+	bucket := client.Bucket("bucket")
+
+	assert.Equal(t, []string{"a/0/0", "a/1", "a/b/2", "a/c/3", "a/c/4", "a/d", "a/e/5"}, listPaths(t, GetGCSLister(context.Background(), bucket)("a/")))
+	assert.Equal(t, []string{"a/0/", "a/1", "a/b/", "a/c/", "a/d", "a/e/"}, listPaths(t, GetGCSDirLister(context.Background(), bucket)("a")))
+	assert.Equal(t, []string{"a/", "b"}, listPaths(t, GetGCSDirLister(context.Background(), bucket)("")))
+}
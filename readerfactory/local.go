@@ -0,0 +1,145 @@
+package readerfactory
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kvanticoss/goutils/v2/iterator"
+	"github.com/kvanticoss/goutils/v2/writerfactory"
+)
+
+// GetLocalReaderFactory returns a reader factory which opens local files in the basePath.
+// Paths are sanitized as by writerfactory.GetLocalWriterFactory.
+// If basePath == "" it defaults to current working dir ("./")
+func GetLocalReaderFactory(basePath string) ReaderFactory {
+	return func(path string) (io.ReadCloser, error) {
+		fullPath, err := writerfactory.JoinLocalPath(basePath, path)
+		if err != nil {
+			return nil, err
+		}
+		return os.Open(fullPath)
+	}
+}
+
+// GetLocalLister returns a lister of the regular files in the basePath; paths are relative to basePath.
+// Prefixes are sanitized as the paths of GetLocalReaderFactory; prefixes escaping basePath yield an error.
+// If basePath == "" it defaults to current working dir ("./")
+func GetLocalLister(basePath string) Lister {
+	basePath = localBase(basePath)
+	return func(prefix string) iterator.RecordIterator[ObjectInfo] {
+		prefix, err := localPrefix(prefix)
+		if err != nil {
+			return sliceIterator(nil, err)
+		}
+		full := filepath.ToSlash(basePath + prefix)
+
+		// Walk the deepest directory that contains all candidates
+		rootRaw := full[:strings.LastIndex(full, "/")+1]
+		root := rootRaw
+		if root == "" {
+			root = "."
+		}
+
+		objects := []ObjectInfo{}
+		err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+			name := rootRaw + filepath.ToSlash(rel)
+			if !strings.HasPrefix(name, full) {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			objects = append(objects, ObjectInfo{
+				Path:    strings.TrimPrefix(name, filepath.ToSlash(basePath)),
+				Size:    info.Size(),
+				ModTime: info.ModTime(),
+			})
+			return nil
+		})
+
+		sort.Slice(objects, func(i, j int) bool { return objects[i].Path < objects[j].Path })
+		return sliceIterator(objects, err)
+	}
+}
+
+// GetLocalDirLister returns a dir lister of the local directories in the basePath; paths are relative to basePath.
+// Directories are sanitized as the paths of GetLocalReaderFactory; directories escaping basePath yield an error.
+// If basePath == "" it defaults to current working dir ("./")
+func GetLocalDirLister(basePath string) DirLister {
+	basePath = localBase(basePath)
+	return func(dir string) iterator.RecordIterator[ObjectInfo] {
+		dir, err := localPrefix(dir)
+		if err != nil {
+			return sliceIterator(nil, err)
+		}
+		dir = dirPrefix(dir)
+		entries, err := os.ReadDir(basePath + dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			return sliceIterator(nil, nil)
+		} else if err != nil {
+			return sliceIterator(nil, err)
+		}
+
+		objects := []ObjectInfo{}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				objects = append(objects, ObjectInfo{Path: dir + entry.Name() + "/", IsDir: true})
+				continue
+			}
+			if !entry.Type().IsRegular() {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return sliceIterator(objects, err)
+			}
+			objects = append(objects, ObjectInfo{Path: dir + entry.Name(), Size: info.Size(), ModTime: info.ModTime()})
+		}
+		sort.Slice(objects, func(i, j int) bool { return objects[i].Path < objects[j].Path })
+		return sliceIterator(objects, nil)
+	}
+}
+
+// localBase defaults basePath to "./" and ensures it ends with "/" so it's treated as a directory
+func localBase(basePath string) string {
+	if basePath == "" {
+		return "./"
+	}
+	return dirPrefix(filepath.ToSlash(basePath))
+}
+
+// localPrefix cleans a (partial) path relative to the basePath; a trailing "/" is kept and "" lists everything
+func localPrefix(prefix string) (string, error) {
+	if prefix == "" {
+		return "", nil
+	}
+	cleaned, err := writerfactory.SanitizePath(prefix, writerfactory.LocalPathRules)
+	if errors.Is(err, writerfactory.ErrEmptyPath) { // e.g. "./"
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	if strings.HasSuffix(prefix, "/") {
+		cleaned += "/"
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,91 @@
+package readerfactory
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+
+	"github.com/kvanticoss/goutils/v2/iterator"
+	"github.com/kvanticoss/goutils/v2/writerfactory"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalRoundTrip(t *testing.T) {
+	basePath := t.TempDir() + "/"
+	wf := writerfactory.GetLocalWriterFactory(basePath)
+	rf, lister := GetLocalReaderFactory(basePath), GetLocalLister(basePath)
+
+	write(t, wf.WithGzip(), "x/a-c", "1")
+	write(t, wf, "x/a/b", "22")
+	write(t, wf, "y", "333")
+
+	assert.Equal(t, []string{"x/a-c.gz", "x/a/b", "y"}, listPaths(t, lister("")))
+	assert.Equal(t, []string{"x/a-c.gz", "x/a/b"}, listPaths(t, lister("x/a")))
+	assert.Equal(t, []string{"x/a/b"}, listPaths(t, lister("x/a/")))
+	assert.Equal(t, []string{}, listPaths(t, lister("missing/dir/")))
+
+	it := lister.WithPrefix("x")("a/")
+	info, err := it()
+	assert.NoError(t, err)
+	assert.Equal(t, "a/b", info.Path)
+	assert.Equal(t, int64(2), info.Size)
+	assert.False(t, info.ModTime.IsZero())
+	_, err = it()
+	assert.Equal(t, iterator.ErrIteratorStop, err)
+
+	assert.Equal(t, "22", read(t, rf.WithPrefix("x"), "a/b"))
+	assert.Equal(t, "1", read(t, rf.WithGzip(), "x/a-c"))
+	_, err = rf("missing")
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+}
+
+func TestLocalDirLister(t *testing.T) {
+	basePath := t.TempDir() + "/"
+	wf := writerfactory.GetLocalWriterFactory(basePath)
+	write(t, wf, "x/a/b", "22")
+	write(t, wf, "x/c", "1")
+
+	dl := GetLocalDirLister(basePath)
+	assert.Equal(t, []string{"x/"}, listPaths(t, dl("")))
+	assert.Equal(t, []string{"x/a/", "x/c"}, listPaths(t, dl("x")))
+	assert.Equal(t, []string{"a/b"}, listPaths(t, dl.WithPrefix("x")("a/")))
+	assert.Equal(t, []string{}, listPaths(t, dl("missing/")))
+}
+
+func TestLocalBasePathWithoutTrailingSlash(t *testing.T) {
+	basePath := t.TempDir() + "/base"
+	wf := writerfactory.GetLocalWriterFactory(basePath)
+	rf, lister, dl := GetLocalReaderFactory(basePath), GetLocalLister(basePath), GetLocalDirLister(basePath)
+
+	write(t, wf, "x/a", "1")
+	assert.Equal(t, []string{"x/a"}, listPaths(t, lister("")))
+	assert.Equal(t, []string{"x/"}, listPaths(t, dl("")))
+	assert.Equal(t, "1", read(t, rf, "x/a"))
+
+	_, err := rf("../base/x/a")
+	assert.True(t, errors.Is(err, writerfactory.ErrPathTraversal))
+}
+
+func TestLocalListerPathTraversal(t *testing.T) {
+	root := t.TempDir()
+	basePath := root + "/base/"
+	write(t, writerfactory.GetLocalWriterFactory(root), "secret.txt", "secret")
+	write(t, writerfactory.GetLocalWriterFactory(basePath), "a/file", "1")
+
+	lister, dl := GetLocalLister(basePath), GetLocalDirLister(basePath)
+	for _, prefix := range []string{"../", "a/../../", "..", "/etc/"} {
+		_, err := lister(prefix)()
+		assert.Error(t, err, prefix)
+		assert.NotEqual(t, iterator.ErrIteratorStop, err, prefix)
+
+		_, err = dl(prefix)()
+		assert.Error(t, err, prefix)
+		assert.NotEqual(t, iterator.ErrIteratorStop, err, prefix)
+	}
+	_, err := lister("a/../../")()
+	assert.True(t, errors.Is(err, writerfactory.ErrPathTraversal))
+
+	assert.Equal(t, []string{"a/file"}, listPaths(t, lister("./a//")))
+	assert.Equal(t, []string{"a/file"}, listPaths(t, dl("a/./")))
+}
@@ -0,0 +1,84 @@
+package readerfactory
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/kvanticoss/goutils/v2/iterator"
+)
+
+// GetMemoryReaderFactory returns a reader factory reading from the buffers of writerfactory.GetMemoryWriterFactory.
+// Reading doesn't consume the buffers.
+func GetMemoryReaderFactory(buffers map[string]*bytes.Buffer) ReaderFactory {
+	return func(path string) (io.ReadCloser, error) {
+		buf, ok := buffers[path]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", fs.ErrNotExist, path)
+		}
+		return io.NopCloser(bytes.NewReader(buf.Bytes())), nil
+	}
+}
+
+// GetMemoryLister returns a lister of the buffers of writerfactory.GetMemoryWriterFactory; ModTime is always zero.
+func GetMemoryLister(buffers map[string]*bytes.Buffer) Lister {
+	return func(prefix string) iterator.RecordIterator[ObjectInfo] {
+		return ListObjects(memoryObjects(buffers), prefix)
+	}
+}
+
+// GetMemoryDirLister returns a dir lister of the buffers of writerfactory.GetMemoryWriterFactory treating "/" as
+// directory separator; ModTime is always zero.
+func GetMemoryDirLister(buffers map[string]*bytes.Buffer) DirLister {
+	return func(dir string) iterator.RecordIterator[ObjectInfo] {
+		return ListDir(memoryObjects(buffers), dir)
+	}
+}
+
+func memoryObjects(buffers map[string]*bytes.Buffer) []ObjectInfo {
+	objects := make([]ObjectInfo, 0, len(buffers))
+	for path, buf := range buffers {
+		objects = append(objects, ObjectInfo{Path: path, Size: int64(buf.Len())})
+	}
+	return objects
+}
+
+// ListObjects yields the objects whose path starts with prefix in lexicographic order; implementing a Lister over
+// an in-memory set of objects
+func ListObjects(objects []ObjectInfo, prefix string) iterator.RecordIterator[ObjectInfo] {
+	matching := []ObjectInfo{}
+	for _, object := range objects {
+		if strings.HasPrefix(object.Path, prefix) {
+			matching = append(matching, object)
+		}
+	}
+	sort.Slice(matching, func(i, j int) bool { return matching[i].Path < matching[j].Path })
+	return sliceIterator(matching, nil)
+}
+
+// ListDir yields the immediate children of dir among the objects treating "/" as directory separator, in
+// lexicographic order; implementing a DirLister over an in-memory set of objects
+func ListDir(objects []ObjectInfo, dir string) iterator.RecordIterator[ObjectInfo] {
+	dir = dirPrefix(dir)
+	children := []ObjectInfo{}
+	dirs := map[string]bool{}
+	for _, object := range objects {
+		if !strings.HasPrefix(object.Path, dir) {
+			continue
+		}
+		if index := strings.Index(object.Path[len(dir):], "/"); index >= 0 {
+			subDir := object.Path[:len(dir)+index+1]
+			if !dirs[subDir] {
+				dirs[subDir] = true
+				children = append(children, ObjectInfo{Path: subDir, IsDir: true})
+			}
+			continue
+		}
+		children = append(children, object)
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].Path < children[j].Path })
+	return sliceIterator(children, nil)
+}
@@ -0,0 +1,125 @@
+package readerfactory
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+
+	"github.com/kvanticoss/goutils/v2/encryption"
+	"github.com/kvanticoss/goutils/v2/iterator"
+	"github.com/kvanticoss/goutils/v2/writerfactory"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func listPaths(t *testing.T, it iterator.RecordIterator[ObjectInfo]) []string {
+	paths := []string{}
+	for info, err := it(); err != iterator.ErrIteratorStop; info, err = it() {
+		if !assert.NoError(t, err) {
+			break
+		}
+		paths = append(paths, info.Path)
+	}
+	return paths
+}
+
+func write(t *testing.T, wf writerfactory.WriterFactory, path, content string) {
+	w, err := wf(path)
+	assert.NoError(t, err)
+	_, err = w.Write([]byte(content))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+}
+
+func read(t *testing.T, rf ReaderFactory, path string) string {
+	r, err := rf(path)
+	if !assert.NoError(t, err) {
+		return ""
+	}
+	content, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.NoError(t, r.Close())
+	return string(content)
+}
+
+func TestMemoryRoundTripWithDecorators(t *testing.T) {
+	buffers, wf := writerfactory.GetMemoryWriterFactory()
+	rf, lister := GetMemoryReaderFactory(buffers), GetMemoryLister(buffers)
+
+	write(t, wf.WithPrefix("data").WithGzip(), "b/2", "two")
+	write(t, wf.WithPrefix("data").WithGzip(), "a/1", "one")
+	write(t, wf.WithPrefix("data"), "plain", "not gzipped")
+	write(t, wf, "data2/other", "outside the prefix")
+
+	assert.Equal(t, []string{"data/a/1.gz", "data/b/2.gz", "data/plain", "data2/other"}, listPaths(t, lister("")))
+	assert.Equal(t, []string{"data/a/1.gz", "data/b/2.gz", "data/plain", "data2/other"}, listPaths(t, lister("data")))
+
+	decoratedLister := lister.WithPrefix("data").WithGzip()
+	decoratedRF := rf.WithPrefix("data").WithGzip()
+	assert.Equal(t, []string{"a/1", "b/2"}, listPaths(t, decoratedLister("")))
+	assert.Equal(t, []string{"b/2"}, listPaths(t, decoratedLister("b/")))
+	assert.Equal(t, "one", read(t, decoratedRF, "a/1"))
+	assert.Equal(t, "two", read(t, decoratedRF, "b/2"))
+	assert.Equal(t, "not gzipped", read(t, rf.WithPrefix("data"), "plain"))
+	assert.Equal(t, "two", read(t, decoratedRF, "b/2"), "reading doesn't consume the buffer")
+
+	_, err := rf("missing")
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+	_, err = decoratedRF("plain")
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+}
+
+func TestMemoryDirLister(t *testing.T) {
+	buffers, wf := writerfactory.GetMemoryWriterFactory()
+	write(t, wf.WithGzip(), "data/date=2023-01-01/part_0", "1")
+	write(t, wf.WithGzip(), "data/date=2023-01-02/part_0", "2")
+	write(t, wf, "data/_SUCCESS", "")
+	write(t, wf, "other", "")
+
+	dl := GetMemoryDirLister(buffers)
+	assert.Equal(t, []string{"data/", "other"}, listPaths(t, dl("")))
+	assert.Equal(t, []string{"data/_SUCCESS", "data/date=2023-01-01/", "data/date=2023-01-02/"}, listPaths(t, dl("data")))
+
+	decorated := dl.WithPrefix("data").WithGzip()
+	assert.Equal(t, []string{"date=2023-01-01/", "date=2023-01-02/"}, listPaths(t, decorated("")))
+	assert.Equal(t, []string{"date=2023-01-02/part_0"}, listPaths(t, decorated("date=2023-01-02/")))
+}
+
+func TestMemoryRoundTripWithZstdAndSnappy(t *testing.T) {
+	buffers, wf := writerfactory.GetMemoryWriterFactory()
+	rf, lister, dirLister := GetMemoryReaderFactory(buffers), GetMemoryLister(buffers), GetMemoryDirLister(buffers)
+
+	write(t, wf.WithZstd(), "a/1", "zstd")
+	write(t, wf.WithSnappy(), "a/2", "snappy")
+	write(t, wf.WithGzip(), "a/3", "gzip")
+
+	assert.Equal(t, []string{"a/1"}, listPaths(t, lister.WithZstd()("")))
+	assert.Equal(t, []string{"a/2"}, listPaths(t, lister.WithSnappy()("")))
+	assert.Equal(t, []string{"a/1"}, listPaths(t, dirLister.WithZstd()("a/")))
+	assert.Equal(t, []string{"a/"}, listPaths(t, dirLister.WithSnappy()("")))
+
+	assert.Equal(t, "zstd", read(t, rf.WithZstd(), "a/1"))
+	assert.Equal(t, "snappy", read(t, rf.WithSnappy(), "a/2.sz"))
+
+	_, err := rf.WithZstd()("a/3")
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+}
+
+func TestMemoryRoundTripWithEncryption(t *testing.T) {
+	buffers, wf := writerfactory.GetMemoryWriterFactory()
+	rf, lister := GetMemoryReaderFactory(buffers), GetMemoryLister(buffers)
+	keys := encryption.NewStaticKeyProvider("test", bytes.Repeat([]byte{1}, 32))
+
+	write(t, wf.WithEncryption(keys).WithGzip(), "a/1", "secret")
+	write(t, wf.WithGzip(), "a/2", "not secret")
+
+	assert.Equal(t, []string{"a/1.gz.enc", "a/2.gz"}, listPaths(t, lister("")))
+	assert.NotContains(t, buffers["a/1.gz.enc"].String(), "secret")
+	assert.Equal(t, []string{"a/1.gz"}, listPaths(t, lister.WithEncryption()("")))
+	assert.Equal(t, "secret", read(t, rf.WithEncryption(keys).WithGzip(), "a/1"))
+
+	_, err := rf.WithEncryption(encryption.NewStaticKeyProvider("other", bytes.Repeat([]byte{1}, 32)))("a/1.gz")
+	assert.True(t, errors.Is(err, encryption.ErrUnknownKey))
+}
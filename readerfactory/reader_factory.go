@@ -0,0 +1,57 @@
+// Package readerfactory is the read side of writerfactory; a ReaderFactory opens paths and a Lister finds the
+// paths under a prefix. Both are decorated with the same WithPrefix/WithGzip semantics as the WriterFactory so
+// that anything written through a decorated WriterFactory can be listed and read back through equally decorated
+// Listers and ReaderFactories.
+package readerfactory
+
+import (
+	"io"
+	"strings"
+	"time"
+
+	"github.com/kvanticoss/goutils/v2/iterator"
+)
+
+// ReaderFactory should yield a new ReadCloser for the given path. Missing paths yield errors wrapping fs.ErrNotExist.
+type ReaderFactory func(path string) (rc io.ReadCloser, err error)
+
+// ObjectInfo describes a listed object
+type ObjectInfo struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+	// IsDir is set for the directories yielded by a DirLister; their Path ends with "/"
+	IsDir bool
+}
+
+// Lister yields all objects whose path starts with the prefix (a plain string prefix; not necessarily a directory)
+// in lexicographic order of their paths.
+type Lister func(prefix string) iterator.RecordIterator[ObjectInfo]
+
+// DirLister yields the immediate children of the directory dir ("" is the root); objects as well as
+// sub directories (IsDir) in lexicographic order of their paths. Paths are given relative to the root, not to dir.
+// Listing directory by directory allows callers to skip entire sub trees.
+type DirLister func(dir string) iterator.RecordIterator[ObjectInfo]
+
+// dirPrefix ensures non root directories end with "/"
+func dirPrefix(dir string) string {
+	if dir != "" && !strings.HasSuffix(dir, "/") {
+		return dir + "/"
+	}
+	return dir
+}
+
+// sliceIterator yields the objects followed by err (iterator.ErrIteratorStop if nil)
+func sliceIterator(objects []ObjectInfo, err error) iterator.RecordIterator[ObjectInfo] {
+	index := 0
+	return func() (ObjectInfo, error) {
+		if index >= len(objects) {
+			if err != nil {
+				return ObjectInfo{}, err
+			}
+			return ObjectInfo{}, iterator.ErrIteratorStop
+		}
+		index++
+		return objects[index-1], nil
+	}
+}
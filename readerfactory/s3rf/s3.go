@@ -0,0 +1,67 @@
+package s3rf
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/kvanticoss/goutils/v2/iterator"
+	"github.com/kvanticoss/goutils/v2/readerfactory"
+	"github.com/kvanticoss/goutils/v2/s3"
+)
+
+// GetS3ReaderFactory returns a readerfactory, pointing to the root of the S3 bucket.
+// Missing objects yield errors wrapping fs.ErrNotExist (as well as being an *s3.Error).
+func GetS3ReaderFactory(ctx context.Context, bucket *s3.Bucket) readerfactory.ReaderFactory {
+	return func(path string) (io.ReadCloser, error) {
+		return bucket.GetObject(ctx, path)
+	}
+}
+
+// GetS3Lister returns a lister of the objects in the S3 bucket
+func GetS3Lister(ctx context.Context, bucket *s3.Bucket) readerfactory.Lister {
+	return func(prefix string) iterator.RecordIterator[readerfactory.ObjectInfo] {
+		return list(ctx, bucket, prefix, "")
+	}
+}
+
+// GetS3DirLister returns a dir lister of the S3 bucket treating "/" as directory separator
+func GetS3DirLister(ctx context.Context, bucket *s3.Bucket) readerfactory.DirLister {
+	return func(dir string) iterator.RecordIterator[readerfactory.ObjectInfo] {
+		if dir != "" && !strings.HasSuffix(dir, "/") {
+			dir += "/"
+		}
+		return list(ctx, bucket, dir, "/")
+	}
+}
+
+// list pages through the listing; objects and common prefixes (directories) of each page are merged in path order
+func list(ctx context.Context, bucket *s3.Bucket, prefix, delimiter string) iterator.RecordIterator[readerfactory.ObjectInfo] {
+	page := []readerfactory.ObjectInfo{}
+	token := ""
+	more := true
+	return func() (readerfactory.ObjectInfo, error) {
+		for len(page) == 0 {
+			if !more {
+				return readerfactory.ObjectInfo{}, iterator.ErrIteratorStop
+			}
+			result, err := bucket.ListObjects(ctx, prefix, delimiter, token)
+			if err != nil {
+				more = false
+				return readerfactory.ObjectInfo{}, err
+			}
+			for _, object := range result.Objects {
+				page = append(page, readerfactory.ObjectInfo{Path: object.Key, Size: object.Size, ModTime: object.LastModified})
+			}
+			for _, dir := range result.CommonPrefixes {
+				page = append(page, readerfactory.ObjectInfo{Path: dir, IsDir: true})
+			}
+			sort.Slice(page, func(i, j int) bool { return page[i].Path < page[j].Path })
+			token, more = result.NextContinuationToken, result.IsTruncated
+		}
+		info := page[0]
+		page = page[1:]
+		return info, nil
+	}
+}
@@ -0,0 +1,56 @@
+package s3rf
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"testing"
+
+	"github.com/kvanticoss/goutils/v2/iterator"
+	"github.com/kvanticoss/goutils/v2/readerfactory"
+	"github.com/kvanticoss/goutils/v2/s3/s3test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func listPaths(t *testing.T, it iterator.RecordIterator[readerfactory.ObjectInfo]) []string {
+	paths := []string{}
+	for info, err := it(); err != iterator.ErrIteratorStop; info, err = it() {
+		if !assert.NoError(t, err) {
+			break
+		}
+		paths = append(paths, info.Path)
+	}
+	return paths
+}
+
+func TestS3ReaderFactory(t *testing.T) {
+	server := s3test.NewServer()
+	defer server.Close()
+	server.PutObject("bucket", "dir/file.txt", []byte("content"))
+	rf := GetS3ReaderFactory(context.Background(), server.Bucket("bucket"))
+
+	r, err := rf("dir/file.txt")
+	assert.NoError(t, err)
+	content, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "content", string(content))
+	assert.NoError(t, r.Close())
+
+	_, err = rf("missing")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestS3Listers(t *testing.T) {
+	server := s3test.NewServer()
+	defer server.Close()
+	server.MaxKeys = 2 // force paging
+	for _, key := range []string{"a/1", "a/2", "a/b/3", "a/c/4", "a/c/5", "a/d", "b"} {
+		server.PutObject("bucket", key, []byte(key))
+	}
+	bucket := server.Bucket("bucket")
+
+	assert.Equal(t, []string{"a/1", "a/2", "a/b/3", "a/c/4", "a/c/5", "a/d"}, listPaths(t, GetS3Lister(context.Background(), bucket)("a/")))
+	assert.Equal(t, []string{"a/1", "a/2", "a/b/", "a/c/", "a/d"}, listPaths(t, GetS3DirLister(context.Background(), bucket)("a")))
+	assert.Equal(t, []string{"a/", "b"}, listPaths(t, GetS3DirLister(context.Background(), bucket)("")))
+}
@@ -0,0 +1,60 @@
+package readerfactory
+
+import (
+	"io"
+	"strings"
+
+	"github.com/kvanticoss/goutils/v2/encryption"
+	"github.com/kvanticoss/goutils/v2/iterator"
+)
+
+// WithEncryption decrypts the readers returned by the underlying ReaderFactory with the keys; ".enc" is appended to
+// paths without it, mirroring writerfactory.WithEncryption. Reads fail if the content has been tampered with or
+// truncated.
+func WithEncryption(rf ReaderFactory, keys encryption.KeyProvider) ReaderFactory {
+	return func(path string) (io.ReadCloser, error) {
+		if !strings.HasSuffix(path, ".enc") {
+			path = path + ".enc"
+		}
+
+		r, err := rf(path)
+		if err != nil {
+			return nil, err
+		}
+		er, err := encryption.NewReader(r, keys)
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		return er, nil
+	}
+}
+
+// WithEncryption decrypts the readers returned by the underlying ReaderFactory; see WithEncryption
+func (rf ReaderFactory) WithEncryption(keys encryption.KeyProvider) ReaderFactory {
+	return WithEncryption(rf, keys)
+}
+
+// WrapListerWithEncryption only yields ".enc" objects and strips the suffix from their paths
+func WrapListerWithEncryption(l Lister) Lister {
+	return func(prefix string) iterator.RecordIterator[ObjectInfo] {
+		return onlySuffix(l(prefix), ".enc")
+	}
+}
+
+// WithEncryption only yields ".enc" objects; see WrapListerWithEncryption
+func (l Lister) WithEncryption() Lister {
+	return WrapListerWithEncryption(l)
+}
+
+// WrapDirListerWithEncryption only yields directories and ".enc" objects, with the suffix stripped from their paths
+func WrapDirListerWithEncryption(dl DirLister) DirLister {
+	return func(dir string) iterator.RecordIterator[ObjectInfo] {
+		return onlySuffix(dl(dir), ".enc")
+	}
+}
+
+// WithEncryption only yields directories and ".enc" objects; see WrapDirListerWithEncryption
+func (dl DirLister) WithEncryption() DirLister {
+	return WrapDirListerWithEncryption(dl)
+}
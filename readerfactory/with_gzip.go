@@ -0,0 +1,76 @@
+package readerfactory
+
+import (
+	"io"
+	"strings"
+
+	"github.com/kvanticoss/goutils/v2/gzip"
+	"github.com/kvanticoss/goutils/v2/iterator"
+)
+
+// WithGzip decompresses the readers returned by the underlying ReaderFactory; ".gz" is appended to
+// paths without it, mirroring writerfactory.WithGzip
+func WithGzip(rf ReaderFactory) ReaderFactory {
+	return func(path string) (io.ReadCloser, error) {
+		if !strings.HasSuffix(path, ".gz") {
+			path = path + ".gz"
+		}
+
+		r, err := rf(path)
+		if err != nil {
+			return nil, err
+		}
+		gzReader, err := gzip.NewReader(r)
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		return gzReader, nil
+	}
+}
+
+// WithGzip decompresses the readers returned by the underlying ReaderFactory
+func (rf ReaderFactory) WithGzip() ReaderFactory {
+	return WithGzip(rf)
+}
+
+// WrapListerWithGzip only yields ".gz" objects and strips the suffix from their paths; i.e. the paths as
+// they were given to a writerfactory.WithGzip WriterFactory. Sizes are the compressed sizes.
+func WrapListerWithGzip(l Lister) Lister {
+	return func(prefix string) iterator.RecordIterator[ObjectInfo] {
+		return onlySuffix(l(prefix), ".gz")
+	}
+}
+
+// WithGzip only yields ".gz" objects; see WrapListerWithGzip
+func (l Lister) WithGzip() Lister {
+	return WrapListerWithGzip(l)
+}
+
+// WrapDirListerWithGzip only yields directories and ".gz" objects, with the suffix stripped from their paths
+func WrapDirListerWithGzip(dl DirLister) DirLister {
+	return func(dir string) iterator.RecordIterator[ObjectInfo] {
+		return onlySuffix(dl(dir), ".gz")
+	}
+}
+
+// WithGzip only yields directories and ".gz" objects; see WrapDirListerWithGzip
+func (dl DirLister) WithGzip() DirLister {
+	return WrapDirListerWithGzip(dl)
+}
+
+// onlySuffix only yields directories and objects with the suffix, which is stripped from their paths
+func onlySuffix(it iterator.RecordIterator[ObjectInfo], suffix string) iterator.RecordIterator[ObjectInfo] {
+	return func() (ObjectInfo, error) {
+		for {
+			info, err := it()
+			if err != nil || info.IsDir {
+				return info, err
+			}
+			if strings.HasSuffix(info.Path, suffix) {
+				info.Path = strings.TrimSuffix(info.Path, suffix)
+				return info, nil
+			}
+		}
+	}
+}
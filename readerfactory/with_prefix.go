@@ -0,0 +1,72 @@
+package readerfactory
+
+import (
+	"io"
+	"path"
+	"strings"
+
+	"github.com/kvanticoss/goutils/v2/iterator"
+)
+
+// WrapRFWithPrefix adds a path prefix to all reads conducted by the reader factory
+func WrapRFWithPrefix(rf ReaderFactory, prefix string) ReaderFactory {
+	return func(readPath string) (io.ReadCloser, error) {
+		return rf(path.Join(prefix, readPath))
+	}
+}
+
+// WithPrefix adds a path prefix to all reads conducted by the reader factory
+func (rf ReaderFactory) WithPrefix(prefix string) ReaderFactory {
+	return WrapRFWithPrefix(rf, prefix)
+}
+
+// WrapListerWithPrefix lists objects under the prefix "directory"; the yielded paths are relative to it so
+// they can be opened with a ReaderFactory decorated with the same prefix.
+func WrapListerWithPrefix(l Lister, prefix string) Lister {
+	dir := path.Clean(prefix) + "/"
+	if prefix == "" || dir == "./" {
+		return l
+	}
+	return func(listPrefix string) iterator.RecordIterator[ObjectInfo] {
+		return trimPathPrefix(l(dir+strings.TrimPrefix(listPrefix, "/")), dir)
+	}
+}
+
+// WithPrefix lists objects under the prefix "directory"; see WrapListerWithPrefix
+func (l Lister) WithPrefix(prefix string) Lister {
+	return WrapListerWithPrefix(l, prefix)
+}
+
+// WrapDirListerWithPrefix lists directories under the prefix "directory"; the yielded paths are relative to it so
+// they can be opened with a ReaderFactory decorated with the same prefix.
+func WrapDirListerWithPrefix(dl DirLister, prefix string) DirLister {
+	dir := path.Clean(prefix) + "/"
+	if prefix == "" || dir == "./" {
+		return dl
+	}
+	return func(listDir string) iterator.RecordIterator[ObjectInfo] {
+		return trimPathPrefix(dl(dir+strings.TrimPrefix(dirPrefix(listDir), "/")), dir)
+	}
+}
+
+// WithPrefix lists directories under the prefix "directory"; see WrapDirListerWithPrefix
+func (dl DirLister) WithPrefix(prefix string) DirLister {
+	return WrapDirListerWithPrefix(dl, prefix)
+}
+
+// trimPathPrefix removes dir from the paths yielded by it
+func trimPathPrefix(it iterator.RecordIterator[ObjectInfo], dir string) iterator.RecordIterator[ObjectInfo] {
+	return func() (ObjectInfo, error) {
+		for {
+			info, err := it()
+			if err != nil {
+				return info, err
+			}
+			// path.Join in the writerfactory cleans paths; skip anything it couldn't have written
+			if rel := strings.TrimPrefix(info.Path, dir); rel != info.Path && rel != "" {
+				info.Path = rel
+				return info, nil
+			}
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package readerfactory
+
+import (
+	"io"
+	"strings"
+
+	"github.com/kvanticoss/goutils/v2/iterator"
+	"github.com/kvanticoss/goutils/v2/snappy"
+)
+
+// WithSnappy decompresses the (framed snappy) readers returned by the underlying ReaderFactory; ".sz" is appended
+// to paths without it, mirroring writerfactory.WithSnappy
+func WithSnappy(rf ReaderFactory) ReaderFactory {
+	return func(path string) (io.ReadCloser, error) {
+		if !strings.HasSuffix(path, ".sz") {
+			path = path + ".sz"
+		}
+
+		r, err := rf(path)
+		if err != nil {
+			return nil, err
+		}
+		return snappy.NewReader(r), nil
+	}
+}
+
+// WithSnappy decompresses the readers returned by the underlying ReaderFactory
+func (rf ReaderFactory) WithSnappy() ReaderFactory {
+	return WithSnappy(rf)
+}
+
+// WrapListerWithSnappy only yields ".sz" objects and strips the suffix from their paths
+func WrapListerWithSnappy(l Lister) Lister {
+	return func(prefix string) iterator.RecordIterator[ObjectInfo] {
+		return onlySuffix(l(prefix), ".sz")
+	}
+}
+
+// WithSnappy only yields ".sz" objects; see WrapListerWithSnappy
+func (l Lister) WithSnappy() Lister {
+	return WrapListerWithSnappy(l)
+}
+
+// WrapDirListerWithSnappy only yields directories and ".sz" objects, with the suffix stripped from their paths
+func WrapDirListerWithSnappy(dl DirLister) DirLister {
+	return func(dir string) iterator.RecordIterator[ObjectInfo] {
+		return onlySuffix(dl(dir), ".sz")
+	}
+}
+
+// WithSnappy only yields directories and ".sz" objects; see WrapDirListerWithSnappy
+func (dl DirLister) WithSnappy() DirLister {
+	return WrapDirListerWithSnappy(dl)
+}
@@ -0,0 +1,59 @@
+package readerfactory
+
+import (
+	"io"
+	"strings"
+
+	"github.com/kvanticoss/goutils/v2/iterator"
+	"github.com/kvanticoss/goutils/v2/zstd"
+)
+
+// WithZstd decompresses the readers returned by the underlying ReaderFactory; ".zst" is appended to
+// paths without it, mirroring writerfactory.WithZstd
+func WithZstd(rf ReaderFactory) ReaderFactory {
+	return func(path string) (io.ReadCloser, error) {
+		if !strings.HasSuffix(path, ".zst") {
+			path = path + ".zst"
+		}
+
+		r, err := rf(path)
+		if err != nil {
+			return nil, err
+		}
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		return zr, nil
+	}
+}
+
+// WithZstd decompresses the readers returned by the underlying ReaderFactory
+func (rf ReaderFactory) WithZstd() ReaderFactory {
+	return WithZstd(rf)
+}
+
+// WrapListerWithZstd only yields ".zst" objects and strips the suffix from their paths
+func WrapListerWithZstd(l Lister) Lister {
+	return func(prefix string) iterator.RecordIterator[ObjectInfo] {
+		return onlySuffix(l(prefix), ".zst")
+	}
+}
+
+// WithZstd only yields ".zst" objects; see WrapListerWithZstd
+func (l Lister) WithZstd() Lister {
+	return WrapListerWithZstd(l)
+}
+
+// WrapDirListerWithZstd only yields directories and ".zst" objects, with the suffix stripped from their paths
+func WrapDirListerWithZstd(dl DirLister) DirLister {
+	return func(dir string) iterator.RecordIterator[ObjectInfo] {
+		return onlySuffix(dl(dir), ".zst")
+	}
+}
+
+// WithZstd only yields directories and ".zst" objects; see WrapDirListerWithZstd
+func (dl DirLister) WithZstd() DirLister {
+	return WrapDirListerWithZstd(dl)
+}
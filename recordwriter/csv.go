@@ -0,0 +1,111 @@
+package recordwriter
+
+import (
+	"io"
+	"os"
+
+	"github.com/kvanticoss/goutils/v2/codec"
+	"github.com/kvanticoss/goutils/v2/iterator"
+	"github.com/kvanticoss/goutils/v2/writerfactory"
+)
+
+// ErrUnknownCSVColumn is returned when a record contains a column that isn't part of the CSV header
+var ErrUnknownCSVColumn = codec.ErrUnknownCSVColumn
+
+// CSVNestedMode controls how nested values (maps and structs) are written to CSV
+type CSVNestedMode = codec.CSVNestedMode
+
+const (
+	// CSVFlatten flattens nested maps and structs into separate columns named parent.child
+	CSVFlatten = codec.CSVFlatten
+	// CSVJSONEncode writes nested maps and structs as a JSON encoded string in a single column
+	CSVJSONEncode = codec.CSVJSONEncode
+)
+
+// DefaultCSVSampleSize is the number of records inspected to derive the header when no columns are provided
+const DefaultCSVSampleSize = codec.DefaultCSVSampleSize
+
+// CSVOptions configures how records are written as CSV. The zero value is usable.
+type CSVOptions = codec.CSVOptions
+
+// CSV writes all the records from the records iterator as CSV, including a header row, to the writer.
+// Struct fields are named by their `csv` tag, falling back to the `json` tag and the field name.
+// Records containing columns not present in the header will yield ErrUnknownCSVColumn.
+// returns the first error from either the record iterator or the csv encoding.
+func CSV[T any](
+	it iterator.RecordIterator[T],
+	w io.Writer,
+	opts CSVOptions,
+) error {
+	return Encode(it, w, codec.NewCSV(opts))
+}
+
+// CSVPartitionedBySize writes all the records from the records iterator as CSV files with a header row in each.
+// The header is derived once (unless provided) so all files share the same columns.
+// if gz is true, the output will be gzipped.
+// records will be put in batches of maxBytesParBatch (uncompressed) bytes into the writer factory
+// with a name of $basename_%06d$index.csv(.gz)
+func CSVPartitionedBySize[T any](
+	it iterator.RecordIterator[T],
+	wf writerfactory.WriterFactory,
+	maxBytesParBatch int,
+	baseName string,
+	gz bool,
+	opts CSVOptions,
+) error {
+	if baseName == "" {
+		baseName, _ = os.Hostname()
+	}
+
+	header, it, err := csvHeader(it, opts)
+	if err != nil {
+		return err
+	}
+	opts.Columns = header
+
+	return PartitionedBySize(it, wf, maxBytesParBatch, baseName, gz, codec.NewCSV(opts))
+}
+
+// csvHeader returns the columns to use and an iterator yielding all records (including any sampled ones)
+func csvHeader[T any](it iterator.RecordIterator[T], opts CSVOptions) ([]string, iterator.RecordIterator[T], error) {
+	if len(opts.Columns) > 0 {
+		return opts.Columns, it, nil
+	}
+
+	sampleSize := opts.SampleSize
+	if sampleSize <= 0 {
+		sampleSize = DefaultCSVSampleSize
+	}
+
+	sampled := []T{}
+	sampledRecords := []interface{}{}
+
+	var record T
+	var err error
+	for record, err = it(); err == nil; record, err = it() {
+		sampled = append(sampled, record)
+		sampledRecords = append(sampledRecords, record)
+		if len(sampled) >= sampleSize {
+			break
+		}
+	}
+	if err != nil && err != iterator.ErrIteratorStop {
+		return nil, nil, err
+	}
+
+	// Replay the sampled records before continuing with the rest of the iterator
+	index := 0
+	resIt := func() (T, error) {
+		if index < len(sampled) {
+			index++
+			return sampled[index-1], nil
+		}
+		if err == iterator.ErrIteratorStop {
+			var empty T
+			return empty, err
+		}
+		return it()
+	}
+
+	return codec.CSVColumns(opts.Nested, sampledRecords...), resIt, nil
+}
@@ -0,0 +1,124 @@
+package recordwriter
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kvanticoss/goutils/v2/iterator"
+	"github.com/kvanticoss/goutils/v2/iterator/test_utils"
+	"github.com/kvanticoss/goutils/v2/writerfactory"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type csvTestInner struct {
+	X int    `json:"x"`
+	Y string `csv:"why"`
+}
+
+type csvTestRecord struct {
+	Name    string        `csv:"name" json:"ignored_name"`
+	Age     int           `json:"age"`
+	Skipped string        `csv:"-"`
+	Tags    []string      `json:"tags"`
+	Inner   csvTestInner  `json:"inner"`
+	Ptr     *csvTestInner `json:"ptr"`
+	When    time.Time
+	hidden  string
+}
+
+func TestCSVStructColumnOrder(t *testing.T) {
+	when := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	it := test_utils.NewDummyIteratorFromArr([]csvTestRecord{
+		{Name: "a", Age: 1, Skipped: "no", Tags: []string{"t1", "t2"}, Inner: csvTestInner{1, "y1"}, When: when, hidden: "no"},
+		{Name: "b, c", Age: 2, Inner: csvTestInner{2, "y2"}, Ptr: &csvTestInner{3, "y3"}, When: when},
+	})
+
+	var buf bytes.Buffer
+	err := CSV(it, &buf, CSVOptions{})
+	assert.Equal(t, iterator.ErrIteratorStop, err)
+	assert.Equal(t, "name,age,tags,inner.x,inner.why,ptr.x,ptr.why,When\n"+
+		`a,1,"[""t1"",""t2""]",1,y1,,,2023-01-02T03:04:05Z`+"\n"+
+		`"b, c",2,,2,y2,3,y3,2023-01-02T03:04:05Z`+"\n",
+		buf.String())
+}
+
+func TestCSVJSONEncodedNested(t *testing.T) {
+	it := test_utils.NewDummyIteratorFromArr([]csvTestRecord{
+		{Name: "a", Inner: csvTestInner{1, "y1"}},
+	})
+
+	var buf bytes.Buffer
+	err := CSV(it, &buf, CSVOptions{Nested: CSVJSONEncode, Comma: ';'})
+	assert.Equal(t, iterator.ErrIteratorStop, err)
+	assert.Equal(t, "name;age;tags;inner;ptr;When\n"+
+		`a;0;;"{""x"":1,""Y"":""y1""}";;0001-01-01T00:00:00Z`+"\n",
+		buf.String())
+}
+
+func TestCSVMapsSampledHeader(t *testing.T) {
+	it := test_utils.NewDummyIteratorFromArr([]map[string]interface{}{
+		{"b": "b1", "a": "a1", "n": map[string]interface{}{"z": 1, "y": 2}},
+		{"c": true, "a": "a2"},
+		{"d": "not in sample"},
+	})
+
+	var buf bytes.Buffer
+	err := CSV(it, &buf, CSVOptions{SampleSize: 2})
+	assert.True(t, errors.Is(err, ErrUnknownCSVColumn), "expected ErrUnknownCSVColumn; got %v", err)
+	assert.Equal(t, "a,b,n.y,n.z,c\n"+
+		"a1,b1,2,1,\n"+
+		"a2,,,,true\n",
+		buf.String())
+}
+
+func TestCSVExplicitColumns(t *testing.T) {
+	it := test_utils.NewDummyIteratorFromArr([]map[string]interface{}{
+		{"b": "b1", "a": "a1"},
+	})
+
+	var buf bytes.Buffer
+	err := CSV(it, &buf, CSVOptions{Columns: []string{"b", "a", "c"}})
+	assert.Equal(t, iterator.ErrIteratorStop, err)
+	assert.Equal(t, "b,a,c\nb1,a1,\n", buf.String())
+}
+
+func TestCSVPartitionedBySize(t *testing.T) {
+	db, wf := writerfactory.GetMemoryWriterFactory()
+	it := test_utils.NewDummyIteratorFromArr([]map[string]interface{}{
+		{"a": "a1", "b": "b1"},
+		{"a": "a2", "b": "b2"},
+		{"a": "a3", "b": "b3"},
+	})
+
+	err := CSVPartitionedBySize(it, wf, 10, "test", false, CSVOptions{})
+	assert.Equal(t, iterator.ErrIteratorStop, err)
+	assert.Len(t, db, 2)
+	assert.Equal(t, "a,b\na1,b1\na2,b2\n", db["test_000000.csv"].String())
+	assert.Equal(t, "a,b\na3,b3\n", db["test_000001.csv"].String())
+}
+
+func TestCSVPartitionedBySizeGzip(t *testing.T) {
+	db, wf := writerfactory.GetMemoryWriterFactory()
+	it := test_utils.NewDummyIteratorFromArr([]map[string]interface{}{
+		{"a": "a1", "b": "b1"},
+	})
+
+	err := CSVPartitionedBySize(it, wf, -1, "test", true, CSVOptions{})
+	assert.Equal(t, iterator.ErrIteratorStop, err)
+
+	s, err := readGzipbuffer(db["test_000000.csv.gz"])
+	assert.NoError(t, err)
+	assert.Equal(t, "a,b\na1,b1\n", s)
+}
+
+func TestCSVPartitionedBySizeWithoutRecords(t *testing.T) {
+	db, wf := writerfactory.GetMemoryWriterFactory()
+	it := test_utils.NewDummyIteratorFromArr([]map[string]interface{}{})
+
+	err := CSVPartitionedBySize(it, wf, 10, "test", false, CSVOptions{Columns: []string{"a", "b"}})
+	assert.Equal(t, iterator.ErrIteratorStop, err)
+	assert.Equal(t, "a,b\n", db["test_000000.csv"].String(), "a header-only file")
+}
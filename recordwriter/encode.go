@@ -0,0 +1,51 @@
+package recordwriter
+
+import (
+	"io"
+
+	"github.com/kvanticoss/goutils/v2/codec"
+	"github.com/kvanticoss/goutils/v2/internal/layered"
+	"github.com/kvanticoss/goutils/v2/iterator"
+	"github.com/kvanticoss/goutils/v2/writerfactory"
+)
+
+// Encode writes all the records from the records iterator to the writer using the codec.
+// returns the first error from either the record iterator or the encoding.
+// If w is a writerfactory.RecordCounter each encoded record is reported to it; encoders buffering records (e.g. CSV
+// and avro) are flushed first so the record boundaries reported are exact.
+func Encode[T any](
+	it iterator.RecordIterator[T],
+	w io.Writer,
+	c codec.Codec,
+) error {
+	var record T
+	var err error
+
+	counter, _ := w.(writerfactory.RecordCounter)
+	enc := c.NewEncoder(w)
+	for record, err = it(); err == nil; record, err = it() {
+		if err := enc.Encode(record); err != nil {
+			enc.Close() // writes the records encoded so far
+			return err
+		}
+		if counter != nil {
+			if err := flushEncoder(enc); err != nil {
+				return err
+			}
+			counter.AddRecords(1)
+		}
+	}
+
+	if err2 := enc.Close(); err2 != nil {
+		return err2
+	}
+	return err
+}
+
+// flushEncoder writes the records buffered by the encoder, if it buffers any
+func flushEncoder(enc codec.Encoder) error {
+	if flusher, ok := enc.(layered.Flusher); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
@@ -1,3 +0,0 @@
-package recordwriter
-
-var jsonRecordDelimiter = []byte("\n")
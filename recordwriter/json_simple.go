@@ -3,9 +3,8 @@ package recordwriter
 import (
 	"io"
 
+	"github.com/kvanticoss/goutils/v2/codec"
 	"github.com/kvanticoss/goutils/v2/iterator"
-
-	jsoniter "github.com/json-iterator/go"
 )
 
 // NewLineJSON writes all the records from the records iterator as newline json to the writer.
@@ -14,17 +13,5 @@ func NewLineJSON[T any](
 	it iterator.RecordIterator[T],
 	w io.Writer,
 ) error {
-	var record interface{}
-	var err error
-
-	for record, err = it(); err == nil; record, err = it() {
-		d, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(record)
-		if err != nil {
-			return err
-		}
-		if _, err := w.Write(append(d, jsonRecordDelimiter...)); err != nil {
-			return err
-		}
-	}
-	return err
+	return Encode(it, w, codec.JSON)
 }
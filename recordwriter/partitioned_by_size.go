@@ -0,0 +1,144 @@
+package recordwriter
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kvanticoss/goutils/v2/codec"
+	"github.com/kvanticoss/goutils/v2/eioutil"
+	"github.com/kvanticoss/goutils/v2/gzip"
+	"github.com/kvanticoss/goutils/v2/iterator"
+	"github.com/kvanticoss/goutils/v2/writerfactory"
+)
+
+// PartitionedBySize writes all the records from the records iterator to the writer factory using the codec.
+// returns the first error from either the record iterator or the encoding.
+// if gz is true, the output will be gzipped.
+// records will be put in batches of maxBytesParBatch (uncompressed) bytes into the writer factory
+// with a name of $basename_%06d$index$extension(.gz) where the extension is given by the codec.
+// Files are only rolled over between records and each file gets its own Encoder (and thereby headers etc).
+// Writers implementing writerfactory.RecordCounter get each encoded record reported. Encoders buffering records
+// (e.g. CSV and avro) are flushed after each record when a size limit is set or records are reported.
+func PartitionedBySize[T any](
+	it iterator.RecordIterator[T],
+	wf writerfactory.WriterFactory,
+	maxBytesParBatch int,
+	baseName string,
+	gz bool,
+	c codec.Codec,
+) error {
+	var record T
+	var err error
+
+	if baseName == "" {
+		baseName, _ = os.Hostname()
+	}
+
+	filenameFormat := baseName + "_" + "%06d" + c.Extension()
+	if gz {
+		filenameFormat = filenameFormat + ".gz"
+	}
+
+	enc := &sizeRotatingEncoder{
+		wf:             wf,
+		filenameFormat: filenameFormat,
+		sizeLimit:      maxBytesParBatch,
+		gz:             gz,
+		codec:          c,
+	}
+	// Always create the first file; even if there are no records
+	if err := enc.open(); err != nil {
+		return err
+	}
+
+	for record, err = it(); err == nil; record, err = it() {
+		if err := enc.Encode(record); err != nil {
+			return err
+		}
+	}
+
+	if err2 := enc.Close(); err2 != nil {
+		return err2
+	}
+
+	return err
+}
+
+// sizeRotatingEncoder closes the current file once more than sizeLimit bytes has been written to it
+// and opens the next file when there is more to write.
+type sizeRotatingEncoder struct {
+	wf             writerfactory.WriterFactory
+	filenameFormat string
+	sizeLimit      int
+	gz             bool
+	codec          codec.Codec
+
+	partitionCount int
+	bytesWritten   int
+	writer         io.WriteCloser
+	counter        writerfactory.RecordCounter
+	encoder        codec.Encoder
+}
+
+func (s *sizeRotatingEncoder) open() error {
+	file := fmt.Sprintf(s.filenameFormat, s.partitionCount)
+	s.partitionCount++
+
+	wc, err := s.wf(file)
+	if err != nil {
+		return fmt.Errorf("failed to open file (%s) for writing: %w", file, err)
+	}
+	if s.gz {
+		wc = gzip.NewWriter(wc)
+	}
+
+	s.writer = wc
+	s.counter, _ = wc.(writerfactory.RecordCounter)
+	s.bytesWritten = 0
+	s.encoder = s.codec.NewEncoder(eioutil.NewPostWriteCallback(wc, func(b []byte) error {
+		s.bytesWritten += len(b)
+		return nil
+	}))
+	return nil
+}
+
+// Encode writes the record to the current file and closes the file if the size limit has been reached
+func (s *sizeRotatingEncoder) Encode(v interface{}) error {
+	if s.encoder == nil {
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+	if err := s.encoder.Encode(v); err != nil {
+		return err
+	}
+	// buffering encoders are flushed when the size or the record boundaries must be known
+	if s.sizeLimit > 0 || s.counter != nil {
+		if err := flushEncoder(s.encoder); err != nil {
+			return err
+		}
+	}
+	if s.counter != nil {
+		s.counter.AddRecords(1)
+	}
+	if s.sizeLimit > 0 && s.bytesWritten > s.sizeLimit {
+		return s.Close()
+	}
+	return nil
+}
+
+// Close closes the encoder and the current file (if any)
+func (s *sizeRotatingEncoder) Close() error {
+	if s.encoder == nil {
+		return nil
+	}
+	encoder, writer := s.encoder, s.writer
+	s.encoder, s.writer, s.counter = nil, nil, nil
+
+	if err := encoder.Close(); err != nil {
+		writer.Close()
+		return err
+	}
+	return writer.Close()
+}
@@ -0,0 +1,43 @@
+package recordwriter
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kvanticoss/goutils/v2/codec"
+	"github.com/kvanticoss/goutils/v2/iterator"
+	"github.com/kvanticoss/goutils/v2/iterator/test_utils"
+	"github.com/kvanticoss/goutils/v2/writerfactory"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type sizeTestRecord struct {
+	A string
+}
+
+func TestPartitionedBySizeUsesCodec(t *testing.T) {
+	db, wf := writerfactory.GetMemoryWriterFactory()
+	records := []sizeTestRecord{{"a1"}, {"a2"}, {"a3"}}
+
+	err := PartitionedBySize(test_utils.NewDummyIteratorFromArr(records), wf, 1, "test", false, codec.Gob)
+	assert.Equal(t, iterator.ErrIteratorStop, err)
+	assert.Len(t, db, 3, "one record per file; no trailing empty file")
+
+	// Each file is a complete gob stream
+	for index, name := range []string{"test_000000.gob", "test_000001.gob", "test_000002.gob"} {
+		it := iterator.CodecRecordIterator(func() *sizeTestRecord { return &sizeTestRecord{} }, bytes.NewReader(db[name].Bytes()), codec.Gob)
+		rec, err := it()
+		assert.NoError(t, err, name)
+		assert.Equal(t, records[index], *rec)
+		_, err = it()
+		assert.Equal(t, iterator.ErrIteratorStop, err)
+	}
+}
+
+func TestEncodeFlushesEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	err := Encode(test_utils.NewDummyIteratorFromArr([]sizeTestRecord{{"a1"}}), &buf, codec.CSV)
+	assert.Equal(t, iterator.ErrIteratorStop, err)
+	assert.Equal(t, "A\na1\n", buf.String())
+}
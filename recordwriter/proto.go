@@ -0,0 +1,38 @@
+package recordwriter
+
+import (
+	"io"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/kvanticoss/goutils/v2/codec"
+	"github.com/kvanticoss/goutils/v2/iterator"
+	"github.com/kvanticoss/goutils/v2/writerfactory"
+)
+
+// Proto writes all the records from the records iterator as varint length-delimited protobuf messages to the writer.
+// returns the first error from either the record iterator or the encoding; messages larger than maxMessageSize fail
+// with codec.ErrMessageTooLarge (<= 0 means codec.DefaultMaxProtoMessageSize, as for iterator.ProtoRecordIterator).
+func Proto[T proto.Message](
+	it iterator.RecordIterator[T],
+	w io.Writer,
+	maxMessageSize int,
+) error {
+	return Encode(it, w, codec.NewProto(maxMessageSize))
+}
+
+// ProtoPartitionedBySize writes all the records from the records iterator as varint length-delimited protobuf messages.
+// if gz is true, the output will be gzipped.
+// records will be put in batches of maxBytesParBatch (uncompressed) bytes into the writer factory
+// with a name of $basename_%06d$index.pb(.gz). Messages larger than maxMessageSize fail with codec.ErrMessageTooLarge;
+// <= 0 means codec.DefaultMaxProtoMessageSize.
+func ProtoPartitionedBySize[T proto.Message](
+	it iterator.RecordIterator[T],
+	wf writerfactory.WriterFactory,
+	maxBytesParBatch int,
+	baseName string,
+	gz bool,
+	maxMessageSize int,
+) error {
+	return PartitionedBySize(it, wf, maxBytesParBatch, baseName, gz, codec.NewProto(maxMessageSize))
+}
@@ -0,0 +1,53 @@
+package recordwriter
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/kvanticoss/goutils/v2/codec"
+	"github.com/kvanticoss/goutils/v2/gzip"
+	"github.com/kvanticoss/goutils/v2/iterator"
+	"github.com/kvanticoss/goutils/v2/iterator/test_utils"
+	"github.com/kvanticoss/goutils/v2/writerfactory"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProtoPartitionedBySizeGzip(t *testing.T) {
+	db, wf := writerfactory.GetMemoryWriterFactory()
+	records := []*wrapperspb.StringValue{wrapperspb.String("a"), wrapperspb.String("b"), wrapperspb.String("c")}
+
+	err := ProtoPartitionedBySize(test_utils.NewDummyIteratorFromArr(records), wf, 4, "test", true, 0)
+	assert.Equal(t, iterator.ErrIteratorStop, err)
+	assert.Len(t, db, 2)
+
+	read := []string{}
+	for _, name := range []string{"test_000000.pb.gz", "test_000001.pb.gz"} {
+		r, err := gzip.NewReader(io.NopCloser(bytes.NewReader(db[name].Bytes())))
+		assert.NoError(t, err)
+		it := iterator.ProtoRecordIterator(func() *wrapperspb.StringValue { return &wrapperspb.StringValue{} }, r, 0)
+		for msg, err := it(); err != iterator.ErrIteratorStop; msg, err = it() {
+			assert.NoError(t, err)
+			read = append(read, msg.GetValue())
+		}
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, read)
+}
+
+func TestProtoMaxMessageSize(t *testing.T) {
+	var buf bytes.Buffer
+	records := []*wrapperspb.StringValue{wrapperspb.String("a"), wrapperspb.String("too large")}
+
+	err := Proto(test_utils.NewDummyIteratorFromArr(records), &buf, 5)
+	assert.ErrorIs(t, err, codec.ErrMessageTooLarge)
+
+	it := iterator.ProtoRecordIterator(func() *wrapperspb.StringValue { return &wrapperspb.StringValue{} }, &buf, 5)
+	msg, err := it()
+	assert.NoError(t, err)
+	assert.Equal(t, "a", msg.GetValue())
+	_, err = it()
+	assert.Equal(t, iterator.ErrIteratorStop, err, "nothing is written for the large message")
+}
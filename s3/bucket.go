@@ -0,0 +1,216 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config describes how to reach and authenticate with the S3 compatible service
+type Config struct {
+	// Endpoint is the base URL of the service; e.g. https://s3.eu-west-1.amazonaws.com or http://localhost:9000
+	Endpoint string
+	// Region is used for signing; defaults to us-east-1
+	Region string
+	// AccessKeyID and SecretAccessKey sign the requests; requests are anonymous if AccessKeyID is empty
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is set for temporary credentials
+	SessionToken string
+	// VirtualHostedStyle addresses buckets as {bucket}.{endpoint host} instead of {endpoint}/{bucket}
+	VirtualHostedStyle bool
+	// HTTPClient defaults to http.DefaultClient
+	HTTPClient *http.Client
+}
+
+// Bucket is a handle to a bucket; it is safe for concurrent use
+type Bucket struct {
+	name   string
+	config Config
+	nowFun func() time.Time
+}
+
+// NewBucket returns a handle to the named bucket
+func NewBucket(name string, config Config) *Bucket {
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	return &Bucket{name: name, config: config, nowFun: time.Now}
+}
+
+// Name returns the name of the bucket
+func (b *Bucket) Name() string {
+	return b.name
+}
+
+// Part is a successfully uploaded part of a multipart upload
+type Part struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// Object is a listed object
+type Object struct {
+	Key          string    `xml:"Key"`
+	Size         int64     `xml:"Size"`
+	LastModified time.Time `xml:"LastModified"`
+}
+
+// ListResult is a page of ListObjects
+type ListResult struct {
+	Objects               []Object `xml:"Contents"`
+	CommonPrefixes        []string `xml:"CommonPrefixes>Prefix"`
+	IsTruncated           bool     `xml:"IsTruncated"`
+	NextContinuationToken string   `xml:"NextContinuationToken"`
+}
+
+// PutObject uploads the object in a single request
+func (b *Bucket) PutObject(ctx context.Context, key string, body []byte) error {
+	resp, err := b.do(ctx, http.MethodPut, key, nil, body)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// GetObject returns the content of the object; missing objects yield an *Error matching fs.ErrNotExist
+func (b *Bucket) GetObject(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := b.do(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// DeleteObject deletes the object; deleting a missing object is not an error
+func (b *Bucket) DeleteObject(ctx context.Context, key string) error {
+	resp, err := b.do(ctx, http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// ListObjects returns a page of the objects starting with prefix (ListObjectsV2). With a delimiter keys containing
+// the delimiter after the prefix are grouped into CommonPrefixes. Pass the NextContinuationToken of a truncated
+// result to get the next page.
+func (b *Bucket) ListObjects(ctx context.Context, prefix, delimiter, continuationToken string) (*ListResult, error) {
+	query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+	if delimiter != "" {
+		query.Set("delimiter", delimiter)
+	}
+	if continuationToken != "" {
+		query.Set("continuation-token", continuationToken)
+	}
+	result := &ListResult{}
+	return result, b.doXML(ctx, http.MethodGet, "", query, nil, result)
+}
+
+// CreateMultipartUpload starts a multipart upload and returns its upload id
+func (b *Bucket) CreateMultipartUpload(ctx context.Context, key string) (string, error) {
+	result := struct {
+		UploadID string `xml:"UploadId"`
+	}{}
+	err := b.doXML(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil, &result)
+	return result.UploadID, err
+}
+
+// UploadPart uploads part partNumber (1 based) of the upload; all but the last part must be at least 5MiB on AWS
+func (b *Bucket) UploadPart(ctx context.Context, key, uploadID string, partNumber int, body []byte) (Part, error) {
+	query := url.Values{"partNumber": {strconv.Itoa(partNumber)}, "uploadId": {uploadID}}
+	resp, err := b.do(ctx, http.MethodPut, key, query, body)
+	if err != nil {
+		return Part{}, err
+	}
+	resp.Body.Close()
+	return Part{PartNumber: partNumber, ETag: resp.Header.Get("ETag")}, nil
+}
+
+// CompleteMultipartUpload assembles the parts (in ascending PartNumber order) into the object
+func (b *Bucket) CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []Part) error {
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name `xml:"CompleteMultipartUpload"`
+		Parts   []Part   `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return err
+	}
+	// Completing may fail with status 200 and an error document in the body
+	result := struct {
+		XMLName xml.Name
+		Error
+	}{}
+	if err := b.doXML(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, body, &result); err != nil {
+		return err
+	}
+	if result.XMLName.Local == "Error" {
+		result.Error.StatusCode = http.StatusOK
+		return &result.Error
+	}
+	return nil
+}
+
+// AbortMultipartUpload discards the upload and its uploaded parts
+func (b *Bucket) AbortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	resp, err := b.do(ctx, http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (b *Bucket) doXML(ctx context.Context, method, key string, query url.Values, body []byte, result interface{}) error {
+	resp, err := b.do(ctx, method, key, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return xml.NewDecoder(resp.Body).Decode(result)
+}
+
+// do sends the signed request; non 2xx responses are returned as *Error
+func (b *Bucket) do(ctx context.Context, method, key string, query url.Values, body []byte) (*http.Response, error) {
+	u, err := url.Parse(b.config.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	escapedPath := "/" + uriEncode(key, false)
+	if b.config.VirtualHostedStyle {
+		u.Host = b.name + "." + u.Host
+	} else {
+		escapedPath = "/" + uriEncode(b.name, true) + escapedPath
+	}
+	u.Path, _ = url.PathUnescape(escapedPath)
+	u.RawPath = escapedPath
+	u.RawQuery = strings.ReplaceAll(query.Encode(), "+", "%20")
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	payloadHash := emptySHA256
+	if len(body) > 0 {
+		payloadHash = hexSHA256(body)
+	}
+	b.config.sign(req, payloadHash, b.nowFun())
+
+	resp, err := b.config.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		return nil, newError(resp)
+	}
+	return resp, nil
+}
@@ -0,0 +1,91 @@
+package s3_test
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"testing"
+
+	"github.com/kvanticoss/goutils/v2/s3"
+	"github.com/kvanticoss/goutils/v2/s3/s3test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBucketObjects(t *testing.T) {
+	server := s3test.NewServer()
+	defer server.Close()
+	bucket := server.Bucket("bucket")
+	ctx := context.Background()
+
+	assert.NoError(t, bucket.PutObject(ctx, "dir/a b+c.txt", []byte("content")))
+	content, ok := server.Object("bucket", "dir/a b+c.txt")
+	assert.True(t, ok)
+	assert.Equal(t, "content", string(content))
+
+	r, err := bucket.GetObject(ctx, "dir/a b+c.txt")
+	assert.NoError(t, err)
+	content, _ = io.ReadAll(r)
+	assert.Equal(t, "content", string(content))
+
+	assert.NoError(t, bucket.DeleteObject(ctx, "dir/a b+c.txt"))
+	_, err = bucket.GetObject(ctx, "dir/a b+c.txt")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	s3Err := &s3.Error{}
+	if assert.ErrorAs(t, err, &s3Err) {
+		assert.Equal(t, "NoSuchKey", s3Err.Code)
+	}
+}
+
+func TestBucketListObjects(t *testing.T) {
+	server := s3test.NewServer()
+	defer server.Close()
+	server.MaxKeys = 2
+	for _, key := range []string{"a/1", "a/2", "b", "c/1", "c/2/3", "d"} {
+		server.PutObject("bucket", key, []byte(key))
+	}
+	bucket := server.Bucket("bucket")
+
+	keys, prefixes := []string{}, []string{}
+	token := ""
+	for {
+		result, err := bucket.ListObjects(context.Background(), "", "/", token)
+		if !assert.NoError(t, err) {
+			return
+		}
+		for _, object := range result.Objects {
+			keys = append(keys, object.Key)
+		}
+		prefixes = append(prefixes, result.CommonPrefixes...)
+		if !result.IsTruncated {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+	assert.Equal(t, []string{"b", "d"}, keys)
+	assert.Equal(t, []string{"a/", "c/"}, prefixes)
+}
+
+func TestBucketMultipartUpload(t *testing.T) {
+	server := s3test.NewServer()
+	defer server.Close()
+	bucket := server.Bucket("bucket")
+	ctx := context.Background()
+
+	uploadID, err := bucket.CreateMultipartUpload(ctx, "key")
+	assert.NoError(t, err)
+	part1, err := bucket.UploadPart(ctx, "key", uploadID, 1, []byte("hello "))
+	assert.NoError(t, err)
+	part2, err := bucket.UploadPart(ctx, "key", uploadID, 2, []byte("world"))
+	assert.NoError(t, err)
+	assert.NoError(t, bucket.CompleteMultipartUpload(ctx, "key", uploadID, []s3.Part{part1, part2}))
+
+	content, _ := server.Object("bucket", "key")
+	assert.Equal(t, "hello world", string(content))
+	assert.Equal(t, 0, server.PendingUploads())
+
+	uploadID, err = bucket.CreateMultipartUpload(ctx, "aborted")
+	assert.NoError(t, err)
+	assert.NoError(t, bucket.AbortMultipartUpload(ctx, "aborted", uploadID))
+	assert.Equal(t, 0, server.PendingUploads())
+}
@@ -0,0 +1,4 @@
+// Package s3 is a minimal client of the S3 REST API (AWS S3 and compatible stores such as MinIO, R2 or GCS
+// interoperability) covering what the s3wf and s3rf factories need; object get/put/delete, listing and multipart
+// uploads. Requests are signed with AWS Signature Version 4 unless no credentials are configured.
+package s3
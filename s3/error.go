@@ -0,0 +1,34 @@
+package s3
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+)
+
+// Error is a failed S3 request; e.g. Code "NoSuchKey" or "AccessDenied"
+type Error struct {
+	StatusCode int
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("s3: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Is makes missing objects match fs.ErrNotExist
+func (e *Error) Is(target error) bool {
+	return target == fs.ErrNotExist && (e.StatusCode == http.StatusNotFound || e.Code == "NoSuchKey")
+}
+
+func newError(resp *http.Response) error {
+	e := &Error{StatusCode: resp.StatusCode}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if xml.Unmarshal(body, e) != nil || e.Code == "" {
+		e.Code = http.StatusText(resp.StatusCode)
+	}
+	return e
+}
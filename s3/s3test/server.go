@@ -0,0 +1,262 @@
+// Package s3test provides an in-process S3 stand-in (path style addressing) for tests of code using the s3 package.
+package s3test
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kvanticoss/goutils/v2/s3"
+)
+
+// Server is a fake S3 service keeping objects in memory. Signatures are not verified.
+type Server struct {
+	*httptest.Server
+
+	// MaxKeys limits the page size of listings; defaults to 1000
+	MaxKeys int
+	// Fail, if set, is called for each request and makes the request fail with a 500 when returning true
+	Fail func(r *http.Request) bool
+
+	mutex    sync.Mutex
+	objects  map[string][]byte // by bucket/key
+	uploads  map[string]map[int][]byte
+	requests []string
+	nextID   int
+}
+
+// NewServer starts a server; Close it when done
+func NewServer() *Server {
+	s := &Server{
+		MaxKeys: 1000,
+		objects: map[string][]byte{},
+		uploads: map[string]map[int][]byte{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Bucket returns a handle to the bucket on the server
+func (s *Server) Bucket(name string) *s3.Bucket {
+	return s3.NewBucket(name, s3.Config{
+		Endpoint:        s.URL,
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "SECRET",
+		HTTPClient:      s.Client(),
+	})
+}
+
+// Object returns the content of the object and if it exists
+func (s *Server) Object(bucket, key string) ([]byte, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	content, ok := s.objects[bucket+"/"+key]
+	return content, ok
+}
+
+// PutObject stores the object
+func (s *Server) PutObject(bucket, key string, content []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.objects[bucket+"/"+key] = content
+}
+
+// PendingUploads returns the number of multipart uploads neither completed nor aborted
+func (s *Server) PendingUploads() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.uploads)
+}
+
+// Requests returns the handled requests as "METHOD path?query"
+func (s *Server) Requests() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string{}, s.requests...)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.RequestURI())
+
+	if s.Fail != nil && s.Fail(r) {
+		writeError(w, http.StatusInternalServerError, "InternalError", "injected failure")
+		return
+	}
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+		writeError(w, http.StatusForbidden, "AccessDenied", "missing signature")
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	bucket, key, _ := strings.Cut(path, "/")
+	query := r.URL.Query()
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+		return
+	}
+
+	switch {
+	case key == "" && r.Method == http.MethodGet:
+		s.list(w, bucket, query.Get("prefix"), query.Get("delimiter"), query.Get("continuation-token"))
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		s.nextID++
+		uploadID := strconv.Itoa(s.nextID)
+		s.uploads[uploadID] = map[int][]byte{}
+		writeXML(w, struct {
+			XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+			Bucket   string
+			Key      string
+			UploadID string `xml:"UploadId"`
+		}{Bucket: bucket, Key: key, UploadID: uploadID})
+	case r.Method == http.MethodPut && query.Has("uploadId"):
+		parts, ok := s.uploads[query.Get("uploadId")]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchUpload", "no such upload")
+			return
+		}
+		partNumber, _ := strconv.Atoi(query.Get("partNumber"))
+		parts[partNumber] = body
+		w.Header().Set("ETag", etag(body))
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		s.complete(w, bucket, key, query.Get("uploadId"), body)
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		delete(s.uploads, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		s.objects[path] = body
+		w.Header().Set("ETag", etag(body))
+	case r.Method == http.MethodGet:
+		content, ok := s.objects[path]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+			return
+		}
+		w.Write(content)
+	case r.Method == http.MethodDelete:
+		delete(s.objects, path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusNotImplemented, "NotImplemented", r.Method+" "+r.URL.String())
+	}
+}
+
+func (s *Server) complete(w http.ResponseWriter, bucket, key, uploadID string, body []byte) {
+	parts, ok := s.uploads[uploadID]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchUpload", "no such upload")
+		return
+	}
+	request := struct {
+		Parts []s3.Part `xml:"Part"`
+	}{}
+	if err := xml.Unmarshal(body, &request); err != nil || len(request.Parts) == 0 {
+		writeError(w, http.StatusBadRequest, "MalformedXML", "invalid part list")
+		return
+	}
+	content := []byte{}
+	for index, part := range request.Parts {
+		data, ok := parts[part.PartNumber]
+		if !ok || part.ETag != etag(data) || (index > 0 && part.PartNumber <= request.Parts[index-1].PartNumber) {
+			writeError(w, http.StatusBadRequest, "InvalidPart", fmt.Sprintf("invalid part %d", part.PartNumber))
+			return
+		}
+		content = append(content, data...)
+	}
+	delete(s.uploads, uploadID)
+	s.objects[bucket+"/"+key] = content
+	writeXML(w, struct {
+		XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+		Key     string
+	}{Key: key})
+}
+
+func (s *Server) list(w http.ResponseWriter, bucket, prefix, delimiter, token string) {
+	keys := []string{}
+	for path := range s.objects {
+		if key, ok := strings.CutPrefix(path, bucket+"/"); ok && strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	type object struct {
+		Key          string
+		Size         int
+		LastModified time.Time
+	}
+	type commonPrefix struct {
+		Prefix string
+	}
+	result := struct {
+		XMLName               xml.Name `xml:"ListBucketResult"`
+		Contents              []object
+		CommonPrefixes        []commonPrefix
+		IsTruncated           bool
+		NextContinuationToken string `xml:",omitempty"`
+	}{}
+
+	// The continuation token is the last key or common prefix returned
+	count := 0
+	for _, key := range keys {
+		if key <= token || (delimiter != "" && strings.HasSuffix(token, delimiter) && strings.HasPrefix(key, token)) {
+			continue
+		}
+		entry := key
+		if delimiter != "" {
+			if index := strings.Index(key[len(prefix):], delimiter); index >= 0 {
+				entry = key[:len(prefix)+index+len(delimiter)]
+			}
+		}
+		if entry == result.NextContinuationToken {
+			continue // another key of the same common prefix
+		}
+		if count == s.MaxKeys {
+			result.IsTruncated = true
+			break
+		}
+		if entry != key {
+			result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{entry})
+		} else {
+			result.Contents = append(result.Contents, object{key, len(s.objects[bucket+"/"+key]), time.Unix(0, 0).UTC()})
+		}
+		result.NextContinuationToken = entry
+		count++
+	}
+	if !result.IsTruncated {
+		result.NextContinuationToken = ""
+	}
+	writeXML(w, result)
+}
+
+func etag(b []byte) string {
+	sum := md5.Sum(b)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func writeXML(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write([]byte(xml.Header))
+	xml.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string
+		Message string
+	}{Code: code, Message: message})
+}
@@ -0,0 +1,3 @@
+// Package snappy provides framed Snappy (https://github.com/google/snappy/blob/main/framing_format.txt) writers and
+// readers with Close and Flush calls cascaded to the underlying writer/reader; the snappy equivalent of the gzip package.
+package snappy
@@ -0,0 +1,23 @@
+package snappy
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/s2"
+)
+
+// Reader is a framed snappy reader that once closed will also close the underlying reader
+type Reader struct {
+	*s2.Reader
+	underlyingReader io.ReadCloser
+}
+
+// NewReader treats the r-stream as a framed snappy stream and returns a Reader
+func NewReader(r io.ReadCloser) *Reader {
+	return &Reader{s2.NewReader(r), r}
+}
+
+// Close closes the underlying reader
+func (sr *Reader) Close() error {
+	return sr.underlyingReader.Close()
+}
@@ -0,0 +1,84 @@
+package snappy
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// underlying records the calls cascaded from the Writer
+type underlying struct {
+	bytes.Buffer
+	flushes, records int
+	closed, aborted  bool
+}
+
+func (u *underlying) Flush() error     { u.flushes++; return nil }
+func (u *underlying) Close() error     { u.closed = true; return nil }
+func (u *underlying) Abort() error     { u.aborted = true; return nil }
+func (u *underlying) AddRecords(n int) { u.records += n }
+
+// closeOnly can't be aborted
+type closeOnly struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (c *closeOnly) Close() error { c.closed = true; return nil }
+
+func decompress(t *testing.T, b []byte) string {
+	r := NewReader(io.NopCloser(bytes.NewReader(b)))
+	defer r.Close()
+	got, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	return string(got)
+}
+
+func TestRoundTrip(t *testing.T) {
+	content := string(bytes.Repeat([]byte("compress me please "), 1000))
+	for _, opts := range []Options{{}, {Level: LevelBetter, Concurrency: 1}, {Level: LevelBest, Concurrency: 4}} {
+		u := &underlying{}
+		w := NewWriterWithOptions(u, opts)
+		_, err := w.Write([]byte(content))
+		assert.NoError(t, err)
+		assert.NoError(t, w.Close())
+
+		assert.True(t, u.closed, "Close should be cascaded to the underlying writer")
+		assert.Less(t, u.Len(), len(content))
+		assert.Equal(t, content, decompress(t, u.Bytes()))
+	}
+}
+
+func TestFlush(t *testing.T) {
+	u := &underlying{}
+	w := NewWriter(u)
+	w.Write([]byte("hello"))
+	assert.NoError(t, w.Flush())
+	assert.Equal(t, 1, u.flushes, "Flush should be cascaded to the underlying writer")
+
+	// Everything written before Flush can be decoded without closing the stream
+	r := NewReader(io.NopCloser(bytes.NewReader(u.Bytes())))
+	got := make([]byte, 5)
+	_, err := io.ReadFull(r, got)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(got))
+	assert.NoError(t, w.Close())
+}
+
+func TestAbortAndAddRecords(t *testing.T) {
+	u := &underlying{}
+	w := NewWriter(u)
+	w.Write([]byte("hello"))
+	w.AddRecords(2)
+	assert.Equal(t, 2, u.records, "AddRecords should be forwarded to the underlying writer")
+
+	assert.NoError(t, w.Abort())
+	assert.True(t, u.aborted)
+	assert.False(t, u.closed)
+
+	closer := &closeOnly{}
+	assert.NoError(t, NewWriter(closer).Abort())
+	assert.True(t, closer.closed, "writers which can't be aborted are closed")
+}
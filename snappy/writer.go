@@ -0,0 +1,78 @@
+package snappy
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/s2"
+
+	"github.com/kvanticoss/goutils/v2/internal/layered"
+)
+
+// Levels of Options.Level
+const (
+	LevelDefault = 0
+	LevelBetter  = 1
+	LevelBest    = 2
+)
+
+// Options configures the encoder
+type Options struct {
+	// Level is one of LevelDefault, LevelBetter or LevelBest; the output is snappy compatible regardless
+	Level int
+	// Concurrency is the number of goroutines compressing blocks in parallel; 0 uses GOMAXPROCS
+	Concurrency int
+}
+
+// Writer creates a framed snappy stream which closes the underlying stream as well as the snappy stream on close
+type Writer struct {
+	*s2.Writer
+	underlyingWriter io.Writer
+}
+
+// NewWriter returns a framed snappy writer with the default options; Close and Flush will be cascaded to the underlying writer
+func NewWriter(w io.Writer) *Writer {
+	return NewWriterWithOptions(w, Options{})
+}
+
+// NewWriterWithOptions returns a framed snappy writer with the options; Close and Flush will be cascaded to the underlying writer
+func NewWriterWithOptions(w io.Writer, opts Options) *Writer {
+	wopts := []s2.WriterOption{s2.WriterSnappyCompat()}
+	switch opts.Level {
+	case LevelBetter:
+		wopts = append(wopts, s2.WriterBetterCompression())
+	case LevelBest:
+		wopts = append(wopts, s2.WriterBestCompression())
+	}
+	if opts.Concurrency > 0 {
+		wopts = append(wopts, s2.WriterConcurrency(opts.Concurrency))
+	}
+	return &Writer{Writer: s2.NewWriter(w, wopts...), underlyingWriter: w}
+}
+
+// Flush flushes the snappy writer AND the underlying writer
+func (sw *Writer) Flush() error {
+	if err := sw.Writer.Flush(); err != nil {
+		return err
+	}
+	return layered.Flush(sw.underlyingWriter)
+}
+
+// Close closes the snappy writer AND the underlying writer
+func (sw *Writer) Close() error {
+	if err := sw.Writer.Close(); err != nil {
+		return err
+	}
+	return layered.Close(sw.underlyingWriter)
+}
+
+// Abort drops the buffered snappy chunks and aborts the underlying writer (or closes it if it can't be aborted)
+func (sw *Writer) Abort() error {
+	sw.Writer.Reset(io.Discard)
+	return layered.Abort(sw.underlyingWriter)
+}
+
+// AddRecords reports the end of n records to the underlying writer; the records may still be buffered in the
+// current snappy chunk.
+func (sw *Writer) AddRecords(n int) {
+	layered.AddRecords(sw.underlyingWriter, n)
+}
@@ -0,0 +1,266 @@
+package writerfactory
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kvanticoss/goutils/v2/gzip"
+	"github.com/kvanticoss/goutils/v2/internal/layered"
+)
+
+// DefaultArchiveMemoryBuffer is the number of bytes of each entry an Archive keeps in memory before spilling to a
+// temp file
+const DefaultArchiveMemoryBuffer = 1 << 20
+
+// ArchiveFormat is the format of an Archive
+type ArchiveFormat int
+
+const (
+	// Tar archives; wrap the writer with gzip for .tar.gz
+	Tar ArchiveFormat = iota
+	// Zip archives; entries are deflated
+	Zip
+)
+
+// ArchiveOptions configures an Archive
+type ArchiveOptions struct {
+	Format ArchiveFormat
+	// MaxMemory is the number of bytes of each entry buffered in memory before the remainder is spilled to a temp
+	// file; 0 uses DefaultArchiveMemoryBuffer
+	MaxMemory int
+	// TempDir is the directory of spill files; "" uses os.TempDir()
+	TempDir string
+}
+
+// Archive writes the files created by its WriterFactory as entries of a single tar or zip stream. Entries are
+// buffered until closed and then written one at a time, so writers may be used concurrently. Directory entries
+// are added for the parents of each entry (e.g. hive partitions). Each path can only be created once. The archive
+// is finalized by Close.
+type Archive struct {
+	mu       sync.Mutex
+	w        io.Writer
+	tw       *tar.Writer
+	zw       *zip.Writer
+	opts     ArchiveOptions
+	dirs     map[string]bool
+	names    map[string]bool // of the created entries which haven't been aborted
+	open     int
+	finished bool
+	err      error
+	now      func() time.Time
+}
+
+// NewArchive returns an Archive of the format writing to w
+func NewArchive(w io.Writer, format ArchiveFormat) *Archive {
+	return NewArchiveWithOptions(w, ArchiveOptions{Format: format})
+}
+
+// NewArchiveWithOptions returns an Archive with the options writing to w
+func NewArchiveWithOptions(w io.Writer, opts ArchiveOptions) *Archive {
+	if opts.MaxMemory <= 0 {
+		opts.MaxMemory = DefaultArchiveMemoryBuffer
+	}
+	a := &Archive{w: w, opts: opts, dirs: map[string]bool{}, names: map[string]bool{}, now: time.Now}
+	if opts.Format == Zip {
+		a.zw = zip.NewWriter(w)
+	} else {
+		a.tw = tar.NewWriter(w)
+	}
+	return a
+}
+
+// OpenArchive creates the archive file at path using wf; the format is chosen by the extension
+// (.zip, .tar, .tar.gz or .tgz). Closing the Archive closes the file.
+func OpenArchive(wf WriterFactory, path string, opts ArchiveOptions) (*Archive, error) {
+	compress := false
+	switch {
+	case strings.HasSuffix(path, ".zip"):
+		opts.Format = Zip
+	case strings.HasSuffix(path, ".tar"):
+		opts.Format = Tar
+	case strings.HasSuffix(path, ".tar.gz"), strings.HasSuffix(path, ".tgz"):
+		opts.Format, compress = Tar, true
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownArchiveFormat, path)
+	}
+
+	w, err := wf(path)
+	if err != nil {
+		return nil, err
+	}
+	if compress {
+		return NewArchiveWithOptions(gzip.NewWriter(w), opts), nil
+	}
+	return NewArchiveWithOptions(w, opts), nil
+}
+
+// WriterFactory returns a WriterFactory creating entries in the archive. Paths are sanitized with LocalPathRules
+// since archives are typically extracted to local disk. Entries are added to the archive when their writers are
+// closed; aborted entries (see Aborter) are discarded. Creating a path which is already in the archive (or still
+// open) fails with ErrDuplicateEntry.
+func (a *Archive) WriterFactory() WriterFactory {
+	return func(p string) (io.WriteCloser, error) {
+		name, err := SanitizePath(p, LocalPathRules)
+		if err != nil {
+			return nil, err
+		}
+
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if a.finished {
+			return nil, ErrArchiveClosed
+		}
+		if a.names[name] {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateEntry, name)
+		}
+		a.names[name] = true
+		a.open++
+		return &archiveEntry{
+			archive: a,
+			name:    name,
+			buf:     &replayBuffer{maxMemory: a.opts.MaxMemory, tempDir: a.opts.TempDir},
+		}, nil
+	}
+}
+
+// Close writes the end of the archive and closes the underlying writer; ErrOpenEntries is returned (and nothing
+// is written) while entries are still open. If adding an entry failed the underlying writer is aborted instead and
+// the error returned.
+func (a *Archive) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.finished {
+		return ErrArchiveClosed
+	}
+	if a.err != nil {
+		a.finished = true
+		layered.Abort(a.w)
+		return a.err
+	}
+	if a.open > 0 {
+		return fmt.Errorf("%w: %d", ErrOpenEntries, a.open)
+	}
+	a.finished = true
+
+	var err error
+	if a.zw != nil {
+		err = a.zw.Close()
+	} else {
+		err = a.tw.Close()
+	}
+	if err != nil {
+		return err
+	}
+	if closer, ok := a.w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Abort discards the archive; the underlying writer is aborted if it supports it, otherwise it is closed.
+// Entries closed afterwards are discarded.
+func (a *Archive) Abort() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.finished = true
+	return layered.Abort(a.w)
+}
+
+// add writes the entry, preceded by entries for its parent directories not yet in the archive
+func (a *Archive) add(name string, buf *replayBuffer) error {
+	modTime := a.now()
+	for i, c := range name {
+		if c != '/' || a.dirs[name[:i+1]] {
+			continue
+		}
+		a.dirs[name[:i+1]] = true
+		if _, err := a.create(name[:i+1], 0, modTime); err != nil {
+			return err
+		}
+	}
+
+	w, err := a.create(name, buf.size(), modTime)
+	if err != nil {
+		return err
+	}
+	return buf.replay(w)
+}
+
+// create writes the header of an entry (directories end with "/") and returns the writer of its content
+func (a *Archive) create(name string, size int64, modTime time.Time) (io.Writer, error) {
+	isDir := strings.HasSuffix(name, "/")
+	if a.zw != nil {
+		header := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modTime}
+		if isDir {
+			header.Method = zip.Store
+			header.SetMode(fs.ModeDir | 0o755)
+		}
+		return a.zw.CreateHeader(header)
+	}
+
+	header := &tar.Header{Name: name, Size: size, Mode: 0o644, ModTime: modTime, Typeflag: tar.TypeReg}
+	if isDir {
+		header.Mode, header.Typeflag = 0o755, tar.TypeDir
+	}
+	return a.tw, a.tw.WriteHeader(header)
+}
+
+type archiveEntry struct {
+	archive *Archive
+	name    string
+	buf     *replayBuffer
+	done    bool
+}
+
+// Write buffers p until the entry is closed
+func (e *archiveEntry) Write(p []byte) (int, error) {
+	if e.done {
+		return 0, os.ErrClosed
+	}
+	if err := e.buf.Write(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close adds the entry to the archive
+func (e *archiveEntry) Close() error {
+	if e.done {
+		return os.ErrClosed
+	}
+	e.done = true
+	defer e.buf.Close()
+
+	a := e.archive
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.open--
+	if a.finished {
+		return ErrArchiveClosed
+	}
+	if a.err != nil {
+		return a.err
+	}
+	a.err = a.add(e.name, e.buf)
+	return a.err
+}
+
+// Abort discards the entry; its path may be created again
+func (e *archiveEntry) Abort() error {
+	if e.done {
+		return nil
+	}
+	e.done = true
+	e.archive.mu.Lock()
+	e.archive.open--
+	delete(e.archive.names, e.name)
+	e.archive.mu.Unlock()
+	return e.buf.Close()
+}
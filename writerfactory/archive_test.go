@@ -0,0 +1,163 @@
+package writerfactory
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// readTar returns the entries of a tar stream with directories mapped to ""
+func readTar(t *testing.T, r io.Reader) map[string]string {
+	entries := map[string]string{}
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return entries
+		}
+		if !assert.NoError(t, err) {
+			return entries
+		}
+		content, _ := io.ReadAll(tr)
+		entries[header.Name] = string(content)
+	}
+}
+
+func TestTarArchive(t *testing.T) {
+	var buf bytes.Buffer
+	archive := NewArchiveWithOptions(&buf, ArchiveOptions{Format: Tar, MaxMemory: 4, TempDir: t.TempDir()})
+	wf := archive.WriterFactory()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w, err := wf(fmt.Sprintf("date=2023-01-0%d/part-%d.json", i%2, i))
+			assert.NoError(t, err)
+			fmt.Fprintf(w, "content of %d", i)
+			assert.NoError(t, w.Close())
+		}(i)
+	}
+	wg.Wait()
+
+	aborted, err := wf("aborted")
+	assert.NoError(t, err)
+	aborted.Write([]byte("discarded"))
+	assert.NoError(t, aborted.(Aborter).Abort())
+
+	assert.NoError(t, archive.Close())
+
+	entries := readTar(t, &buf)
+	assert.Len(t, entries, 12)
+	assert.Contains(t, entries, "date=2023-01-00/")
+	assert.Contains(t, entries, "date=2023-01-01/")
+	assert.Equal(t, "content of 3", entries["date=2023-01-01/part-3.json"])
+	assert.NotContains(t, entries, "aborted")
+}
+
+func TestZipArchive(t *testing.T) {
+	var buf bytes.Buffer
+	archive := NewArchive(&buf, Zip)
+	wf := archive.WriterFactory()
+
+	w, err := wf("a/b/file.txt")
+	assert.NoError(t, err)
+	w.Write([]byte("hello"))
+	assert.ErrorIs(t, archive.Close(), ErrOpenEntries, "open entries prevents finalizing")
+	assert.NoError(t, w.Close())
+	assert.NoError(t, archive.Close())
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+	names := []string{}
+	for _, f := range r.File {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{"a/", "a/b/", "a/b/file.txt"}, names)
+	rc, err := r.File[2].Open()
+	assert.NoError(t, err)
+	content, _ := io.ReadAll(rc)
+	assert.Equal(t, "hello", string(content))
+
+	_, err = wf("late")
+	assert.ErrorIs(t, err, ErrArchiveClosed)
+	assert.ErrorIs(t, archive.Close(), ErrArchiveClosed)
+}
+
+func TestArchivePathTraversal(t *testing.T) {
+	archive := NewArchive(io.Discard, Tar)
+	_, err := archive.WriterFactory()("../escape")
+	assert.ErrorIs(t, err, ErrPathTraversal)
+}
+
+func TestOpenArchive(t *testing.T) {
+	buffers, wf := GetMemoryWriterFactory()
+
+	_, err := OpenArchive(wf, "archive.rar", ArchiveOptions{})
+	assert.ErrorIs(t, err, ErrUnknownArchiveFormat)
+
+	archive, err := OpenArchive(wf, "archive.tar.gz", ArchiveOptions{})
+	assert.NoError(t, err)
+	w, err := archive.WriterFactory()("file")
+	assert.NoError(t, err)
+	w.Write([]byte("compressed"))
+	assert.NoError(t, w.Close())
+	assert.NoError(t, archive.Close())
+
+	gz, err := gzip.NewReader(buffers["archive.tar.gz"])
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"file": "compressed"}, readTar(t, gz))
+}
+
+func TestArchiveDuplicateEntries(t *testing.T) {
+	var buf bytes.Buffer
+	archive := NewArchive(&buf, Tar)
+	wf := archive.WriterFactory()
+
+	w, err := wf("dir/file")
+	assert.NoError(t, err)
+	_, err = wf("dir/./file")
+	assert.ErrorIs(t, err, ErrDuplicateEntry, "open entries can't be created again")
+	assert.NoError(t, w.(Aborter).Abort())
+
+	w, err = wf("dir/file")
+	assert.NoError(t, err, "aborted entries can be created again")
+	w.Write([]byte("second"))
+	assert.NoError(t, w.Close())
+	_, err = wf("dir/file")
+	assert.ErrorIs(t, err, ErrDuplicateEntry)
+
+	assert.NoError(t, archive.Close())
+	assert.Equal(t, map[string]string{"dir/": "", "dir/file": "second"}, readTar(t, &buf))
+}
+
+// failingArchiveWriter fails all writes and records whether it was aborted or closed
+type failingArchiveWriter struct {
+	aborted, closed bool
+}
+
+func (w *failingArchiveWriter) Write(p []byte) (int, error) { return 0, io.ErrClosedPipe }
+func (w *failingArchiveWriter) Close() error                { w.closed = true; return nil }
+func (w *failingArchiveWriter) Abort() error                { w.aborted = true; return nil }
+
+func TestArchiveCloseAfterFailure(t *testing.T) {
+	underlying := &failingArchiveWriter{}
+	archive := NewArchive(underlying, Tar)
+
+	w, err := archive.WriterFactory()("file")
+	assert.NoError(t, err)
+	w.Write([]byte("content"))
+	assert.ErrorIs(t, w.Close(), io.ErrClosedPipe)
+
+	assert.ErrorIs(t, archive.Close(), io.ErrClosedPipe)
+	assert.True(t, underlying.aborted, "the partial archive should be aborted")
+	assert.False(t, underlying.closed)
+}
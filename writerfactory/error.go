@@ -0,0 +1,54 @@
+package writerfactory
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrAborted is returned when writing to or closing an aborted writer
+	ErrAborted = errors.New("writer aborted")
+	// ErrInvalidTemplate is returned by WithPathTemplate for malformed templates or unknown placeholders
+	ErrInvalidTemplate = errors.New("invalid path template")
+	// ErrMissingPartition is returned when a path template refers to a partition not present in the path
+	ErrMissingPartition = errors.New("partition missing from path")
+	// ErrRotationBelowStream is returned when compression, encryption or checksums are applied on top of WithRotation
+	// instead of below it; e.g. wf.WithRotation(...).WithGzip() rather than wf.WithGzip().WithRotation(...)
+	ErrRotationBelowStream = errors.New("rotation must be applied after compression, encryption and checksums")
+
+	// ErrArchiveClosed is returned when writing entries to a closed Archive
+	ErrArchiveClosed = errors.New("archive is closed")
+	// ErrDuplicateEntry is returned when creating an Archive entry for a path which has already been created
+	ErrDuplicateEntry = errors.New("duplicate archive entry")
+	// ErrOpenEntries is returned when closing an Archive while some of its entries haven't been closed
+	ErrOpenEntries = errors.New("archive has open entries")
+	// ErrUnknownArchiveFormat is returned by OpenArchive for paths without a known archive extension
+	ErrUnknownArchiveFormat = errors.New("unknown archive format")
+
+	// ErrEmptyPath is returned by SanitizePath for empty paths
+	ErrEmptyPath = errors.New("empty path")
+	// ErrAbsolutePath is returned by SanitizePath for absolute paths
+	ErrAbsolutePath = errors.New("absolute path")
+	// ErrPathTraversal is returned by SanitizePath for paths with ".." segments
+	ErrPathTraversal = errors.New("path traversal")
+	// ErrPathTooLong is returned by SanitizePath for paths or segments exceeding the max length of the PathRules
+	ErrPathTooLong = errors.New("path too long")
+	// ErrForbiddenCharacter is returned by SanitizePath for paths with invalid UTF-8, control characters or
+	// characters forbidden by the PathRules
+	ErrForbiddenCharacter = errors.New("forbidden character in path")
+)
+
+// PathError is returned for paths rejected by SanitizePath; Err is one of ErrEmptyPath, ErrAbsolutePath,
+// ErrPathTraversal, ErrPathTooLong or ErrForbiddenCharacter
+type PathError struct {
+	Path string
+	Err  error
+}
+
+func (e *PathError) Error() string {
+	return fmt.Sprintf("invalid path %q: %v", e.Path, e.Err)
+}
+
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
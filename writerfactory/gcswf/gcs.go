@@ -1,16 +1,196 @@
 package gcswf
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
 	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
 
 	"cloud.google.com/go/storage"
+	"google.golang.org/api/googleapi"
+
 	"github.com/kvanticoss/goutils/v2/writerfactory"
 )
 
-// GetGCSWriterFactory returns a writerfactory, pointing to the root of the GCS bucket.
+var (
+	// ErrObjectExists is returned when Options.DoesNotExist is set and the object already exists; it wraps fs.ErrExist
+	ErrObjectExists = fmt.Errorf("gcs object %w", fs.ErrExist)
+	// ErrChecksumMismatch is returned by Close if GCS rejects the upload since its CRC32C doesn't match the written data
+	ErrChecksumMismatch = errors.New("gcs object crc32c mismatch")
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Options configures the objects written
+type Options struct {
+	// ContentType of the objects; inferred from the extension of the path if empty (see ContentType)
+	ContentType string
+	// Metadata is set as custom metadata on all objects
+	Metadata map[string]string
+	// ChunkSize is the size of the chunks uploaded (and buffered) per request, rounded up to a multiple of 256KiB.
+	// 0 uses the storage client default (16MiB) and a negative value uploads objects in a single request.
+	ChunkSize int
+	// DoesNotExist makes writes fail with ErrObjectExists instead of replacing existing objects
+	DoesNotExist bool
+	// CRC32C sends the CRC32C of the written data with the upload so GCS rejects (ErrChecksumMismatch) corrupted
+	// uploads without replacing the existing object. Since GCS needs the checksum before the upload starts the
+	// objects are buffered in memory and uploaded on Close.
+	CRC32C bool
+}
+
+// GetGCSWriterFactory returns a writerfactory, pointing to the root of the GCS bucket. Same as
+// GetGCSWriterFactoryWithOptions with the zero Options.
 func GetGCSWriterFactory(ctx context.Context, bucket *storage.BucketHandle) writerfactory.WriterFactory {
-	return func(path string) (wc io.WriteCloser, err error) {
-		return bucket.Object(path).NewWriter(ctx), nil
+	return GetGCSWriterFactoryWithOptions(ctx, bucket, Options{})
+}
+
+// GetGCSWriterFactoryWithOptions returns a writerfactory, pointing to the root of the GCS bucket.
+// Objects become visible once the writer is closed successfully. Writers stop writing when ctx is cancelled and
+// can be aborted (writerfactory.Aborter) without creating the object. Errors are annotated with the object name;
+// upload errors surface on the Write after the failed chunk or on Close. Uploads start on the first Write and hold
+// resources until the writer is closed or aborted.
+func GetGCSWriterFactoryWithOptions(ctx context.Context, bucket *storage.BucketHandle, opts Options) writerfactory.WriterFactory {
+	return func(name string) (wc io.WriteCloser, err error) {
+		object := bucket.Object(name)
+		if opts.DoesNotExist {
+			object = object.If(storage.Conditions{DoesNotExist: true})
+		}
+		gw := &writer{ctx: ctx, object: object, name: name, opts: opts}
+		if opts.CRC32C {
+			gw.buf = &bytes.Buffer{}
+		}
+		return gw, nil
+	}
+}
+
+type writer struct {
+	ctx    context.Context
+	object *storage.ObjectHandle
+	name   string
+	opts   Options
+
+	cancel func()
+	w      *storage.Writer
+	buf    *bytes.Buffer // the content of CRC32C verified objects until Close
+	err    error
+}
+
+// open starts the upload
+func (w *writer) open() {
+	ctx, cancel := context.WithCancel(w.ctx)
+	sw := w.object.NewWriter(ctx)
+	sw.ContentType = w.opts.ContentType
+	if sw.ContentType == "" {
+		sw.ContentType = ContentType(w.name)
+	}
+	sw.Metadata = w.opts.Metadata
+	if w.opts.ChunkSize < 0 {
+		sw.ChunkSize = 0
+	} else if w.opts.ChunkSize > 0 {
+		sw.ChunkSize = w.opts.ChunkSize
+	}
+	w.w, w.cancel = sw, cancel
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	if err := w.ctx.Err(); err != nil {
+		return 0, w.wrap(err)
+	}
+	if w.buf != nil {
+		return w.buf.Write(p)
+	}
+	if w.w == nil {
+		w.open()
+	}
+	n, err := w.w.Write(p)
+	return n, w.wrap(err)
+}
+
+func (w *writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	w.err = w.wrap(os.ErrClosed)
+	if w.w == nil {
+		w.open()
+	}
+	defer w.cancel()
+
+	if w.buf != nil {
+		w.w.CRC32C = crc32.Checksum(w.buf.Bytes(), castagnoli)
+		w.w.SendCRC32C = true
+		_, err := w.w.Write(w.buf.Bytes())
+		w.buf = nil
+		if err != nil {
+			w.w.Close()
+			return w.wrap(err)
+		}
+	}
+	return w.wrap(w.w.Close())
+}
+
+// Abort cancels the upload; the object isn't created (or replaced)
+func (w *writer) Abort() error {
+	w.err = w.wrap(context.Canceled)
+	w.buf = nil
+	if w.w != nil {
+		w.cancel()
+		w.w.Close() // returns the context.Canceled
+	}
+	return nil
+}
+
+func (w *writer) wrap(err error) error {
+	if err == nil {
+		return nil
+	}
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed {
+		return fmt.Errorf("%w: %s: %w", ErrObjectExists, w.name, err)
+	}
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusBadRequest && strings.Contains(apiErr.Message, "CRC32C") {
+		return fmt.Errorf("%w: %s: %w", ErrChecksumMismatch, w.name, err)
+	}
+	return fmt.Errorf("gcs object %s: %w", w.name, err)
+}
+
+// contentTypes are the types of the extensions produced by this module (which mime.TypeByExtension mostly lacks)
+var contentTypes = map[string]string{
+	".avro":    "application/avro",
+	".csv":     "text/csv",
+	".gob":     "application/octet-stream",
+	".gz":      "application/gzip",
+	".json":    "application/json",
+	".jsonl":   "application/x-ndjson",
+	".msgpack": "application/msgpack",
+	".ndjson":  "application/x-ndjson",
+	".parquet": "application/vnd.apache.parquet",
+	".pb":      "application/x-protobuf",
+	".sz":      "application/x-snappy-framed",
+	".txt":     "text/plain; charset=utf-8",
+	".zst":     "application/zstd",
+}
+
+// ContentType infers the content type from the extension of the path; compressed files get the type of the
+// compression (e.g. application/gzip) since their content isn't transcoded. Unknown extensions are application/octet-stream.
+func ContentType(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	if contentType, ok := contentTypes[ext]; ok {
+		return contentType
+	}
+	if contentType := mime.TypeByExtension(ext); contentType != "" {
+		return contentType
 	}
+	return "application/octet-stream"
 }
@@ -0,0 +1,132 @@
+package gcswf
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/kvanticoss/goutils/v2/gcstest"
+	"github.com/kvanticoss/goutils/v2/writerfactory"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func write(wf writerfactory.WriterFactory, name string, content []byte) error {
+	w, err := wf(name)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(content); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func TestGCSWriterFactoryWithOptions(t *testing.T) {
+	server := gcstest.NewServer()
+	defer server.Close()
+	client, err := server.Client(context.Background())
+	assert.NoError(t, err)
+
+	wf := GetGCSWriterFactoryWithOptions(context.Background(), client.Bucket("bucket"), Options{
+		Metadata: map[string]string{"job": "test"},
+		CRC32C:   true,
+	})
+	assert.NoError(t, write(wf, "dir/part_000000.ndjson", []byte(`{"a":1}`+"\n")))
+
+	object, ok := server.Object("bucket", "dir/part_000000.ndjson")
+	assert.True(t, ok)
+	assert.Equal(t, `{"a":1}`+"\n", string(object.Content))
+	assert.Equal(t, "application/x-ndjson", object.ContentType)
+	assert.Equal(t, map[string]string{"job": "test"}, object.Metadata)
+}
+
+func TestGCSWriterFactoryChunks(t *testing.T) {
+	server := gcstest.NewServer()
+	defer server.Close()
+	client, err := server.Client(context.Background())
+	assert.NoError(t, err)
+
+	content := bytes.Repeat([]byte("0123456789"), 60*1024) // > 2 chunks of 256KiB
+	wf := GetGCSWriterFactoryWithOptions(context.Background(), client.Bucket("bucket"), Options{ChunkSize: 1, CRC32C: true})
+	assert.NoError(t, write(wf, "big.bin", content))
+
+	object, _ := server.Object("bucket", "big.bin")
+	assert.Equal(t, content, object.Content)
+	chunks := 0
+	for _, request := range server.Requests() {
+		if strings.Contains(request, "upload_id=") {
+			chunks++
+		}
+	}
+	assert.Equal(t, 3, chunks, "ChunkSize is rounded up to 256KiB")
+}
+
+func TestGCSWriterFactoryDoesNotExist(t *testing.T) {
+	server := gcstest.NewServer()
+	defer server.Close()
+	client, err := server.Client(context.Background())
+	assert.NoError(t, err)
+	server.PutObject("bucket", "existing.txt", []byte("original"))
+
+	wf := GetGCSWriterFactoryWithOptions(context.Background(), client.Bucket("bucket"), Options{DoesNotExist: true})
+	err = write(wf, "existing.txt", []byte("replaced"))
+	assert.ErrorIs(t, err, ErrObjectExists)
+	assert.ErrorIs(t, err, fs.ErrExist)
+	object, _ := server.Object("bucket", "existing.txt")
+	assert.Equal(t, "original", string(object.Content))
+
+	assert.NoError(t, write(wf, "new.txt", []byte("new")))
+}
+
+func TestGCSWriterFactoryChecksumMismatch(t *testing.T) {
+	server := gcstest.NewServer()
+	defer server.Close()
+	client, err := server.Client(context.Background())
+	assert.NoError(t, err)
+	server.PutObject("bucket", "file.txt", []byte("original"))
+	server.CorruptUploads = true
+
+	wf := GetGCSWriterFactoryWithOptions(context.Background(), client.Bucket("bucket"), Options{CRC32C: true})
+	assert.ErrorIs(t, write(wf, "file.txt", []byte("content")), ErrChecksumMismatch)
+	object, ok := server.Object("bucket", "file.txt")
+	assert.True(t, ok)
+	assert.Equal(t, "original", string(object.Content), "the existing object is kept")
+}
+
+func TestGCSWriterFactoryAbort(t *testing.T) {
+	server := gcstest.NewServer()
+	defer server.Close()
+	client, err := server.Client(context.Background())
+	assert.NoError(t, err)
+
+	wf := GetGCSWriterFactory(context.Background(), client.Bucket("bucket"))
+	w, err := wf("file.txt")
+	assert.NoError(t, err)
+	w.Write([]byte("content"))
+	assert.NoError(t, w.(writerfactory.Aborter).Abort())
+	_, err = w.Write([]byte("more"))
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, ok := server.Object("bucket", "file.txt")
+	assert.False(t, ok)
+
+	// Nothing is uploaded before the first Write
+	w, err = wf("empty.txt")
+	assert.NoError(t, err)
+	assert.Nil(t, w.(*writer).w)
+	assert.NoError(t, w.(writerfactory.Aborter).Abort())
+	_, err = w.Write([]byte("more"))
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, server.Requests())
+}
+
+func TestContentType(t *testing.T) {
+	assert.Equal(t, "application/x-ndjson", ContentType("a/b.ndjson"))
+	assert.Equal(t, "application/gzip", ContentType("a/b.ndjson.gz"))
+	assert.Equal(t, "text/csv", ContentType("b.CSV"))
+	assert.Equal(t, "application/octet-stream", ContentType("b"))
+}
@@ -7,14 +7,16 @@ import (
 )
 
 // GetLocalWriterFactory returns a writer factory which creates local files in the basePath.
+// Paths are sanitized (see JoinLocalPath); absolute paths and paths escaping basePath are rejected with a *PathError.
 // If basePath == "" it defaults to current working dir ("./")
 func GetLocalWriterFactory(basePath string) WriterFactory {
-	if basePath == "" {
-		basePath = "./"
-	}
 	return func(path string) (wc io.WriteCloser, err error) {
-		os.MkdirAll(filepath.Dir(basePath+path), os.ModePerm)
-		w, err := os.Create(basePath + path)
+		fullPath, err := JoinLocalPath(basePath, path)
+		if err != nil {
+			return nil, err
+		}
+		os.MkdirAll(filepath.Dir(fullPath), os.ModePerm)
+		w, err := os.Create(fullPath)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,135 @@
+package writerfactory
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/kvanticoss/goutils/v2/internal/layered"
+)
+
+// Aborter is implemented by writers which can be discarded without making their content visible
+type Aborter = layered.Aborter
+
+// GetAtomicLocalWriterFactory returns a writer factory which creates local files in the basePath atomically.
+// Content is written to a hidden temp file (.{name}.tmp-*) in the target directory which on Close is fsynced and
+// renamed into place (followed by an fsync of the directory); readers never observe partially written files.
+// The temp file is removed if a Write or the Close fails or if the writer is aborted (see AtomicFile.Abort); writers
+// which are neither closed nor aborted leave their temp file behind.
+// Paths are sanitized as by GetLocalWriterFactory.
+// If basePath == "" it defaults to current working dir ("./")
+func GetAtomicLocalWriterFactory(basePath string) WriterFactory {
+	return func(path string) (wc io.WriteCloser, err error) {
+		fullPath, err := JoinLocalPath(basePath, path)
+		if err != nil {
+			return nil, err
+		}
+		return NewAtomicFile(fullPath)
+	}
+}
+
+// AtomicFile is a file which becomes visible under its name only once successfully closed
+type AtomicFile struct {
+	name string
+	tmp  *os.File
+
+	mutex sync.Mutex
+	err   error // first write error or ErrAborted / os.ErrClosed once done
+}
+
+// NewAtomicFile creates the temp file (and any missing parent directories) for name
+func NewAtomicFile(name string) (*AtomicFile, error) {
+	dir, base := filepath.Split(name)
+	if dir == "" {
+		dir = "."
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(dir, "."+base+".tmp-*")
+	if err != nil {
+		return nil, err
+	}
+	return &AtomicFile{name: name, tmp: tmp}, nil
+}
+
+// Write writes to the temp file. A failed write removes the temp file; the error is returned by subsequent calls,
+// including Close.
+func (f *AtomicFile) Write(p []byte) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.err != nil {
+		return 0, f.err
+	}
+	n, err := f.tmp.Write(p)
+	if err != nil {
+		f.discard()
+		f.err = err
+	}
+	return n, err
+}
+
+// Close syncs the temp file and renames it into place. On failure the temp file is removed.
+func (f *AtomicFile) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.err != nil {
+		if f.err == os.ErrClosed || f.err == ErrAborted {
+			return f.err
+		}
+		err := f.err
+		f.err = ErrAborted // the temp file was removed by the failed Write
+		return err
+	}
+
+	err := f.tmp.Sync()
+	if closeErr := f.tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.tmp.Name(), f.name)
+	}
+	if err != nil {
+		os.Remove(f.tmp.Name())
+		f.err = ErrAborted
+		return err
+	}
+	f.err = os.ErrClosed
+	return syncDir(filepath.Dir(f.name))
+}
+
+// Abort discards the written content and removes the temp file. Aborting a closed file is a no-op.
+func (f *AtomicFile) Abort() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.err == os.ErrClosed || f.err == ErrAborted {
+		return nil
+	}
+	if f.err != nil {
+		f.err = ErrAborted // the temp file was removed by the failed Write
+		return nil
+	}
+	f.err = ErrAborted
+	return f.discard()
+}
+
+// discard closes and removes the temp file
+func (f *AtomicFile) discard() error {
+	f.tmp.Close()
+	return os.Remove(f.tmp.Name())
+}
+
+// syncDir makes a rename in the directory durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil && !errors.Is(err, os.ErrInvalid) {
+		return err // some platforms (e.g. windows) don't support syncing directories
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package writerfactory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAtomicLocalWriterFactory(t *testing.T) {
+	base := t.TempDir() + "/"
+	wf := GetAtomicLocalWriterFactory(base)
+
+	w, err := wf("sub/file.txt")
+	assert.NoError(t, err)
+	_, err = w.Write([]byte("testing string"))
+	assert.NoError(t, err)
+
+	_, err = os.Stat(base + "sub/file.txt")
+	assert.True(t, os.IsNotExist(err), "the file must not be visible before Close")
+	tmps, _ := filepath.Glob(base + "sub/.file.txt.tmp-*")
+	assert.Len(t, tmps, 1, "content is written to a hidden temp file")
+
+	assert.NoError(t, w.Close())
+	content, err := os.ReadFile(base + "sub/file.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "testing string", string(content))
+
+	entries, _ := os.ReadDir(base + "sub")
+	assert.Len(t, entries, 1, "no temp files should remain")
+	assert.ErrorIs(t, w.Close(), os.ErrClosed)
+}
+
+func TestAtomicLocalWriterFactoryAbort(t *testing.T) {
+	base := t.TempDir() + "/"
+	wf := GetAtomicLocalWriterFactory(base)
+
+	w, err := wf("file.txt")
+	assert.NoError(t, err)
+	_, err = w.Write([]byte("testing string"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.(Aborter).Abort())
+
+	entries, _ := os.ReadDir(base)
+	assert.Len(t, entries, 0, "aborting must remove the temp file")
+	assert.ErrorIs(t, w.Close(), ErrAborted)
+	_, err = w.Write([]byte("more"))
+	assert.ErrorIs(t, err, ErrAborted)
+}
+
+func TestAtomicLocalWriterFactoryAbortWithGzip(t *testing.T) {
+	base := t.TempDir() + "/"
+	wf := GetAtomicLocalWriterFactory(base).WithGzip()
+
+	w, err := wf("file.txt")
+	assert.NoError(t, err)
+	_, err = w.Write([]byte("testing string"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.(Aborter).Abort())
+
+	entries, _ := os.ReadDir(base)
+	assert.Len(t, entries, 0, "aborting must cascade through gzip")
+}
+
+func TestAtomicLocalWriterFactoryFailedWrite(t *testing.T) {
+	base := t.TempDir() + "/"
+	w, err := GetAtomicLocalWriterFactory(base)("file.txt")
+	assert.NoError(t, err)
+	w.(*AtomicFile).tmp.Close() // make the next write fail
+
+	_, err = w.Write([]byte("testing string"))
+	assert.Error(t, err)
+	entries, _ := os.ReadDir(base)
+	assert.Len(t, entries, 0, "a failed write must remove the temp file")
+
+	assert.Equal(t, err, w.Close(), "Close returns the write error")
+	assert.ErrorIs(t, w.Close(), ErrAborted)
+	assert.NoError(t, w.(Aborter).Abort())
+}
@@ -0,0 +1,222 @@
+package s3wf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/kvanticoss/goutils/v2/s3"
+	"github.com/kvanticoss/goutils/v2/writerfactory"
+)
+
+const (
+	// DefaultPartSize is the size of the parts of multipart uploads; S3 requires at least 5MiB for all but the last part
+	DefaultPartSize = 8 * 1024 * 1024
+	// DefaultConcurrency is the number of parts uploaded in parallel per writer
+	DefaultConcurrency = 4
+	// MinPartSize is the smallest part size S3 accepts for all but the last part
+	MinPartSize = 5 * 1024 * 1024
+	// MaxPartSize is the largest part size S3 accepts
+	MaxPartSize = 5 * 1024 * 1024 * 1024
+	// MaxParts is the max number of parts of a multipart upload
+	MaxParts = 10000
+)
+
+// ErrTooManyParts is returned by Write when a stream would need more than MaxParts parts; use a larger PartSize
+var ErrTooManyParts = errors.New("s3 multipart upload exceeds the max number of parts")
+
+// the limits are variables so tests can use small parts
+var (
+	minPartSize = MinPartSize
+	maxParts    = MaxParts
+)
+
+// Options configures the uploads
+type Options struct {
+	// PartSize is the size at which streams are split into multipart upload parts; streams smaller than PartSize are
+	// uploaded in a single request. Defaults to DefaultPartSize and is clamped to [MinPartSize, MaxPartSize]. Streams
+	// are limited to MaxParts parts, i.e. PartSize * MaxParts bytes.
+	PartSize int
+	// Concurrency is the max number of parts uploaded in parallel (and buffered in memory) per writer.
+	// Defaults to DefaultConcurrency
+	Concurrency int
+}
+
+// GetS3WriterFactory returns a writerfactory, pointing to the root of the S3 bucket.
+// Objects only become visible once the writer is closed successfully. On errors (or Abort) multipart uploads
+// are aborted so no parts are left behind.
+func GetS3WriterFactory(ctx context.Context, bucket *s3.Bucket, opts Options) writerfactory.WriterFactory {
+	if opts.PartSize <= 0 {
+		opts.PartSize = DefaultPartSize
+	} else if opts.PartSize < minPartSize {
+		opts.PartSize = minPartSize
+	} else if int64(opts.PartSize) > MaxPartSize {
+		opts.PartSize = MaxPartSize
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultConcurrency
+	}
+	return func(path string) (wc io.WriteCloser, err error) {
+		return &writer{
+			ctx:    ctx,
+			bucket: bucket,
+			key:    path,
+			opts:   opts,
+			sem:    make(chan struct{}, opts.Concurrency),
+		}, nil
+	}
+}
+
+// writer buffers a part at the time and uploads full parts in the background
+type writer struct {
+	ctx    context.Context
+	bucket *s3.Bucket
+	key    string
+	opts   Options
+
+	buf        []byte
+	uploadID   string
+	partNumber int
+	sem        chan struct{}
+	wg         sync.WaitGroup
+	done       bool
+
+	mutex sync.Mutex // guards parts and err which are updated by the uploads
+	parts []s3.Part
+	err   error
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if w.done {
+		return 0, os.ErrClosed
+	}
+	if err := w.getErr(); err != nil {
+		return 0, err
+	}
+	written := len(p)
+	for len(p) > 0 {
+		if w.buf == nil {
+			w.buf = make([]byte, 0, w.opts.PartSize)
+		}
+		n := w.opts.PartSize - len(w.buf)
+		if n > len(p) {
+			n = len(p)
+		}
+		w.buf = append(w.buf, p[:n]...)
+		p = p[n:]
+		if len(w.buf) == w.opts.PartSize && len(p) > 0 { // the last part is uploaded by Close
+			if err := w.uploadPart(); err != nil {
+				return written - len(p), err
+			}
+		}
+	}
+	return written, nil
+}
+
+// uploadPart uploads the buffer as the next part; blocks while Concurrency parts are being uploaded
+func (w *writer) uploadPart() error {
+	if w.uploadID == "" {
+		uploadID, err := w.bucket.CreateMultipartUpload(w.ctx, w.key)
+		if err != nil {
+			w.setErr(err)
+			return err
+		}
+		w.uploadID = uploadID
+	}
+
+	if w.partNumber == maxParts {
+		err := fmt.Errorf("%w: %d parts of %d bytes", ErrTooManyParts, maxParts, w.opts.PartSize)
+		w.setErr(err)
+		return err
+	}
+	w.partNumber++
+	partNumber, data := w.partNumber, w.buf
+	w.buf = nil
+
+	w.sem <- struct{}{}
+	w.wg.Add(1)
+	go func() {
+		defer func() {
+			<-w.sem
+			w.wg.Done()
+		}()
+		if w.getErr() != nil {
+			return
+		}
+		part, err := w.bucket.UploadPart(w.ctx, w.key, w.uploadID, partNumber, data)
+		w.mutex.Lock()
+		defer w.mutex.Unlock()
+		if err != nil {
+			if w.err == nil {
+				w.err = err
+			}
+			return
+		}
+		w.parts = append(w.parts, part)
+	}()
+	return w.getErr()
+}
+
+// Close uploads the remaining data and completes the upload; on failure the upload is aborted
+func (w *writer) Close() error {
+	if w.done {
+		if w.getErr() == writerfactory.ErrAborted {
+			return writerfactory.ErrAborted
+		}
+		return os.ErrClosed
+	}
+	if w.uploadID == "" && w.getErr() == nil {
+		w.done = true
+		return w.bucket.PutObject(w.ctx, w.key, w.buf)
+	}
+
+	if len(w.buf) > 0 && w.getErr() == nil {
+		w.uploadPart()
+	}
+	w.wg.Wait()
+	if err := w.getErr(); err != nil {
+		w.Abort()
+		return err
+	}
+	w.done = true
+
+	sort.Slice(w.parts, func(i, j int) bool { return w.parts[i].PartNumber < w.parts[j].PartNumber })
+	if err := w.bucket.CompleteMultipartUpload(w.ctx, w.key, w.uploadID, w.parts); err != nil {
+		w.bucket.AbortMultipartUpload(context.Background(), w.key, w.uploadID)
+		return err
+	}
+	return nil
+}
+
+// Abort discards the written data and aborts the multipart upload (if started); implements writerfactory.Aborter
+func (w *writer) Abort() error {
+	if w.done {
+		return nil
+	}
+	w.done = true
+	w.setErr(writerfactory.ErrAborted)
+	w.wg.Wait()
+	if w.uploadID == "" {
+		return nil
+	}
+	// the ctx may be what caused the abort; still clean up
+	return w.bucket.AbortMultipartUpload(context.Background(), w.key, w.uploadID)
+}
+
+func (w *writer) getErr() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.err
+}
+
+func (w *writer) setErr(err error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.err == nil {
+		w.err = err
+	}
+}
@@ -0,0 +1,133 @@
+package s3wf
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/kvanticoss/goutils/v2/s3/s3test"
+	"github.com/kvanticoss/goutils/v2/writerfactory"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	minPartSize = 1 // the fake server accepts small parts
+}
+
+func countRequests(server *s3test.Server, prefix string) int {
+	count := 0
+	for _, request := range server.Requests() {
+		if strings.HasPrefix(request, prefix) {
+			count++
+		}
+	}
+	return count
+}
+
+func TestS3WriterFactorySinglePut(t *testing.T) {
+	server := s3test.NewServer()
+	defer server.Close()
+	wf := GetS3WriterFactory(context.Background(), server.Bucket("bucket"), Options{PartSize: 10})
+
+	w, err := wf("dir/file.txt")
+	assert.NoError(t, err)
+	_, err = w.Write([]byte("0123456789"))
+	assert.NoError(t, err)
+	_, ok := server.Object("bucket", "dir/file.txt")
+	assert.False(t, ok, "objects are only visible after Close")
+	assert.NoError(t, w.Close())
+
+	content, _ := server.Object("bucket", "dir/file.txt")
+	assert.Equal(t, "0123456789", string(content))
+	assert.Equal(t, []string{"PUT /bucket/dir/file.txt"}, server.Requests(), "streams up to PartSize are uploaded with a single PUT")
+}
+
+func TestS3WriterFactoryMultipart(t *testing.T) {
+	server := s3test.NewServer()
+	defer server.Close()
+	wf := GetS3WriterFactory(context.Background(), server.Bucket("bucket"), Options{PartSize: 4, Concurrency: 2})
+
+	expected := &bytes.Buffer{}
+	w, err := wf("file.txt")
+	assert.NoError(t, err)
+	for _, chunk := range []string{"a", "bcdefghij", "klmnopqrstuvwxy", "z"} {
+		_, err = w.Write([]byte(chunk))
+		assert.NoError(t, err)
+		expected.WriteString(chunk)
+	}
+	assert.NoError(t, w.Close())
+
+	content, _ := server.Object("bucket", "file.txt")
+	assert.Equal(t, expected.String(), string(content))
+	assert.Equal(t, 7, countRequests(server, "PUT /bucket/file.txt?partNumber="))
+	assert.Equal(t, 0, server.PendingUploads())
+}
+
+func TestS3WriterFactoryAbortsOnError(t *testing.T) {
+	server := s3test.NewServer()
+	defer server.Close()
+	server.Fail = func(r *http.Request) bool {
+		return r.URL.Query().Get("partNumber") == "2"
+	}
+	wf := GetS3WriterFactory(context.Background(), server.Bucket("bucket"), Options{PartSize: 4, Concurrency: 1})
+
+	w, err := wf("file.txt")
+	assert.NoError(t, err)
+	w.Write([]byte("0123456789abcdef"))
+	assert.Error(t, w.Close())
+
+	_, ok := server.Object("bucket", "file.txt")
+	assert.False(t, ok)
+	assert.Equal(t, 0, server.PendingUploads(), "failed uploads must be aborted")
+	assert.Equal(t, 1, countRequests(server, "DELETE /bucket/file.txt?uploadId="))
+}
+
+func TestS3WriterFactoryAbort(t *testing.T) {
+	server := s3test.NewServer()
+	defer server.Close()
+	wf := GetS3WriterFactory(context.Background(), server.Bucket("bucket"), Options{PartSize: 4})
+
+	w, err := wf("file.txt")
+	assert.NoError(t, err)
+	w.Write([]byte("0123456789"))
+	assert.NoError(t, w.(writerfactory.Aborter).Abort())
+	assert.ErrorIs(t, w.Close(), writerfactory.ErrAborted)
+
+	_, ok := server.Object("bucket", "file.txt")
+	assert.False(t, ok)
+	assert.Equal(t, 0, server.PendingUploads())
+}
+
+func TestS3WriterFactoryPartLimits(t *testing.T) {
+	defer func(min, max int) { minPartSize, maxParts = min, max }(minPartSize, maxParts)
+	minPartSize, maxParts = MinPartSize, 2
+
+	server := s3test.NewServer()
+	defer server.Close()
+	for size, expected := range map[int]int{1: MinPartSize, MinPartSize + 1: MinPartSize + 1} {
+		w, err := GetS3WriterFactory(context.Background(), server.Bucket("bucket"), Options{PartSize: size})("file.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, expected, w.(*writer).opts.PartSize, "part sizes below the S3 minimum are clamped")
+		assert.NoError(t, w.(writerfactory.Aborter).Abort())
+	}
+
+	minPartSize = 1
+	wf := GetS3WriterFactory(context.Background(), server.Bucket("bucket"), Options{PartSize: 4, Concurrency: 1})
+	w, err := wf("file.txt")
+	assert.NoError(t, err)
+	_, err = w.Write([]byte("0123456789ab0"))
+	assert.ErrorIs(t, err, ErrTooManyParts)
+	assert.ErrorIs(t, w.Close(), ErrTooManyParts)
+	_, ok := server.Object("bucket", "file.txt")
+	assert.False(t, ok)
+	assert.Equal(t, 0, server.PendingUploads(), "the upload is aborted")
+
+	w, err = wf("exact.txt")
+	assert.NoError(t, err)
+	_, err = w.Write([]byte("01234567"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close(), "exactly MaxParts parts")
+}
@@ -0,0 +1,112 @@
+package writerfactory
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PathRules are the naming rules of a storage backend enforced by SanitizePath
+type PathRules struct {
+	// MaxLength is the max length in bytes of the whole path; 0 is unlimited
+	MaxLength int
+	// MaxSegmentLength is the max length in bytes of each "/" separated segment; 0 is unlimited
+	MaxSegmentLength int
+	// ForbiddenChars may not occur in paths; control characters and invalid UTF-8 are always forbidden
+	ForbiddenChars string
+}
+
+var (
+	// LocalPathRules are the rules of common local filesystems. Backslashes are forbidden as they are separators
+	// on Windows.
+	LocalPathRules = PathRules{MaxLength: 4096, MaxSegmentLength: 255, ForbiddenChars: `\`}
+	// GCSPathRules follow https://cloud.google.com/storage/docs/objects#naming; including the characters it
+	// recommends to avoid
+	GCSPathRules = PathRules{MaxLength: 1024, ForbiddenChars: "#[]*?"}
+	// S3PathRules follow https://docs.aws.amazon.com/AmazonS3/latest/userguide/object-keys.html; including the
+	// characters it recommends to avoid except "%" which occurs in escaped partition values
+	S3PathRules = PathRules{MaxLength: 1024, ForbiddenChars: "\\{}^`[]\"<>~#|"}
+)
+
+// SanitizePath cleans p ("a//b/./c" becomes "a/b/c") and rejects empty, absolute and traversing ("..") paths as well
+// as paths breaking the rules. Rejected paths are returned as a *PathError.
+func SanitizePath(p string, rules PathRules) (string, error) {
+	if err := checkPath(p, rules); err != nil {
+		return "", &PathError{Path: p, Err: err}
+	}
+	return path.Clean(p), nil
+}
+
+func checkPath(p string, rules PathRules) error {
+	if p == "" {
+		return ErrEmptyPath
+	}
+	if !utf8.ValidString(p) {
+		return fmt.Errorf("%w: invalid UTF-8", ErrForbiddenCharacter)
+	}
+	if strings.HasPrefix(p, "/") || strings.HasPrefix(p, `\`) || filepath.VolumeName(p) != "" ||
+		(len(p) >= 2 && p[1] == ':' && unicode.IsLetter(rune(p[0]))) { // a Windows drive regardless of GOOS
+		return ErrAbsolutePath
+	}
+	for _, segment := range strings.FieldsFunc(p, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if segment == ".." {
+			return ErrPathTraversal
+		}
+	}
+	for _, r := range p {
+		if unicode.IsControl(r) || strings.ContainsRune(rules.ForbiddenChars, r) {
+			return fmt.Errorf("%w: %q", ErrForbiddenCharacter, r)
+		}
+	}
+
+	cleaned := path.Clean(p)
+	if cleaned == "." {
+		return ErrEmptyPath
+	}
+	if rules.MaxLength > 0 && len(cleaned) > rules.MaxLength {
+		return fmt.Errorf("%w: %d > %d bytes", ErrPathTooLong, len(cleaned), rules.MaxLength)
+	}
+	if rules.MaxSegmentLength > 0 {
+		for _, segment := range strings.Split(cleaned, "/") {
+			if len(segment) > rules.MaxSegmentLength {
+				return fmt.Errorf("%w: segment %d > %d bytes", ErrPathTooLong, len(segment), rules.MaxSegmentLength)
+			}
+		}
+	}
+	return nil
+}
+
+// JoinLocalPath sanitizes p with LocalPathRules and joins it with basePath ("./" if empty). basePath is treated as
+// a directory whether or not it ends with a separator.
+func JoinLocalPath(basePath, p string) (string, error) {
+	cleaned, err := SanitizePath(p, LocalPathRules)
+	if err != nil {
+		return "", err
+	}
+	if basePath == "" {
+		basePath = "./"
+	}
+	return filepath.Join(basePath, filepath.FromSlash(cleaned)), nil
+}
+
+// WithSanitizedPaths cleans the paths given to the underlying WriterFactory and rejects paths which are empty,
+// absolute, traverse out of the root ("..") or break the rules with a *PathError; e.g. to protect against partition
+// values derived from records.
+func WithSanitizedPaths(wf WriterFactory, rules PathRules) WriterFactory {
+	return func(p string) (io.WriteCloser, error) {
+		cleaned, err := SanitizePath(p, rules)
+		if err != nil {
+			return nil, err
+		}
+		return wf(cleaned)
+	}
+}
+
+// WithSanitizedPaths cleans and validates paths; see WithSanitizedPaths
+func (wf WriterFactory) WithSanitizedPaths(rules PathRules) WriterFactory {
+	return WithSanitizedPaths(wf, rules)
+}
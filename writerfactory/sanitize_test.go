@@ -0,0 +1,92 @@
+package writerfactory
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizePath(t *testing.T) {
+	for p, expected := range map[string]string{
+		"a/b/c.txt":                    "a/b/c.txt",
+		"a//b/./c.txt":                 "a/b/c.txt",
+		"./a/b/":                       "a/b",
+		"date=2023-01-01T10:00/x.json": "date=2023-01-01T10:00/x.json",
+		"country=..%2Fetc/part":        "country=..%2Fetc/part",
+		"dots..in..name":               "dots..in..name",
+	} {
+		cleaned, err := SanitizePath(p, LocalPathRules)
+		assert.NoError(t, err, p)
+		assert.Equal(t, expected, cleaned, p)
+	}
+
+	for p, expected := range map[string]error{
+		"":                              ErrEmptyPath,
+		".":                             ErrEmptyPath,
+		"/etc/passwd":                   ErrAbsolutePath,
+		`\\server\share`:                ErrAbsolutePath,
+		"C:/Windows":                    ErrAbsolutePath,
+		"../escape":                     ErrPathTraversal,
+		"a/../../escape":                ErrPathTraversal,
+		"a/b/..":                        ErrPathTraversal,
+		`a\..\escape`:                   ErrPathTraversal,
+		"new\nline":                     ErrForbiddenCharacter,
+		"nul\x00byte":                   ErrForbiddenCharacter,
+		"invalid\xffutf8":               ErrForbiddenCharacter,
+		`back\slash`:                    ErrForbiddenCharacter,
+		strings.Repeat("a", 256) + "/b": ErrPathTooLong,
+	} {
+		_, err := SanitizePath(p, LocalPathRules)
+		assert.True(t, errors.Is(err, expected), "%q: %v", p, err)
+		var pathErr *PathError
+		if assert.True(t, errors.As(err, &pathErr), p) {
+			assert.Equal(t, p, pathErr.Path)
+		}
+	}
+
+	_, err := SanitizePath("file#1", GCSPathRules)
+	assert.True(t, errors.Is(err, ErrForbiddenCharacter))
+	_, err = SanitizePath(strings.Repeat("a/", 512)+"a", S3PathRules)
+	assert.True(t, errors.Is(err, ErrPathTooLong))
+	_, err = SanitizePath("escaped%2Fvalue", S3PathRules)
+	assert.NoError(t, err)
+}
+
+func TestWithSanitizedPaths(t *testing.T) {
+	buffers, rawWF := GetMemoryWriterFactory()
+	wf := rawWF.WithSanitizedPaths(GCSPathRules)
+
+	w, err := wf("a//b/./c")
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	_, ok := buffers["a/b/c"]
+	assert.True(t, ok)
+
+	_, err = wf("../c")
+	assert.True(t, errors.Is(err, ErrPathTraversal))
+	assert.Len(t, buffers, 1)
+}
+
+func TestLocalWriterFactoryRejectsTraversal(t *testing.T) {
+	root := t.TempDir()
+	base := filepath.Join(root, "base") // no trailing separator
+
+	w, err := GetLocalWriterFactory(base)("sub/file")
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	_, err = os.Stat(filepath.Join(base, "sub", "file"))
+	assert.NoError(t, err, "basePath is a directory even without a trailing separator")
+
+	for _, wf := range []WriterFactory{GetLocalWriterFactory(base), GetAtomicLocalWriterFactory(base)} {
+		_, err = wf("sub/../../escaped")
+		assert.True(t, errors.Is(err, ErrPathTraversal))
+		_, err = wf("/tmp/absolute")
+		assert.True(t, errors.Is(err, ErrAbsolutePath))
+	}
+	_, err = os.Stat(filepath.Join(root, "escaped"))
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
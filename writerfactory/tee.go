@@ -0,0 +1,199 @@
+package writerfactory
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/kvanticoss/goutils/v2/internal/layered"
+)
+
+// TeePolicy decides how failing secondary backends are handled by Tee writers. Failures of the primary backend
+// always fail the writer.
+type TeePolicy int
+
+const (
+	// TeeFailAll fails the writer as soon as any backend fails; the other backends are aborted (or closed)
+	TeeFailAll TeePolicy = iota
+	// TeeContinue stops writing to failed secondaries and continues with the others. The failures are reported on
+	// Close to TeeOptions.OnSecondaryErrors instead of failing the writer.
+	TeeContinue
+)
+
+// TeeOptions configures TeeWithOptions
+type TeeOptions struct {
+	Policy TeePolicy
+	// OnSecondaryErrors receives the failures of secondaries on Close when using TeeContinue; defaults to log.Print
+	OnSecondaryErrors func(err *TeeError)
+}
+
+// TeeError holds the failures of the backends of a Tee writer by index; 0 is the primary and 1.. the secondaries
+type TeeError struct {
+	Path   string
+	Errors map[int]error
+}
+
+func (e *TeeError) Error() string {
+	indexes := make([]int, 0, len(e.Errors))
+	for index := range e.Errors {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	msgs := make([]string, 0, len(indexes))
+	for _, index := range indexes {
+		msgs = append(msgs, fmt.Sprintf("backend %d: %v", index, e.Errors[index]))
+	}
+	return fmt.Sprintf("tee %s: %s", e.Path, strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the errors of the backends in index order
+func (e *TeeError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for index := 0; len(errs) < len(e.Errors); index++ {
+		if err, ok := e.Errors[index]; ok {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// Tee returns a writer factory whose writers write all bytes to the writers of all the factories (e.g. for dual
+// writes during migrations). Any failing backend fails the writer (TeeFailAll).
+func Tee(primary WriterFactory, secondaries ...WriterFactory) WriterFactory {
+	return TeeWithOptions(TeeOptions{}, primary, secondaries...)
+}
+
+// TeeWithOptions is Tee with a configurable policy for failing secondaries
+func TeeWithOptions(opts TeeOptions, primary WriterFactory, secondaries ...WriterFactory) WriterFactory {
+	if opts.OnSecondaryErrors == nil {
+		opts.OnSecondaryErrors = func(err *TeeError) { log.Print(err) }
+	}
+	factories := append([]WriterFactory{primary}, secondaries...)
+	return func(path string) (io.WriteCloser, error) {
+		t := &teeWriter{opts: opts, path: path, writers: make([]io.WriteCloser, len(factories)), errs: map[int]error{}}
+		for index, wf := range factories {
+			w, err := wf(path)
+			if err != nil {
+				if index == 0 || opts.Policy == TeeFailAll {
+					t.abortAll()
+					return nil, err
+				}
+				t.errs[index] = err
+				continue
+			}
+			t.writers[index] = w
+		}
+		return t, nil
+	}
+}
+
+type teeWriter struct {
+	opts    TeeOptions
+	path    string
+	writers []io.WriteCloser // nil once failed
+	errs    map[int]error
+	err     error // set once the writer as a whole has failed
+}
+
+func (t *teeWriter) Write(p []byte) (int, error) {
+	if t.err != nil {
+		return 0, t.err
+	}
+	for index, w := range t.writers {
+		if w == nil {
+			continue
+		}
+		n, err := w.Write(p)
+		if err == nil && n < len(p) {
+			err = io.ErrShortWrite
+		}
+		if err != nil {
+			if err := t.fail(index, err); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(p), nil
+}
+
+// fail drops the backend; returns an error if the writer as a whole has failed
+func (t *teeWriter) fail(index int, err error) error {
+	t.errs[index] = err
+	abort(t.writers[index])
+	t.writers[index] = nil
+	if index == 0 || t.opts.Policy == TeeFailAll {
+		t.abortAll()
+		t.err = &TeeError{Path: t.path, Errors: t.errs}
+	}
+	return t.err
+}
+
+// Flush flushes all backends; failures are handled like failed writes
+func (t *teeWriter) Flush() error {
+	if t.err != nil {
+		return t.err
+	}
+	for index, w := range t.writers {
+		if w == nil {
+			continue
+		}
+		if err := layered.Flush(w); err != nil {
+			if err := t.fail(index, err); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Rotates implements Rotator; true if any backend rotates
+func (t *teeWriter) Rotates() bool {
+	for _, w := range t.writers {
+		if layered.Rotates(w) {
+			return true
+		}
+	}
+	return false
+}
+
+// Close closes all backends; closing again returns os.ErrClosed (or the error of the first Close)
+func (t *teeWriter) Close() error {
+	if t.err != nil {
+		return t.err
+	}
+	for index, w := range t.writers {
+		if w == nil {
+			continue
+		}
+		t.writers[index] = nil
+		if err := w.Close(); err != nil {
+			t.errs[index] = err
+		}
+	}
+	t.err = os.ErrClosed
+	if len(t.errs) == 0 {
+		return nil
+	}
+	if _, primaryFailed := t.errs[0]; primaryFailed || t.opts.Policy == TeeFailAll {
+		t.err = &TeeError{Path: t.path, Errors: t.errs}
+		return t.err
+	}
+	t.opts.OnSecondaryErrors(&TeeError{Path: t.path, Errors: t.errs})
+	return nil
+}
+
+// Abort aborts all backends; implements Aborter
+func (t *teeWriter) Abort() error {
+	t.abortAll()
+	return nil
+}
+
+func (t *teeWriter) abortAll() {
+	for index, w := range t.writers {
+		abort(w)
+		t.writers[index] = nil
+	}
+}
@@ -0,0 +1,95 @@
+package writerfactory
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/kvanticoss/goutils/v2/eioutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errBackend = errors.New("backend failure")
+
+// failingWriterFactory yields writers failing after failAfter writes
+func failingWriterFactory(failAfter int) WriterFactory {
+	return func(path string) (io.WriteCloser, error) {
+		writes := 0
+		return eioutil.NewWriteNOPCloser(eioutil.NewPreWriteCallback(io.Discard, func([]byte) error {
+			writes++
+			if writes > failAfter {
+				return errBackend
+			}
+			return nil
+		})), nil
+	}
+}
+
+func TestTee(t *testing.T) {
+	primary, primaryWF := GetMemoryWriterFactory()
+	secondary, secondaryWF := GetMemoryWriterFactory()
+
+	w, err := Tee(primaryWF, secondaryWF)("path")
+	assert.NoError(t, err)
+	_, err = w.Write([]byte("hello "))
+	assert.NoError(t, err)
+	_, err = w.Write([]byte("world"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	assert.Equal(t, "hello world", primary["path"].String())
+	assert.Equal(t, "hello world", secondary["path"].String())
+}
+
+func TestTeeFailAll(t *testing.T) {
+	_, primaryWF := GetMemoryWriterFactory()
+
+	w, err := Tee(primaryWF, failingWriterFactory(1))("path")
+	assert.NoError(t, err)
+	_, err = w.Write([]byte("hello "))
+	assert.NoError(t, err)
+	_, err = w.Write([]byte("world"))
+	assert.ErrorIs(t, err, errBackend)
+
+	teeErr := &TeeError{}
+	if assert.ErrorAs(t, err, &teeErr) {
+		assert.Equal(t, map[int]error{1: errBackend}, teeErr.Errors)
+	}
+	assert.Equal(t, err, w.Close())
+}
+
+func TestTeeContinue(t *testing.T) {
+	primary, primaryWF := GetMemoryWriterFactory()
+	secondary, secondaryWF := GetMemoryWriterFactory()
+
+	var reported *TeeError
+	wf := TeeWithOptions(TeeOptions{
+		Policy:            TeeContinue,
+		OnSecondaryErrors: func(err *TeeError) { reported = err },
+	}, primaryWF, failingWriterFactory(0), secondaryWF)
+
+	w, err := wf("path")
+	assert.NoError(t, err)
+	_, err = w.Write([]byte("hello"))
+	assert.NoError(t, err)
+	assert.Nil(t, reported, "errors are reported on Close")
+	assert.NoError(t, w.Close())
+
+	assert.Equal(t, "hello", primary["path"].String())
+	assert.Equal(t, "hello", secondary["path"].String())
+	if assert.NotNil(t, reported) {
+		assert.Equal(t, "path", reported.Path)
+		assert.Equal(t, map[int]error{1: errBackend}, reported.Errors)
+	}
+	reported = nil
+	assert.Equal(t, os.ErrClosed, w.Close())
+	assert.Nil(t, reported, "errors are only reported by the first Close")
+
+	// Primary failures always fail the writer
+	w, err = TeeWithOptions(TeeOptions{Policy: TeeContinue}, failingWriterFactory(0), secondaryWF)("other")
+	assert.NoError(t, err)
+	_, err = w.Write([]byte("hello"))
+	assert.ErrorIs(t, err, errBackend)
+}
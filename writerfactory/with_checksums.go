@@ -0,0 +1,102 @@
+package writerfactory
+
+import (
+	"io"
+
+	"github.com/kvanticoss/goutils/v2/checksum"
+	"github.com/kvanticoss/goutils/v2/internal/layered"
+)
+
+// WithChecksums computes the digests (SHA256 if no algorithms are given) of everything written to the writers
+// returned by the underlying WriterFactory. Once a writer has been closed a sidecar per algorithm is written next to
+// it through the underlying WriterFactory (path + ".sha256" etc, in the format of sha256sum plus the size). Use
+// readerfactory.VerifyChecksums to verify the files later on.
+func WithChecksums(wf WriterFactory, algos ...checksum.Algorithm) WriterFactory {
+	return WithChecksumsCallback(wf, writeSidecars(wf), algos...)
+}
+
+// WithChecksumsCallback computes the digests and size of everything written to the writers returned by the
+// underlying WriterFactory and calls onClose with them once a writer has been closed; e.g. to store them in a
+// database. An error from onClose is returned by Close. Aborted writers aren't reported.
+func WithChecksumsCallback(wf WriterFactory, onClose func(checksum.Checksums) error, algos ...checksum.Algorithm) WriterFactory {
+	return func(path string) (io.WriteCloser, error) {
+		hasher, err := checksum.NewHasher(algos...)
+		if err != nil {
+			return nil, err
+		}
+		w, err := wf(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := rejectRotation(w); err != nil {
+			return nil, err
+		}
+		return &checksumWriter{WriteCloser: w, path: path, hasher: hasher, onClose: onClose}, nil
+	}
+}
+
+// WithChecksums computes digests of the writers and writes them as sidecars; see WithChecksums
+func (wf WriterFactory) WithChecksums(algos ...checksum.Algorithm) WriterFactory {
+	return WithChecksums(wf, algos...)
+}
+
+func writeSidecars(wf WriterFactory) func(checksum.Checksums) error {
+	return func(checksums checksum.Checksums) error {
+		for algo, digest := range checksums.Digests {
+			w, err := wf(checksum.SidecarPath(checksums.Path, algo))
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(checksum.FormatSidecar(digest, checksums.Bytes, checksums.Path)); err != nil {
+				abort(w)
+				return err
+			}
+			if err := w.Close(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+type checksumWriter struct {
+	io.WriteCloser
+	path    string
+	hasher  *checksum.Hasher
+	onClose func(checksum.Checksums) error
+}
+
+// Write writes to the underlying writer and hashes what was written
+func (cw *checksumWriter) Write(p []byte) (int, error) {
+	n, err := cw.WriteCloser.Write(p)
+	cw.hasher.Write(p[:n])
+	return n, err
+}
+
+// Close closes the underlying writer and reports the checksums if it succeeded
+func (cw *checksumWriter) Close() error {
+	if err := cw.WriteCloser.Close(); err != nil {
+		return err
+	}
+	return cw.onClose(cw.hasher.Checksums(cw.path))
+}
+
+// Flush flushes the underlying writer
+func (cw *checksumWriter) Flush() error {
+	return layered.Flush(cw.WriteCloser)
+}
+
+// Rotates implements Rotator
+func (cw *checksumWriter) Rotates() bool {
+	return layered.Rotates(cw.WriteCloser)
+}
+
+// Abort implements Aborter; the underlying writer is closed if it can't be aborted
+func (cw *checksumWriter) Abort() error {
+	return layered.Abort(cw.WriteCloser)
+}
+
+// AddRecords implements RecordCounter
+func (cw *checksumWriter) AddRecords(n int) {
+	layered.AddRecords(cw.WriteCloser, n)
+}
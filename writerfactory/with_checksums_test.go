@@ -0,0 +1,54 @@
+package writerfactory
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kvanticoss/goutils/v2/checksum"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithChecksums(t *testing.T) {
+	buffers, rawWF := GetMemoryWriterFactory()
+
+	writer, err := rawWF.WithChecksums(checksum.SHA256, checksum.MD5)("dir/hello.txt")
+	assert.NoError(t, err)
+	writer.Write([]byte("hel"))
+	writer.Write([]byte("lo"))
+	_, ok := buffers["dir/hello.txt.sha256"]
+	assert.False(t, ok, "sidecars are written on Close")
+	assert.NoError(t, writer.Close())
+
+	assert.Equal(t, "hello", buffers["dir/hello.txt"].String())
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824  hello.txt\n# size: 5\n", buffers["dir/hello.txt.sha256"].String())
+	assert.Equal(t, "5d41402abc4b2a76b9719d911017c592  hello.txt\n# size: 5\n", buffers["dir/hello.txt.md5"].String())
+	assert.Len(t, buffers, 3)
+
+	_, err = rawWF.WithChecksums("sha1")("other")
+	assert.True(t, errors.Is(err, checksum.ErrUnknownAlgorithm))
+}
+
+func TestWithChecksumsCallback(t *testing.T) {
+	_, rawWF := GetMemoryWriterFactory()
+	reported := []checksum.Checksums{}
+	callbackErr := errors.New("callback failed")
+	wf := WithChecksumsCallback(rawWF, func(c checksum.Checksums) error {
+		reported = append(reported, c)
+		return callbackErr
+	}, checksum.CRC32C)
+
+	writer, err := wf("a")
+	assert.NoError(t, err)
+	writer.Write([]byte("hello"))
+	assert.Equal(t, callbackErr, writer.Close())
+
+	writer, err = wf("aborted")
+	assert.NoError(t, err)
+	writer.Write([]byte("hello"))
+	assert.NoError(t, writer.(Aborter).Abort())
+
+	assert.Equal(t, []checksum.Checksums{
+		{Path: "a", Bytes: 5, Digests: map[checksum.Algorithm]string{checksum.CRC32C: "9a71bb4c"}},
+	}, reported)
+}
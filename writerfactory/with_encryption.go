@@ -0,0 +1,44 @@
+package writerfactory
+
+import (
+	"io"
+	"strings"
+
+	"github.com/kvanticoss/goutils/v2/encryption"
+)
+
+// WithEncryption encrypts (AES-GCM, in authenticated chunks) the writers that is returned by the underlying
+// WriterFactory with the current key of the KeyProvider; ".enc" is appended to paths without it.
+// Compress before encrypting; e.g. wf.WithEncryption(keys).WithGzip()
+func WithEncryption(wf WriterFactory, keys encryption.KeyProvider) WriterFactory {
+	return WithEncryptionOptions(wf, keys, encryption.Options{})
+}
+
+// WithEncryptionOptions is like WithEncryption but with the options (e.g. chunk size). The underlying writer is
+// aborted if the current key can't be used.
+func WithEncryptionOptions(wf WriterFactory, keys encryption.KeyProvider, opts encryption.Options) WriterFactory {
+	return func(path string) (io.WriteCloser, error) {
+		if !strings.HasSuffix(path, ".enc") {
+			path = path + ".enc"
+		}
+
+		w, err := wf(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := rejectRotation(w); err != nil {
+			return nil, err
+		}
+		ew, err := encryption.NewWriterWithOptions(w, keys, opts)
+		if err != nil {
+			abort(w)
+			return nil, err
+		}
+		return ew, nil
+	}
+}
+
+// WithEncryption encrypts the writers that is returned by the underlying WriterFactory; see WithEncryption
+func (wf WriterFactory) WithEncryption(keys encryption.KeyProvider) WriterFactory {
+	return WithEncryption(wf, keys)
+}
@@ -0,0 +1,39 @@
+package writerfactory
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/kvanticoss/goutils/v2/encryption"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithEncryptionWriterFactory(t *testing.T) {
+	keys := encryption.NewStaticKeyProvider("k1", bytes.Repeat([]byte{1}, 32))
+	buffers, rawWF := GetMemoryWriterFactory()
+
+	writer, err := rawWF.WithEncryption(keys)("subpath")
+	assert.NoError(t, err)
+	writer.Write([]byte("testing string"))
+	assert.NoError(t, writer.Close())
+
+	r, err := encryption.NewReader(io.NopCloser(buffers["subpath.enc"]), keys)
+	assert.NoError(t, err)
+	content, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "testing string", string(content))
+}
+
+func TestWithEncryptionWriterFactoryUnknownKey(t *testing.T) {
+	base := t.TempDir() + "/"
+	keys := &encryption.StaticKeys{CurrentID: "missing"}
+
+	_, err := GetAtomicLocalWriterFactory(base).WithEncryption(keys)("subpath")
+	assert.ErrorIs(t, err, encryption.ErrUnknownKey)
+
+	entries, _ := os.ReadDir(base)
+	assert.Empty(t, entries, "the underlying writer should be aborted")
+}
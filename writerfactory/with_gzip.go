@@ -18,6 +18,9 @@ func WithGzip(wf WriterFactory) WriterFactory {
 		if err != nil {
 			return nil, err
 		}
+		if err := rejectRotation(w); err != nil {
+			return nil, err
+		}
 		return gzip.NewWriter(w), err
 	}
 }
@@ -26,3 +29,96 @@ func WithGzip(wf WriterFactory) WriterFactory {
 func (wf WriterFactory) WithGzip() WriterFactory {
 	return WithGzip(wf)
 }
+
+// WithGzipLevel is like WithGzip but with the compression level (gzip.BestSpeed...gzip.BestCompression); an invalid
+// level is returned as an error when a writer is created, without creating the underlying writer
+func WithGzipLevel(wf WriterFactory, level int) WriterFactory {
+	_, levelErr := gzip.NewWriterLevel(io.Discard, level)
+	return func(path string) (io.WriteCloser, error) {
+		if levelErr != nil {
+			return nil, levelErr
+		}
+		if !strings.HasSuffix(path, ".gz") {
+			path = path + ".gz"
+		}
+
+		w, err := wf(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := rejectRotation(w); err != nil {
+			return nil, err
+		}
+		gzWriter, err := gzip.NewWriterLevel(w, level)
+		if err != nil {
+			abort(w)
+			return nil, err
+		}
+		return gzWriter, nil
+	}
+}
+
+// WithParallelGzip is like WithGzip but where blocks of data are compressed on multiple goroutines; see
+// gzip.ParallelWriter. An invalid level is returned as an error when a writer is created, without creating the
+// underlying writer
+func WithParallelGzip(wf WriterFactory, opts gzip.ParallelOptions) WriterFactory {
+	_, optsErr := gzip.NewParallelWriter(io.Discard, opts)
+	return func(path string) (io.WriteCloser, error) {
+		if optsErr != nil {
+			return nil, optsErr
+		}
+		if !strings.HasSuffix(path, ".gz") {
+			path = path + ".gz"
+		}
+
+		w, err := wf(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := rejectRotation(w); err != nil {
+			return nil, err
+		}
+		gzWriter, err := gzip.NewParallelWriter(w, opts)
+		if err != nil {
+			abort(w)
+			return nil, err
+		}
+		return gzWriter, nil
+	}
+}
+
+// WithIndexedGzip is like WithGzip but where the stream is written as independently decompressible members with an
+// index written next to it (path + gzip.IndexExtension) on Close; see gzip.IndexedWriter. The index allows
+// gzip.IndexedReader to seek to records without decompressing the stream from the start. An invalid level is
+// returned as an error when a writer is created, without creating the underlying writers.
+func WithIndexedGzip(wf WriterFactory, opts gzip.IndexOptions) WriterFactory {
+	_, optsErr := gzip.NewIndexedWriter(io.Discard, io.Discard, opts)
+	return func(path string) (io.WriteCloser, error) {
+		if optsErr != nil {
+			return nil, optsErr
+		}
+		if !strings.HasSuffix(path, ".gz") {
+			path = path + ".gz"
+		}
+
+		w, err := wf(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := rejectRotation(w); err != nil {
+			return nil, err
+		}
+		indexWriter, err := wf(path + gzip.IndexExtension)
+		if err != nil {
+			abort(w)
+			return nil, err
+		}
+		gzWriter, err := gzip.NewIndexedWriter(w, indexWriter, opts)
+		if err != nil {
+			abort(w)
+			abort(indexWriter)
+			return nil, err
+		}
+		return gzWriter, nil
+	}
+}
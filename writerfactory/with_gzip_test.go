@@ -1,9 +1,15 @@
 package writerfactory
 
 import (
+	"bytes"
 	"compress/gzip"
+	"io"
+	"os"
+	"strings"
 	"testing"
 
+	goutilsgzip "github.com/kvanticoss/goutils/v2/gzip"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -30,3 +36,79 @@ func TestWithGzipWriterFactory(t *testing.T) {
 
 	assert.Equal(t, buffers["rawGzipWritten.gz"].Bytes(), buffers[testCase1Path+".gz"].Bytes(), "MemWriter factory should store the results in buffers")
 }
+
+func TestWithGzipLevelWriterFactory(t *testing.T) {
+	buffers, rawWF := GetMemoryWriterFactory()
+
+	writer, err := WithGzipLevel(rawWF, gzip.BestCompression)("subpath")
+	assert.NoError(t, err)
+	writer.Write([]byte("testing string"))
+	assert.NoError(t, writer.Close())
+
+	r, err := gzip.NewReader(buffers["subpath.gz"])
+	assert.NoError(t, err)
+	content, _ := io.ReadAll(r)
+	assert.Equal(t, "testing string", string(content))
+
+	_, err = WithGzipLevel(rawWF, 42)("invalid")
+	assert.Error(t, err)
+	assert.NotContains(t, buffers, "invalid.gz", "no file should be created for an invalid level")
+}
+
+func TestWithParallelGzipWriterFactory(t *testing.T) {
+	buffers, rawWF := GetMemoryWriterFactory()
+
+	writer, err := WithParallelGzip(rawWF, goutilsgzip.ParallelOptions{BlockSize: 4, Concurrency: 2})("subpath")
+	assert.NoError(t, err)
+	writer.Write([]byte("testing string"))
+	assert.NoError(t, writer.Close())
+
+	r, err := gzip.NewReader(buffers["subpath.gz"])
+	assert.NoError(t, err)
+	content, _ := io.ReadAll(r)
+	assert.Equal(t, "testing string", string(content))
+
+	_, err = WithParallelGzip(rawWF, goutilsgzip.ParallelOptions{Level: 42})("invalid")
+	assert.Error(t, err)
+	assert.NotContains(t, buffers, "invalid.gz", "no file should be created for an invalid level")
+}
+
+func TestWithIndexedGzipWriterFactory(t *testing.T) {
+	buffers, rawWF := GetMemoryWriterFactory()
+
+	writer, err := WithIndexedGzip(rawWF, goutilsgzip.IndexOptions{MemberSize: 1})("subpath")
+	assert.NoError(t, err)
+	for _, line := range []string{"first\n", "second\n"} {
+		writer.Write([]byte(line))
+		writer.(RecordCounter).AddRecords(1)
+	}
+	assert.NoError(t, writer.Close())
+
+	index, err := goutilsgzip.ReadIndex(buffers["subpath.gz.idx"])
+	assert.NoError(t, err)
+	assert.Len(t, index.Members, 2)
+
+	r := goutilsgzip.NewIndexedReader(bytes.NewReader(buffers["subpath.gz"].Bytes()), index)
+	assert.NoError(t, r.SeekRecord(1))
+	content, _ := io.ReadAll(r)
+	assert.Equal(t, "second\n", string(content))
+}
+
+func TestWithIndexedGzipWriterFactoryErrors(t *testing.T) {
+	base := t.TempDir() + "/"
+	atomic := GetAtomicLocalWriterFactory(base)
+	failingIndex := WriterFactory(func(path string) (io.WriteCloser, error) {
+		if strings.HasSuffix(path, goutilsgzip.IndexExtension) {
+			return nil, io.ErrClosedPipe
+		}
+		return atomic(path)
+	})
+
+	_, err := WithIndexedGzip(failingIndex, goutilsgzip.IndexOptions{})("subpath")
+	assert.ErrorIs(t, err, io.ErrClosedPipe)
+	_, err = WithIndexedGzip(atomic, goutilsgzip.IndexOptions{Level: 42})("invalid")
+	assert.Error(t, err)
+
+	entries, _ := os.ReadDir(base)
+	assert.Empty(t, entries, "the stream should be aborted when the index can't be created")
+}
@@ -0,0 +1,211 @@
+package writerfactory
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/kvanticoss/goutils/v2/keyvaluelist"
+)
+
+// templateNow is the clock of path templates
+var templateNow = time.Now
+
+// expansion is the state a template is expanded with for each created writer
+type expansion struct {
+	path       string
+	now        time.Time
+	seq        int64
+	partitions keyvaluelist.KeyValues
+}
+
+type placeholder func(e *expansion) (string, error)
+
+// WrapWFWithPathTemplate expands the template into the path used with the underlying WriterFactory each time a
+// writer is created. Placeholders are:
+//
+//	{path}            the path the writer was requested for
+//	{date}            current UTC date as %Y-%m-%d
+//	{hour}            current UTC hour as %H
+//	{time:FORMAT}     current UTC time in the strftime FORMAT; e.g. {time:%Y/%m/%d/%H%M%S}
+//	{uuid}            a random (v4) UUID
+//	{hostname}        the hostname
+//	{pid}             the process id
+//	{seq}             number of writers created by the factory before this one as %06d
+//	{partition:KEY}   the (query escaped) value of the hive partition KEY=value in the requested path (e.g. from
+//	                  keyvaluelist.MaybePartitions); values which would traverse the path ("." or "..") yield a *PathError
+//	{partitions}      all the hive partitions of the requested path as key1=val1/key2=val2
+//
+// Malformed templates and unknown placeholders yield ErrInvalidTemplate.
+func WrapWFWithPathTemplate(wf WriterFactory, template string) (WriterFactory, error) {
+	parts, err := parseTemplate(template)
+	if err != nil {
+		return nil, err
+	}
+
+	var seq int64 = -1
+	return func(path string) (io.WriteCloser, error) {
+		e := &expansion{
+			path:       path,
+			now:        templateNow().UTC(),
+			seq:        atomic.AddInt64(&seq, 1),
+			partitions: keyvaluelist.NewKeyValuesFromPath(path),
+		}
+		expanded := &strings.Builder{}
+		for _, part := range parts {
+			value, err := part(e)
+			if err != nil {
+				return nil, err
+			}
+			expanded.WriteString(value)
+		}
+		return wf(expanded.String())
+	}, nil
+}
+
+// WithPathTemplate expands the template into the path used for each created writer; see WrapWFWithPathTemplate
+func (wf WriterFactory) WithPathTemplate(template string) (WriterFactory, error) {
+	return WrapWFWithPathTemplate(wf, template)
+}
+
+// parseTemplate splits the template into literals and placeholders
+func parseTemplate(template string) ([]placeholder, error) {
+	parts := []placeholder{}
+	for rest := template; rest != ""; {
+		start := strings.IndexAny(rest, "{}")
+		if start < 0 {
+			parts = append(parts, literal(rest))
+			break
+		}
+		if rest[start] == '}' {
+			return nil, fmt.Errorf("%w: unexpected } in %q", ErrInvalidTemplate, template)
+		}
+		if start > 0 {
+			parts = append(parts, literal(rest[:start]))
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("%w: unterminated placeholder in %q", ErrInvalidTemplate, template)
+		}
+		part, err := newPlaceholder(rest[start+1 : start+end])
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, part)
+		rest = rest[start+end+1:]
+	}
+	return parts, nil
+}
+
+func literal(s string) placeholder {
+	return func(*expansion) (string, error) { return s, nil }
+}
+
+func newPlaceholder(spec string) (placeholder, error) {
+	name, arg, hasArg := strings.Cut(spec, ":")
+	if hasArg != (name == "time" || name == "partition") || (hasArg && arg == "") {
+		return nil, fmt.Errorf("%w: {%s}", ErrInvalidTemplate, spec)
+	}
+
+	switch name {
+	case "path":
+		return func(e *expansion) (string, error) { return e.path, nil }, nil
+	case "date":
+		return func(e *expansion) (string, error) { return strftime("%Y-%m-%d", e.now), nil }, nil
+	case "hour":
+		return func(e *expansion) (string, error) { return strftime("%H", e.now), nil }, nil
+	case "time":
+		if err := validateStrftime(arg); err != nil {
+			return nil, err
+		}
+		return func(e *expansion) (string, error) { return strftime(arg, e.now), nil }, nil
+	case "uuid":
+		return func(*expansion) (string, error) { return newUUID() }, nil
+	case "hostname":
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, err
+		}
+		return literal(hostname), nil
+	case "pid":
+		return literal(strconv.Itoa(os.Getpid())), nil
+	case "seq":
+		return func(e *expansion) (string, error) { return fmt.Sprintf("%06d", e.seq), nil }, nil
+	case "partition":
+		return func(e *expansion) (string, error) {
+			for _, kv := range e.partitions {
+				if kv.Key != arg {
+					continue
+				}
+				// Values are derived from records; escaping keeps "/" from creating (or leaving) directories
+				value := url.QueryEscape(kv.Value)
+				if value == "." || value == ".." {
+					return "", &PathError{Path: e.path, Err: ErrPathTraversal}
+				}
+				return value, nil
+			}
+			return "", fmt.Errorf("%w: %s in %s", ErrMissingPartition, arg, e.path)
+		}, nil
+	case "partitions":
+		return func(e *expansion) (string, error) { return e.partitions.ToPartitionKey(), nil }, nil
+	}
+	return nil, fmt.Errorf("%w: unknown placeholder {%s}", ErrInvalidTemplate, spec)
+}
+
+// strftimeDirectives formats the supported strftime directives
+var strftimeDirectives = map[byte]func(t time.Time) string{
+	'Y': func(t time.Time) string { return fmt.Sprintf("%04d", t.Year()) },
+	'y': func(t time.Time) string { return fmt.Sprintf("%02d", t.Year()%100) },
+	'm': func(t time.Time) string { return fmt.Sprintf("%02d", int(t.Month())) },
+	'd': func(t time.Time) string { return fmt.Sprintf("%02d", t.Day()) },
+	'H': func(t time.Time) string { return fmt.Sprintf("%02d", t.Hour()) },
+	'M': func(t time.Time) string { return fmt.Sprintf("%02d", t.Minute()) },
+	'S': func(t time.Time) string { return fmt.Sprintf("%02d", t.Second()) },
+	'j': func(t time.Time) string { return fmt.Sprintf("%03d", t.YearDay()) },
+	'b': func(t time.Time) string { return t.Format("Jan") },
+	's': func(t time.Time) string { return strconv.FormatInt(t.Unix(), 10) },
+	'%': func(time.Time) string { return "%" },
+}
+
+func validateStrftime(format string) error {
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		if i+1 == len(format) || strftimeDirectives[format[i+1]] == nil {
+			return fmt.Errorf("%w: unsupported time format %q", ErrInvalidTemplate, format)
+		}
+		i++
+	}
+	return nil
+}
+
+// strftime formats t according to a (validated) format with the directives of strftimeDirectives
+func strftime(format string, t time.Time) string {
+	b := &strings.Builder{}
+	for i := 0; i < len(format); i++ {
+		if format[i] == '%' && i+1 < len(format) {
+			b.WriteString(strftimeDirectives[format[i+1]](t))
+			i++
+			continue
+		}
+		b.WriteByte(format[i])
+	}
+	return b.String()
+}
+
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
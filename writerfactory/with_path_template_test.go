@@ -0,0 +1,80 @@
+package writerfactory
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithPathTemplate(t *testing.T) {
+	templateNow = func() time.Time { return time.Date(2023, 2, 3, 4, 5, 6, 0, time.UTC) }
+	defer func() { templateNow = time.Now }()
+
+	buffers, wf := GetMemoryWriterFactory()
+	templated, err := wf.WithPathTemplate("{partitions}/{date}/{hour}/{time:%Y%m%dT%H%M%S_%j}/{partition:country}_{pid}_{seq}_{path}")
+	assert.NoError(t, err)
+
+	for _, path := range []string{"date=2023-02-03/country=se/file.ndjson", "country=no/file.ndjson"} {
+		w, err := templated(path)
+		assert.NoError(t, err)
+		assert.NoError(t, w.Close())
+	}
+	pid := strconv.Itoa(os.Getpid())
+	assert.Contains(t, buffers, "date=2023-02-03/country=se/2023-02-03/04/20230203T040506_034/se_"+pid+"_000000_date=2023-02-03/country=se/file.ndjson")
+	assert.Contains(t, buffers, "country=no/2023-02-03/04/20230203T040506_034/no_"+pid+"_000001_country=no/file.ndjson")
+
+	_, err = templated("file.ndjson")
+	assert.ErrorIs(t, err, ErrMissingPartition)
+}
+
+func TestWithPathTemplatePartitionTraversal(t *testing.T) {
+	buffers, wf := GetMemoryWriterFactory()
+	templated, err := wf.WithPathTemplate("out/{partition:user}/{seq}.json")
+	assert.NoError(t, err)
+
+	w, err := templated("user=..%2F..%2Fetc/f")
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	assert.Contains(t, buffers, "out/..%2F..%2Fetc/000000.json", "partition values can't create directories")
+
+	for _, path := range []string{"user=../f", "user=./f"} {
+		_, err = templated(path)
+		assert.ErrorIs(t, err, ErrPathTraversal, path)
+	}
+	assert.Len(t, buffers, 1)
+}
+
+func TestWithPathTemplateUUIDAndHostname(t *testing.T) {
+	buffers, wf := GetMemoryWriterFactory()
+	templated, err := wf.WithPathTemplate("{hostname}/{uuid}")
+	assert.NoError(t, err)
+	w, err := templated("")
+	assert.NoError(t, err)
+	w.Close()
+
+	hostname, _ := os.Hostname()
+	for path := range buffers {
+		assert.Regexp(t, "^"+regexp.QuoteMeta(hostname)+"/[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$", path)
+	}
+}
+
+func TestWithPathTemplateValidation(t *testing.T) {
+	_, wf := GetMemoryWriterFactory()
+	for _, template := range []string{
+		"{unknown}",
+		"{path",
+		"path}",
+		"{time}",
+		"{time:%Q}",
+		"{time:%}",
+		"{partition}",
+		"{date:%Y}",
+	} {
+		_, err := wf.WithPathTemplate(template)
+		assert.ErrorIs(t, err, ErrInvalidTemplate, template)
+	}
+}
@@ -0,0 +1,281 @@
+package writerfactory
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/kvanticoss/goutils/v2/backoff"
+	"github.com/kvanticoss/goutils/v2/internal/layered"
+)
+
+const (
+	// DefaultRetryMemoryBuffer is the number of bytes WithBufferedRetry keeps in memory before spilling to a temp file
+	DefaultRetryMemoryBuffer = 8 << 20
+	// DefaultRetryMinBackoff is the unit of the random, exponentially growing, sleeps between attempts when WithRetry
+	// and WithBufferedRetry get a nil backoff
+	DefaultRetryMinBackoff = 100 * time.Millisecond
+	// DefaultRetryMaxAttempts is the number of retries when WithRetry and WithBufferedRetry get a nil backoff
+	DefaultRetryMaxAttempts = 5
+)
+
+// RetryClassifier returns true for errors worth retrying; e.g. timeouts and 5xx responses. A nil RetryClassifier
+// retries all errors.
+type RetryClassifier func(err error) bool
+
+// RetryOptions configures the replay buffer of WithBufferedRetry
+type RetryOptions struct {
+	// MaxMemory is the number of bytes buffered in memory before the remainder is spilled to a temp file;
+	// 0 uses DefaultRetryMemoryBuffer
+	MaxMemory int
+	// TempDir is the directory of the spill file; "" uses os.TempDir()
+	TempDir string
+}
+
+// WithRetry retries the creation of writers by the underlying WriterFactory while classify considers the error
+// retryable, sleeping according to bo between attempts. bo is copied for each path so it can be shared; a nil bo
+// sleeps multiples of DefaultRetryMinBackoff and gives up after DefaultRetryMaxAttempts retries.
+func WithRetry(wf WriterFactory, bo *backoff.RandExpBackoff, classify RetryClassifier) WriterFactory {
+	return func(path string) (io.WriteCloser, error) {
+		return retry(bo, classify, func() (io.WriteCloser, error) { return wf(path) })
+	}
+}
+
+// WithRetry retries the creation of writers; see WithRetry
+func (wf WriterFactory) WithRetry(bo *backoff.RandExpBackoff, classify RetryClassifier) WriterFactory {
+	return WithRetry(wf, bo, classify)
+}
+
+// WithBufferedRetry is like WithRetry but where everything written is also buffered (in memory up to
+// opts.MaxMemory, then in a temp file). If a Write, Flush or Close of the underlying writer fails with a retryable
+// error the writer is aborted and the buffered bytes are replayed from scratch to a fresh writer for the same path.
+// The underlying WriterFactory must replace existing content when a path is opened again (as object stores and
+// local files do). Records reported through AddRecords are reported again to the fresh writer. A nil bo uses the
+// defaults of WithRetry.
+func WithBufferedRetry(wf WriterFactory, bo *backoff.RandExpBackoff, classify RetryClassifier, opts RetryOptions) WriterFactory {
+	if opts.MaxMemory <= 0 {
+		opts.MaxMemory = DefaultRetryMemoryBuffer
+	}
+	return func(path string) (io.WriteCloser, error) {
+		open := func() (io.WriteCloser, error) { return wf(path) }
+		w, err := retry(bo, classify, open)
+		if err != nil {
+			return nil, err
+		}
+		return &retryWriter{
+			open:     open,
+			bo:       bo,
+			classify: classify,
+			w:        w,
+			buf:      &replayBuffer{maxMemory: opts.MaxMemory, tempDir: opts.TempDir},
+		}, nil
+	}
+}
+
+func retry(bo *backoff.RandExpBackoff, classify RetryClassifier, open func() (io.WriteCloser, error)) (io.WriteCloser, error) {
+	bo = copyBackoff(bo)
+	for {
+		w, err := open()
+		if err == nil || !isRetryable(classify, err) {
+			return w, err
+		}
+		var boErr error
+		if bo, boErr = bo.SleepAndIncr(); boErr != nil {
+			return nil, fmt.Errorf("giving up after %d attempts: %w", bo.Attempt(), err)
+		}
+	}
+}
+
+// copyBackoff copies bo so the attempts aren't shared; nil gets the defaults of WithRetry
+func copyBackoff(bo *backoff.RandExpBackoff) *backoff.RandExpBackoff {
+	if bo == nil {
+		return backoff.New().WithScale(1).WithMinBackoff(DefaultRetryMinBackoff).WithMaxAttempts(DefaultRetryMaxAttempts - 1)
+	}
+	copied := *bo
+	return &copied
+}
+
+func isRetryable(classify RetryClassifier, err error) bool {
+	return classify == nil || classify(err)
+}
+
+type retryWriter struct {
+	open     func() (io.WriteCloser, error)
+	bo       *backoff.RandExpBackoff
+	classify RetryClassifier
+
+	w       io.WriteCloser
+	buf     *replayBuffer
+	records int
+	err     error
+}
+
+// Write buffers p and writes it to the underlying writer; replaying to a fresh writer on retryable errors
+func (rw *retryWriter) Write(p []byte) (int, error) {
+	if rw.err != nil {
+		return 0, rw.err
+	}
+	if err := rw.buf.Write(p); err != nil {
+		rw.err = err
+		return 0, err
+	}
+	if _, err := rw.w.Write(p); err != nil {
+		if err = rw.recover(err, nil); err != nil {
+			rw.err = err
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush flushes the underlying writer if it is a flusher; replaying to a fresh writer on retryable errors
+func (rw *retryWriter) Flush() error {
+	if rw.err != nil {
+		return rw.err
+	}
+	flush := func(w io.WriteCloser) error { return layered.Flush(w) }
+	if err := flush(rw.w); err != nil {
+		if rw.err = rw.recover(err, flush); rw.err != nil {
+			return rw.err
+		}
+	}
+	return nil
+}
+
+// Close closes the underlying writer; replaying to a fresh writer on retryable errors. The buffer is released.
+func (rw *retryWriter) Close() error {
+	defer rw.buf.Close()
+	if rw.err != nil {
+		abort(rw.w)
+		rw.w = nil
+		return rw.err
+	}
+
+	err := rw.w.Close()
+	if err != nil {
+		err = rw.recover(err, func(w io.WriteCloser) error { return w.Close() })
+	}
+	rw.w = nil
+	rw.err = os.ErrClosed
+	return err
+}
+
+// Abort implements Aborter; the underlying writer is aborted and the buffer released
+func (rw *retryWriter) Abort() error {
+	rw.err = ErrAborted
+	abort(rw.w)
+	rw.w = nil
+	return rw.buf.Close()
+}
+
+// Rotates implements Rotator
+func (rw *retryWriter) Rotates() bool {
+	return layered.Rotates(rw.w)
+}
+
+// AddRecords implements RecordCounter
+func (rw *retryWriter) AddRecords(n int) {
+	rw.records += n
+	layered.AddRecords(rw.w, n)
+}
+
+// recover aborts the current writer, opens a fresh one, replays the buffer and calls then (if set) until it
+// succeeds or fails with an error which isn't retryable, or the backoff gives up. A fresh writer failing with an error
+// which isn't retryable is aborted.
+func (rw *retryWriter) recover(err error, then func(w io.WriteCloser) error) error {
+	bo := copyBackoff(rw.bo)
+	reopened := false
+	defer func() {
+		if err != nil && reopened {
+			abort(rw.w)
+			rw.w = nil
+		}
+	}()
+	for err != nil && isRetryable(rw.classify, err) {
+		abort(rw.w)
+		rw.w = nil
+
+		var boErr error
+		if bo, boErr = bo.SleepAndIncr(); boErr != nil {
+			return fmt.Errorf("giving up after %d attempts: %w", bo.Attempt(), err)
+		}
+		if rw.w, err = rw.open(); err != nil {
+			continue
+		}
+		reopened = true
+		if err = rw.buf.replay(rw.w); err != nil {
+			continue
+		}
+		if rw.records > 0 {
+			layered.AddRecords(rw.w, rw.records)
+		}
+		if then != nil {
+			err = then(rw.w)
+		}
+	}
+	return err
+}
+
+// replayBuffer keeps everything written; the first maxMemory bytes in memory and the rest in a temp file
+type replayBuffer struct {
+	maxMemory int
+	tempDir   string
+
+	mem      bytes.Buffer
+	file     *os.File
+	fileSize int64
+}
+
+func (b *replayBuffer) Write(p []byte) error {
+	if b.file == nil {
+		if n := b.maxMemory - b.mem.Len(); n > 0 {
+			if n > len(p) {
+				n = len(p)
+			}
+			b.mem.Write(p[:n])
+			p = p[n:]
+		}
+		if len(p) == 0 {
+			return nil
+		}
+		file, err := os.CreateTemp(b.tempDir, "retry-buffer-*")
+		if err != nil {
+			return err
+		}
+		b.file = file
+	}
+	n, err := b.file.Write(p)
+	b.fileSize += int64(n)
+	return err
+}
+
+// size is the number of buffered bytes
+func (b *replayBuffer) size() int64 {
+	return int64(b.mem.Len()) + b.fileSize
+}
+
+// replay writes the buffered bytes to w
+func (b *replayBuffer) replay(w io.Writer) error {
+	if _, err := w.Write(b.mem.Bytes()); err != nil {
+		return err
+	}
+	if b.file != nil {
+		_, err := io.Copy(w, io.NewSectionReader(b.file, 0, b.fileSize))
+		return err
+	}
+	return nil
+}
+
+// Close releases the memory and removes the temp file
+func (b *replayBuffer) Close() error {
+	b.mem = bytes.Buffer{}
+	if b.file == nil {
+		return nil
+	}
+	file := b.file
+	b.file = nil
+	file.Close()
+	return os.Remove(file.Name())
+}
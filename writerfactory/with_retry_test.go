@@ -0,0 +1,181 @@
+package writerfactory
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/kvanticoss/goutils/v2/backoff"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errTransient = errors.New("transient")
+
+// flakyStore is an object store where each open replaces the object; failures are injected per attempt
+type flakyStore struct {
+	objects     map[string]*bytes.Buffer
+	opens       int
+	failOpens   int // number of opens failing before succeeding
+	failWriteAt int // fail the n:th write (counted over all writers); 0 never
+	failCloses  int // number of closes failing
+	writes      int
+	aborted     int
+	records     map[string]int
+}
+
+type flakyWriter struct {
+	store   *flakyStore
+	path    string
+	buf     *bytes.Buffer
+	records int
+}
+
+func (s *flakyStore) wf(path string) (io.WriteCloser, error) {
+	s.opens++
+	if s.failOpens > 0 {
+		s.failOpens--
+		return nil, errTransient
+	}
+	return &flakyWriter{store: s, path: path, buf: &bytes.Buffer{}}, nil
+}
+
+func (w *flakyWriter) Write(p []byte) (int, error) {
+	w.store.writes++
+	if w.store.writes == w.store.failWriteAt {
+		return 0, errTransient
+	}
+	return w.buf.Write(p)
+}
+
+func (w *flakyWriter) Close() error {
+	if w.store.failCloses > 0 {
+		w.store.failCloses--
+		return errTransient
+	}
+	w.store.objects[w.path] = w.buf
+	w.store.records[w.path] = w.records
+	return nil
+}
+
+func (w *flakyWriter) Abort() error {
+	w.store.aborted++
+	return nil
+}
+
+func (w *flakyWriter) AddRecords(n int) {
+	w.records += n
+}
+
+func newFlakyStore() *flakyStore {
+	return &flakyStore{objects: map[string]*bytes.Buffer{}, records: map[string]int{}}
+}
+
+func TestWithRetry(t *testing.T) {
+	store := newFlakyStore()
+	store.failOpens = 2
+	bo := backoff.New().WithMaxAttempts(5)
+
+	w, err := WriterFactory(store.wf).WithRetry(bo, nil)("a")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, store.opens)
+	assert.Equal(t, 0, bo.Attempt(), "the backoff is copied per path")
+	w.Write([]byte("data"))
+	assert.NoError(t, w.Close())
+	assert.Equal(t, "data", store.objects["a"].String())
+
+	store.failOpens = 10
+	_, err = WithRetry(store.wf, backoff.New().WithMaxAttempts(2), nil)("b")
+	assert.True(t, errors.Is(err, errTransient))
+
+	permanent := errors.New("permanent")
+	store.opens = 0
+	_, err = WithRetry(func(string) (io.WriteCloser, error) {
+		store.opens++
+		return nil, permanent
+	}, bo, func(err error) bool { return err == errTransient })("c")
+	assert.Equal(t, permanent, err)
+	assert.Equal(t, 1, store.opens)
+}
+
+func TestWithBufferedRetryReplays(t *testing.T) {
+	for name, opts := range map[string]RetryOptions{
+		"memory": {},
+		"spill":  {MaxMemory: 3, TempDir: t.TempDir()},
+	} {
+		store := newFlakyStore()
+		store.failWriteAt = 3
+		store.failCloses = 1
+		wf := WithBufferedRetry(store.wf, backoff.New().WithMaxAttempts(5), nil, opts)
+
+		w, err := wf("a")
+		assert.NoError(t, err, name)
+		for _, chunk := range []string{"first ", "second ", "third"} {
+			_, err := w.Write([]byte(chunk))
+			assert.NoError(t, err, name)
+			w.(RecordCounter).AddRecords(1)
+		}
+		assert.NoError(t, w.Close(), name)
+
+		assert.Equal(t, "first second third", store.objects["a"].String(), name)
+		assert.Equal(t, 2, store.aborted, "the failed writers are aborted")
+		assert.Equal(t, 3, store.opens, name)
+		assert.Equal(t, 3, store.records["a"], "records are reported again to fresh writers")
+
+		_, err = w.Write([]byte("more"))
+		assert.Equal(t, os.ErrClosed, err, name)
+		if opts.TempDir != "" {
+			entries, _ := os.ReadDir(opts.TempDir)
+			assert.Empty(t, entries, "the spill file is removed")
+		}
+	}
+}
+
+func TestWithBufferedRetryGivesUp(t *testing.T) {
+	store := newFlakyStore()
+	store.failCloses = 10
+	w, err := WithBufferedRetry(store.wf, backoff.New().WithMaxAttempts(1), nil, RetryOptions{})("a")
+	assert.NoError(t, err)
+	w.Write([]byte("data"))
+	assert.True(t, errors.Is(w.Close(), errTransient))
+	assert.Empty(t, store.objects)
+
+	w, err = WithBufferedRetry(failingWriterFactory(0), nil, func(err error) bool { return err == errTransient }, RetryOptions{})("a")
+	assert.NoError(t, err)
+	_, err = w.Write([]byte("data"))
+	assert.Equal(t, errBackend, err)
+	assert.Equal(t, errBackend, w.Close())
+}
+
+func TestWithBufferedRetryAbortsFreshWriterOnPermanentError(t *testing.T) {
+	store := newFlakyStore()
+	store.failCloses = 1
+	store.failWriteAt = 2 // the replay to the fresh writer
+	retries := 0
+	classify := func(err error) bool {
+		retries++
+		return retries == 1 // only the failed close is retryable
+	}
+
+	w, err := WithBufferedRetry(store.wf, backoff.New().WithMaxAttempts(1), classify, RetryOptions{})("a")
+	assert.NoError(t, err)
+	w.Write([]byte("data"))
+	assert.Equal(t, errTransient, w.Close())
+	assert.Equal(t, 2, store.opens)
+	assert.Equal(t, 2, store.aborted, "both the failed and the fresh writer are aborted")
+	assert.Empty(t, store.objects)
+}
+
+func TestRetryDefaultBackoff(t *testing.T) {
+	store := newFlakyStore()
+	store.failOpens = 1
+	w, err := WithRetry(store.wf, nil, nil)("a")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, store.opens)
+	assert.NoError(t, w.Close())
+
+	_, err = copyBackoff(nil).WithStartAttempt(DefaultRetryMaxAttempts).SleepAndIncr()
+	assert.Equal(t, backoff.ErrTooManyAttempts, err, "the default backoff gives up")
+}
@@ -0,0 +1,181 @@
+package writerfactory
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kvanticoss/goutils/v2/internal/layered"
+)
+
+var rotationNow = time.Now
+
+// Rotator is implemented by writers splitting their stream across several files, such as the writers of
+// WithRotation. Writers wrapping another writer should forward it; the writers of this package do.
+type Rotator = layered.Rotator
+
+// WithRotation rolls the writers returned by the underlying WriterFactory over to successor files once maxBytes
+// bytes (uncompressed), maxRecords records or maxAge since the file was opened has been reached; a limit <= 0 is
+// disabled. The first file is created under the requested path and its successors as name_000001.ext,
+// name_000002.ext etc (the extension starts at the first "." of the file name).
+//
+// Files are only rolled over on record boundaries, i.e. when the end of a record is reported through AddRecords
+// (see RecordCounter, as done by recordwriter); writers which never report records are never rotated. The successor
+// is created on the first Write after the rollover, so no empty trailing files are created. Each file is written by
+// a separate underlying writer; codecs writing headers (e.g. CSV) should use recordwriter.PartitionedBySize instead
+// to get a header in each file. Aborting the writer only aborts the current file.
+//
+// Compression, encryption and checksums frame the stream as a whole and must be applied below the rotation so each
+// file gets its own stream; e.g. wf.WithGzip().WithRotation(...). Applying them on top of a rotating writer fails
+// with ErrRotationBelowStream. Rotating writers are detected through Rotator, so the check is best-effort for
+// writers wrapped by decorators which don't forward it.
+func WithRotation(wf WriterFactory, maxBytes int, maxRecords int, maxAge time.Duration) WriterFactory {
+	return func(path string) (io.WriteCloser, error) {
+		rw := &rotatingWriter{
+			wf:         wf,
+			path:       path,
+			maxBytes:   maxBytes,
+			maxRecords: maxRecords,
+			maxAge:     maxAge,
+		}
+		if err := rw.open(); err != nil {
+			return nil, err
+		}
+		return rw, nil
+	}
+}
+
+// WithRotation rolls writers over to successor files on record boundaries; see WithRotation
+func (wf WriterFactory) WithRotation(maxBytes int, maxRecords int, maxAge time.Duration) WriterFactory {
+	return WithRotation(wf, maxBytes, maxRecords, maxAge)
+}
+
+// RotationPath returns the path of the index:th file of a rotated path; index 0 is the path itself
+func RotationPath(path string, index int) string {
+	if index == 0 {
+		return path
+	}
+	dir, name := "", path
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		dir, name = path[:i+1], path[i+1:]
+	}
+	start := 0
+	if strings.HasPrefix(name, ".") {
+		start = 1 // hidden files (".name") keep their dot
+	}
+	base, ext := name, ""
+	if i := strings.Index(name[start:], "."); i >= 0 {
+		base, ext = name[:start+i], name[start+i:]
+	}
+	return fmt.Sprintf("%s%s_%06d%s", dir, base, index, ext)
+}
+
+// rejectRotation aborts w and returns ErrRotationBelowStream if it's a rotating writer (see Rotator); used by the
+// decorators which can't be split across files
+func rejectRotation(w io.WriteCloser) error {
+	if !layered.Rotates(w) {
+		return nil
+	}
+	abort(w)
+	return ErrRotationBelowStream
+}
+
+type rotatingWriter struct {
+	wf         WriterFactory
+	path       string
+	maxBytes   int
+	maxRecords int
+	maxAge     time.Duration
+
+	index   int // of the next file
+	current io.WriteCloser
+	opened  time.Time
+	bytes   int
+	records int
+	err     error // first error of rotation or os.ErrClosed / ErrAborted once done
+}
+
+func (rw *rotatingWriter) open() error {
+	w, err := rw.wf(RotationPath(rw.path, rw.index))
+	if err != nil {
+		return err
+	}
+	rw.index++
+	rw.current, rw.opened, rw.bytes, rw.records = w, rotationNow(), 0, 0
+	return nil
+}
+
+// Write writes to the current file; creating the successor if the previous one was rolled over
+func (rw *rotatingWriter) Write(p []byte) (int, error) {
+	if rw.err != nil {
+		return 0, rw.err
+	}
+	if rw.current == nil {
+		if err := rw.open(); err != nil {
+			rw.err = err
+			return 0, err
+		}
+	}
+	n, err := rw.current.Write(p)
+	rw.bytes += n
+	return n, err
+}
+
+// AddRecords marks the end of n records; the current file is closed if it has reached any of the limits. The count
+// is forwarded to the underlying writer if it keeps track of records.
+func (rw *rotatingWriter) AddRecords(n int) {
+	if rw.current == nil {
+		return
+	}
+	layered.AddRecords(rw.current, n)
+	rw.records += n
+	if rw.err == nil && rw.full() {
+		current := rw.current
+		rw.current = nil
+		rw.err = current.Close()
+	}
+}
+
+func (rw *rotatingWriter) full() bool {
+	return (rw.maxBytes > 0 && rw.bytes >= rw.maxBytes) ||
+		(rw.maxRecords > 0 && rw.records >= rw.maxRecords) ||
+		(rw.maxAge > 0 && rotationNow().Sub(rw.opened) >= rw.maxAge)
+}
+
+// Flush flushes the current file (if any)
+func (rw *rotatingWriter) Flush() error {
+	if rw.err != nil {
+		return rw.err
+	}
+	return layered.Flush(rw.current)
+}
+
+// Rotates implements Rotator
+func (rw *rotatingWriter) Rotates() bool {
+	return true
+}
+
+// Close closes the current file (if any)
+func (rw *rotatingWriter) Close() error {
+	if rw.err != nil {
+		return rw.err
+	}
+	rw.err = os.ErrClosed
+	if rw.current == nil {
+		return nil
+	}
+	return rw.current.Close()
+}
+
+// Abort implements Aborter; files which have already been rolled over are kept
+func (rw *rotatingWriter) Abort() error {
+	if rw.err == os.ErrClosed || rw.err == ErrAborted {
+		return nil
+	}
+	rw.err = ErrAborted
+	current := rw.current
+	rw.current = nil
+	return layered.Abort(current)
+}
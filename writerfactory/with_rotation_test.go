@@ -0,0 +1,156 @@
+package writerfactory
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kvanticoss/goutils/v2/encryption"
+	"github.com/kvanticoss/goutils/v2/gzip"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotationPath(t *testing.T) {
+	assert.Equal(t, "dir/data.ndjson.gz", RotationPath("dir/data.ndjson.gz", 0))
+	assert.Equal(t, "dir/data_000001.ndjson.gz", RotationPath("dir/data.ndjson.gz", 1))
+	assert.Equal(t, "a.b/data_000002", RotationPath("a.b/data", 2))
+	assert.Equal(t, ".hidden_000001.json", RotationPath(".hidden.json", 1))
+}
+
+func TestWithRotationByRecords(t *testing.T) {
+	buffers, wf := GetMemoryWriterFactory()
+
+	w, err := wf.WithRotation(0, 2, 0)("part=a/data.json")
+	assert.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		fmt.Fprintf(w, "%d\n", i)
+		w.(RecordCounter).AddRecords(1)
+	}
+	assert.NoError(t, w.Close())
+
+	assert.Len(t, buffers, 3)
+	assert.Equal(t, "0\n1\n", buffers["part=a/data.json"].String())
+	assert.Equal(t, "2\n3\n", buffers["part=a/data_000001.json"].String())
+	assert.Equal(t, "4\n", buffers["part=a/data_000002.json"].String())
+}
+
+func TestWithRotationBySize(t *testing.T) {
+	buffers, wf := GetMemoryWriterFactory()
+
+	w, err := WithRotation(wf, 5, 0, 0)("data.json")
+	assert.NoError(t, err)
+	for _, record := range []string{"abc\n", "def\n", "ghijkl\n"} {
+		w.Write([]byte(record))
+		w.(RecordCounter).AddRecords(1)
+	}
+	assert.NoError(t, w.Close())
+
+	assert.Len(t, buffers, 2, "no empty trailing file")
+	assert.Equal(t, "abc\ndef\n", buffers["data.json"].String(), "records are never split")
+	assert.Equal(t, "ghijkl\n", buffers["data_000001.json"].String())
+}
+
+func TestWithRotationByAge(t *testing.T) {
+	now := time.Date(2023, 2, 3, 0, 0, 0, 0, time.UTC)
+	rotationNow = func() time.Time { return now }
+	defer func() { rotationNow = time.Now }()
+
+	buffers, wf := GetMemoryWriterFactory()
+	w, err := WithRotation(wf, 0, 0, time.Minute)("data")
+	assert.NoError(t, err)
+
+	w.Write([]byte("first"))
+	w.(RecordCounter).AddRecords(1)
+	now = now.Add(time.Minute)
+	w.Write([]byte("second"))
+	w.(RecordCounter).AddRecords(1)
+	w.Write([]byte("third"))
+	assert.NoError(t, w.Close())
+
+	assert.Equal(t, "firstsecond", buffers["data"].String())
+	assert.Equal(t, "third", buffers["data_000001"].String())
+}
+
+func TestWithRotationAbort(t *testing.T) {
+	dir := t.TempDir()
+	wf := GetAtomicLocalWriterFactory(dir)
+
+	w, err := WithRotation(wf, 0, 1, 0)("data.json")
+	assert.NoError(t, err)
+	w.Write([]byte("kept\n"))
+	w.(RecordCounter).AddRecords(1)
+	w.Write([]byte("discarded\n"))
+	assert.NoError(t, w.(Aborter).Abort())
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 1, "the aborted successor is removed") {
+		assert.Equal(t, "data.json", entries[0].Name())
+	}
+}
+
+func TestWithRotationBelowStreamDecorators(t *testing.T) {
+	keys := encryption.NewStaticKeyProvider("k1", bytes.Repeat([]byte{1}, 32))
+	base := t.TempDir() + "/"
+	rotating := GetAtomicLocalWriterFactory(base).WithRotation(10, 0, 0)
+	for name, wf := range map[string]WriterFactory{
+		"gzip":       rotating.WithGzip(),
+		"gzip level": WithGzipLevel(rotating, gzip.BestSpeed),
+		"parallel":   WithParallelGzip(rotating, gzip.ParallelOptions{}),
+		"indexed":    WithIndexedGzip(rotating, gzip.IndexOptions{}),
+		"zstd":       rotating.WithZstd(),
+		"snappy":     rotating.WithSnappy(),
+		"encryption": rotating.WithEncryption(keys),
+		"checksums":  rotating.WithChecksums(),
+		"retry":      WithBufferedRetry(rotating, nil, nil, RetryOptions{}).WithGzip(),
+		"tee":        Tee(GetAtomicLocalWriterFactory(t.TempDir()+"/"), rotating).WithGzip(),
+	} {
+		_, err := wf("data.json")
+		assert.ErrorIs(t, err, ErrRotationBelowStream, name)
+	}
+	entries, _ := os.ReadDir(base)
+	assert.Empty(t, entries, "the rotating writers should be aborted")
+}
+
+func TestFlushThroughWrappers(t *testing.T) {
+	buffers, wf := GetMemoryWriterFactory()
+	for name, wf := range map[string]WriterFactory{
+		"rotation.gz":  wf.WithGzip().WithRotation(0, 1, 0),
+		"checksums.gz": wf.WithGzip().WithChecksums(),
+		"tee.gz":       Tee(wf.WithGzip()),
+	} {
+		w, err := wf(name)
+		assert.NoError(t, err)
+		w.Write([]byte("data"))
+		assert.Equal(t, 10, buffers[name].Len(), name+": only the gzip header is written")
+		assert.NoError(t, w.(interface{ Flush() error }).Flush(), name)
+		assert.Greater(t, buffers[name].Len(), 10, name+": flushed through the wrapper")
+		assert.NoError(t, w.Close(), name)
+	}
+}
+
+func TestWithRotationAboveGzip(t *testing.T) {
+	buffers, wf := GetMemoryWriterFactory()
+
+	w, err := wf.WithGzip().WithRotation(0, 1, 0)("data.json")
+	assert.NoError(t, err)
+	for _, record := range []string{"abc\n", "def\n"} {
+		w.Write([]byte(record))
+		w.(RecordCounter).AddRecords(1)
+	}
+	assert.NoError(t, w.Close())
+
+	assert.Len(t, buffers, 2)
+	for path, expected := range map[string]string{"data.json.gz": "abc\n", "data_000001.json.gz": "def\n"} {
+		r, err := gzip.NewReader(io.NopCloser(buffers[path]))
+		if assert.NoError(t, err, path) {
+			content, err := io.ReadAll(r)
+			assert.NoError(t, err, path)
+			assert.Equal(t, expected, string(content), "each file is a complete gzip stream")
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package writerfactory
+
+import (
+	"io"
+	"strings"
+
+	"github.com/kvanticoss/goutils/v2/snappy"
+)
+
+// WithSnappy adds framed snappy compression (default options) to the writers that is returned by the underlying
+// WriterFactory; ".sz" is appended to paths without it
+func WithSnappy(wf WriterFactory) WriterFactory {
+	return WithSnappyOptions(wf, snappy.Options{})
+}
+
+// WithSnappyOptions adds framed snappy compression with the level/concurrency options to the writers that is
+// returned by the underlying WriterFactory; ".sz" is appended to paths without it
+func WithSnappyOptions(wf WriterFactory, opts snappy.Options) WriterFactory {
+	return func(path string) (io.WriteCloser, error) {
+		if !strings.HasSuffix(path, ".sz") {
+			path = path + ".sz"
+		}
+
+		w, err := wf(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := rejectRotation(w); err != nil {
+			return nil, err
+		}
+		return snappy.NewWriterWithOptions(w, opts), nil
+	}
+}
+
+// WithSnappy adds framed snappy compression to the writers that is returned by the underlying WriterFactory
+func (wf WriterFactory) WithSnappy() WriterFactory {
+	return WithSnappy(wf)
+}
@@ -0,0 +1,27 @@
+package writerfactory
+
+import (
+	"io"
+	"testing"
+
+	"github.com/kvanticoss/goutils/v2/snappy"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithSnappyWriterFactory(t *testing.T) {
+	for _, level := range []int{snappy.LevelDefault, snappy.LevelBetter, snappy.LevelBest} {
+		buffers, rawWF := GetMemoryWriterFactory()
+		writer, err := WithSnappyOptions(rawWF, snappy.Options{Level: level})("subpath")
+		assert.NoError(t, err, "MemoryWriterFactory should never yield errors")
+		_, err = writer.Write([]byte("testing string"))
+		assert.NoError(t, err)
+		assert.NoError(t, writer.Close())
+
+		// The stream must be readable by a strict snappy framing reader
+		content, err := io.ReadAll(s2.NewReader(buffers["subpath.sz"], s2.ReaderMaxBlockSize(64<<10)))
+		assert.NoError(t, err)
+		assert.Equal(t, "testing string", string(content))
+	}
+}
@@ -0,0 +1,43 @@
+package writerfactory
+
+import (
+	"io"
+	"strings"
+
+	"github.com/kvanticoss/goutils/v2/zstd"
+)
+
+// WithZstd adds zstd compression (default options) to the writers that is returned by the underlying WriterFactory;
+// ".zst" is appended to paths without it
+func WithZstd(wf WriterFactory) WriterFactory {
+	return WithZstdOptions(wf, zstd.Options{})
+}
+
+// WithZstdOptions adds zstd compression with the level/concurrency options to the writers that is returned by the
+// underlying WriterFactory; ".zst" is appended to paths without it
+func WithZstdOptions(wf WriterFactory, opts zstd.Options) WriterFactory {
+	return func(path string) (io.WriteCloser, error) {
+		if !strings.HasSuffix(path, ".zst") {
+			path = path + ".zst"
+		}
+
+		w, err := wf(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := rejectRotation(w); err != nil {
+			return nil, err
+		}
+		zw, err := zstd.NewWriterWithOptions(w, opts)
+		if err != nil {
+			abort(w)
+			return nil, err
+		}
+		return zw, nil
+	}
+}
+
+// WithZstd adds zstd compression to the writers that is returned by the underlying WriterFactory
+func (wf WriterFactory) WithZstd() WriterFactory {
+	return WithZstd(wf)
+}
@@ -0,0 +1,42 @@
+package writerfactory
+
+import (
+	"io"
+	"testing"
+
+	"github.com/kvanticoss/goutils/v2/zstd"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithZstdWriterFactory(t *testing.T) {
+	buffers, rawWF := GetMemoryWriterFactory()
+
+	for _, wf := range []WriterFactory{rawWF.WithZstd(), WithZstdOptions(rawWF, zstd.Options{Level: 19, Concurrency: 2})} {
+		writer, err := wf("subpath")
+		assert.NoError(t, err, "MemoryWriterFactory should never yield errors")
+		_, err = writer.Write([]byte("testing string"))
+		assert.NoError(t, err)
+		assert.NoError(t, writer.Close())
+
+		_, ok := buffers["subpath"]
+		assert.False(t, ok, "the .zst suffix should be appended")
+		r, err := zstd.NewReader(io.NopCloser(buffers["subpath.zst"]))
+		assert.NoError(t, err)
+		content, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		assert.Equal(t, "testing string", string(content))
+		assert.NoError(t, r.Close())
+	}
+}
+
+func TestWithZstdKeepsSuffix(t *testing.T) {
+	buffers, rawWF := GetMemoryWriterFactory()
+	writer, err := rawWF.WithZstd()("file.zst")
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+
+	_, ok := buffers["file.zst"]
+	assert.True(t, ok)
+	assert.Len(t, buffers, 1)
+}
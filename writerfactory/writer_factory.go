@@ -1,6 +1,21 @@
 package writerfactory
 
-import "io"
+import (
+	"io"
+
+	"github.com/kvanticoss/goutils/v2/internal/layered"
+)
 
 // WriterFactory should yield a new WriteCloser under the given path.
 type WriterFactory func(path string) (wc io.WriteCloser, err error)
+
+// RecordCounter is implemented by writers which keep track of the number of records written to them (e.g. for
+// manifests); record writers report each encoded record.
+type RecordCounter = layered.RecordCounter
+
+// abort aborts the writer if possible; otherwise it's closed
+func abort(w io.WriteCloser) {
+	if w != nil {
+		layered.Abort(w)
+	}
+}
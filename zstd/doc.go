@@ -0,0 +1,3 @@
+// Package zstd provides Zstandard writers and readers with Close and Flush calls cascaded to the underlying
+// writer/reader; the zstd equivalent of the gzip package.
+package zstd
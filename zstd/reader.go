@@ -0,0 +1,28 @@
+package zstd
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Reader is a zstd reader that once closed will also close the underlying reader
+type Reader struct {
+	*zstd.Decoder
+	underlyingReader io.ReadCloser
+}
+
+// NewReader treats the r-stream as a zstd stream and returns a Reader; concatenated frames are read as one stream
+func NewReader(r io.ReadCloser) (*Reader, error) {
+	dec, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{dec, r}, nil
+}
+
+// Close releases the zstd decoder and closes the underlying reader
+func (zr *Reader) Close() error {
+	zr.Decoder.Close()
+	return zr.underlyingReader.Close()
+}
@@ -0,0 +1,75 @@
+package zstd
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/kvanticoss/goutils/v2/internal/layered"
+)
+
+// Options configures the encoder
+type Options struct {
+	// Level is the zstd compression level (1-22; mapped to the nearest supported level). 0 uses the default level (3)
+	Level int
+	// Concurrency is the number of goroutines compressing blocks in parallel; 0 uses GOMAXPROCS
+	Concurrency int
+}
+
+// Writer creates a zstd stream which closes the underlying stream as well as the zstd stream on close
+type Writer struct {
+	*zstd.Encoder
+	underlyingWriter io.Writer
+}
+
+// NewWriter returns a zstd writer with the default options; Close and Flush will be cascaded to the underlying writer
+func NewWriter(w io.Writer) *Writer {
+	zw, _ := NewWriterWithOptions(w, Options{}) // the default options are always valid
+	return zw
+}
+
+// NewWriterWithOptions returns a zstd writer with the options; Close and Flush will be cascaded to the underlying writer
+func NewWriterWithOptions(w io.Writer, opts Options) (*Writer, error) {
+	eopts := []zstd.EOption{}
+	if opts.Level != 0 {
+		eopts = append(eopts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(opts.Level)))
+	}
+	if opts.Concurrency > 0 {
+		eopts = append(eopts, zstd.WithEncoderConcurrency(opts.Concurrency))
+	}
+	enc, err := zstd.NewWriter(w, eopts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{Encoder: enc, underlyingWriter: w}, nil
+}
+
+// Flush flushes the zstd writer AND the underlying writer
+func (zw *Writer) Flush() error {
+	if err := zw.Encoder.Flush(); err != nil {
+		return err
+	}
+	return layered.Flush(zw.underlyingWriter)
+}
+
+// Close closes the zstd writer AND the underlying writer
+func (zw *Writer) Close() error {
+	if err := zw.Encoder.Close(); err != nil {
+		return err
+	}
+	return layered.Close(zw.underlyingWriter)
+}
+
+// Abort aborts the underlying writer without ending the zstd frame, so no partial frame is made visible by writers
+// supporting it; others are closed. Blocks compressed in the background are discarded.
+func (zw *Writer) Abort() error {
+	zw.Encoder.Reset(io.Discard)
+	zw.Encoder.Close() // releases the background goroutines
+	return layered.Abort(zw.underlyingWriter)
+}
+
+// AddRecords reports the end of n records to the underlying writer; the records may still be held in the current
+// zstd block.
+func (zw *Writer) AddRecords(n int) {
+	layered.AddRecords(zw.underlyingWriter, n)
+}